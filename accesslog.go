@@ -0,0 +1,52 @@
+package assetserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// An AccessEvent describes an HTTP request handled by a Server.
+// See [WithAccessLog].
+type AccessEvent struct {
+	Method string
+	// Path is the URL path of the request as received by the Server
+	// (after any prefix is removed by http.StripPrefix, for instance).
+	Path string
+	// Name is the name of the requested file in the file system, without
+	// any tag. It is empty if the request was rejected before the name
+	// was resolved (for example, because of an unsupported method).
+	Name string
+	// Tag is the tag given in the request path, if any.
+	Tag    string
+	Status int
+	// Bytes is the number of response body bytes written.
+	Bytes int64
+	// Duration is the time taken to handle the request, including
+	// sending the response body.
+	Duration time.Duration
+	// Encoding is the Content-Encoding of the response, or empty if the
+	// response was not encoded.
+	Encoding string
+}
+
+// WithAccessLog makes the Server call fn after handling each HTTP request
+// with a description of the request and response. It is called
+// synchronously by ServeHTTP, so it should be fast.
+func WithAccessLog(fn func(e AccessEvent)) Option {
+	return func(s *Server) {
+		s.accessLog = fn
+	}
+}
+
+func (s *Server) logAccess(r *http.Request, path string, rec *responseRecorder, start time.Time) {
+	s.accessLog(AccessEvent{
+		Method:   r.Method,
+		Path:     path,
+		Name:     rec.name,
+		Tag:      rec.tag,
+		Status:   rec.code(),
+		Bytes:    rec.bytes,
+		Duration: time.Since(start),
+		Encoding: rec.Header().Get("Content-Encoding"),
+	})
+}
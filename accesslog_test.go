@@ -0,0 +1,48 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAccessLog(t *testing.T) {
+	var events []AccessEvent
+	s := New(fstest.MapFS{
+		"a.txt":    &fstest.MapFile{Data: []byte("hello")},
+		"x/b.html": &fstest.MapFile{Data: []byte("<p>hi</p>")},
+	}, WithAccessLog(func(e AccessEvent) {
+		if e.Duration <= 0 {
+			t.Errorf("got non-positive duration %s", e.Duration)
+		}
+		events = append(events, e)
+	}))
+	tag := hashTag("hello")
+	for _, target := range []struct{ method, path string }{
+		{"GET", "/a.txt"},
+		{"GET", "/a." + tag + ".txt"},
+		{"HEAD", "x/b.html"},
+		{"GET", "/a.0123456789.txt"},
+		{"GET", "/missing.js"},
+		{"POST", "/a.txt"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Method = target.method
+		r.URL.Path = target.path
+		s.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	want := []AccessEvent{
+		{Method: "GET", Path: "/a.txt", Name: "a.txt", Status: 200, Bytes: 5},
+		{Method: "GET", Path: "/a." + tag + ".txt", Name: "a.txt", Tag: tag, Status: 200, Bytes: 5},
+		{Method: "HEAD", Path: "x/b.html", Name: "x/b.html", Status: 200},
+		{Method: "GET", Path: "/a.0123456789.txt", Name: "a.txt", Tag: "0123456789", Status: 404, Bytes: 19},
+		{Method: "GET", Path: "/missing.js", Name: "missing.js", Status: 404, Bytes: 19},
+		{Method: "POST", Path: "/a.txt", Status: 405, Bytes: 23},
+	}
+	if diff := cmp.Diff(want, events, cmpopts.IgnoreFields(AccessEvent{}, "Duration")); diff != "" {
+		t.Errorf("access events (-want +got):\n%s", diff)
+	}
+}
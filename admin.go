@@ -0,0 +1,125 @@
+package assetserver
+
+import (
+	"container/list"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// AdminHandler returns an HTTP handler for operating a long-lived Server,
+// particularly one whose files are updated in place. Each request passes
+// through auth, which should reject requests that aren't from administrators
+// (for instance, by checking a token or client certificate); AdminHandler
+// panics if auth is nil.
+//
+// The handler responds to POST requests for the path "/purge" (relative to
+// wherever the handler is mounted, as with [http.StripPrefix]) by discarding
+// cached information, such as tags and transformed contents, so that the
+// Server reads the affected files again when they are next requested. The
+// form values select what to discard:
+//
+//	name=css/style.css   the named file
+//	prefix=css/          all files whose names start with the prefix
+//	all=true             everything
+//
+// The response is a JSON object giving the number of files whose
+// information was discarded, such as {"purged": 3}. For a Server created with
+// [WithFSSelector], the files of every tenant are purged, and likewise for
+// every generation (see [Server.BeginGeneration]).
+//
+// The handler responds to GET requests for the path "/integrity" with a JSON
+// array describing every file of the current generation, as returned by
+// [Server.Integrity]:
+//
+//	[{"path":"css/style.css","tag":"1b2cf9aa0e","sha256":"9f86d0...","size":1024}, ...]
+func (s *Server) AdminHandler(auth func(http.Handler) http.Handler) http.Handler {
+	if auth == nil {
+		panic("assetserver: AdminHandler called with nil auth")
+	}
+	return auth(http.HandlerFunc(s.serveAdmin))
+}
+
+func (s *Server) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	var method string
+	var serve func(http.ResponseWriter, *http.Request)
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "purge":
+		method, serve = "POST", s.servePurge
+	case "integrity":
+		method, serve = "GET", s.serveIntegrity
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	serve(w, r)
+}
+
+func (s *Server) servePurge(w http.ResponseWriter, r *http.Request) {
+	var match func(name string) bool
+	name, prefix := r.FormValue("name"), r.FormValue("prefix")
+	switch {
+	case name != "":
+		name = strings.TrimPrefix(name, "/")
+		match = func(n string) bool { return n == name }
+	case prefix != "":
+		prefix = strings.TrimPrefix(prefix, "/")
+		match = func(n string) bool { return strings.HasPrefix(n, prefix) }
+	case r.FormValue("all") == "true":
+		// A nil match purges everything.
+	default:
+		http.Error(w, "400 Bad Request: one of name, prefix, or all=true is required", http.StatusBadRequest)
+		return
+	}
+	n := s.purge(match)
+	s.log(r.Context(), slog.LevelInfo, "purged cached info", "name", name, "prefix", prefix, "files", n)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(struct {
+		Purged int `json:"purged"`
+	}{n})
+}
+
+// purge discards the cached information for the files whose names satisfy
+// match, or for all files if match is nil, and returns the number of files
+// whose info was discarded.
+func (s *Server) purge(match func(name string) bool) int {
+	var n int
+	for _, t := range append(s.allTenants(), s.otherGenerations()...) {
+		n += t.purge(match)
+	}
+	all := match == nil
+	if all {
+		match = func(string) bool { return true }
+	}
+	for _, name := range s.cache.names() {
+		if match(name) && s.cache.evict(name) {
+			n++
+		}
+	}
+	if ts := s.transforms; ts != nil {
+		ts.mu.Lock()
+		for name := range ts.outputs {
+			if match(name) {
+				delete(ts.outputs, name)
+			}
+		}
+		ts.mu.Unlock()
+	}
+	if ir := s.imageResizer; ir != nil && all {
+		// Resized images are keyed by hashes, so they can only be
+		// discarded all at once. (Stale ones are never served anyway,
+		// since the keys include the tags of the originals.)
+		ir.mu.Lock()
+		ir.entries = make(map[string]*list.Element)
+		ir.lru.Init()
+		ir.mu.Unlock()
+	}
+	return n
+}
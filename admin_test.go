@@ -0,0 +1,92 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAdminHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/a.css": &fstest.MapFile{Data: []byte("a")},
+		"css/b.css": &fstest.MapFile{Data: []byte("b")},
+		"js/c.js":   &fstest.MapFile{Data: []byte("c")},
+		"d.txt":     &fstest.MapFile{Data: []byte("d")},
+	}
+	s := New(fsys, WithTransform(TransformFunc(upper), "*.txt"))
+	auth := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer admin" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	h := http.StripPrefix("/admin", s.AdminHandler(auth))
+	load := func() {
+		t.Helper()
+		for name := range fsys {
+			if _, err := s.Tag(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, tt := range []struct {
+		method string
+		path   string
+		form   url.Values
+		noAuth bool
+		code   int
+		body   string
+		left   int // number of cached files left
+	}{
+		{"POST", "/admin/purge", url.Values{"name": {"/css/a.css"}}, false, 200, `{"purged":1}`, 3},
+		{"POST", "/admin/purge", url.Values{"prefix": {"css/"}}, false, 200, `{"purged":2}`, 2},
+		{"POST", "/admin/purge", url.Values{"all": {"true"}}, false, 200, `{"purged":4}`, 0},
+		{"POST", "/admin/purge", url.Values{"name": {"nonexistent"}}, false, 200, `{"purged":0}`, 4},
+		{"POST", "/admin/purge", nil, false, 400, "", 4},
+		{"GET", "/admin/purge", nil, false, 405, "", 4},
+		{"POST", "/admin/other", nil, false, 404, "", 4},
+		{"POST", "/admin/purge", url.Values{"all": {"true"}}, true, 403, "", 4},
+	} {
+		load()
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if !tt.noAuth {
+			r.Header.Set("Authorization", "Bearer admin")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s %s %v: got status %d; want %d", tt.method, tt.path, tt.form, w.Code, tt.code)
+			continue
+		}
+		if tt.body != "" {
+			if got := strings.TrimSpace(w.Body.String()); got != tt.body {
+				t.Errorf("%s %s %v: got body %s; want %s", tt.method, tt.path, tt.form, got, tt.body)
+			}
+		}
+		if got := s.cache.len(); got != tt.left {
+			t.Errorf("%s %s %v: %d files left in cache; want %d", tt.method, tt.path, tt.form, got, tt.left)
+		}
+	}
+	s.transforms.mu.Lock()
+	n := len(s.transforms.outputs)
+	s.transforms.mu.Unlock()
+	if n != 1 {
+		t.Errorf("got %d transform outputs after reload; want 1", n)
+	}
+}
+
+func TestAdminHandlerNilAuth(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("AdminHandler(nil) didn't panic")
+		}
+	}()
+	New(fstest.MapFS{}).AdminHandler(nil)
+}
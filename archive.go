@@ -0,0 +1,294 @@
+package assetserver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ZipFS reads a zip archive and returns a file system containing its contents
+// which is suitable for passing to New. This allows a bundle of assets which is
+// shipped as a single archive to be served without extracting it to disk first.
+//
+// The archive is decompressed into memory. Only regular files and directories
+// are included. Since the archive cannot change, a Server using the returned
+// file system behaves as if the [WithImmutableFS] option were given.
+func ZipFS(r io.ReaderAt, size int64) (fs.FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	afs := newArchiveFS()
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		if !mode.IsRegular() && !mode.IsDir() {
+			continue
+		}
+		if mode.IsDir() {
+			if err := afs.addDir(zf.Name, zf.Modified); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("assetserver: error reading %s from zip archive: %w", zf.Name, err)
+		}
+		if err := afs.addFile(zf.Name, data, mode, zf.Modified); err != nil {
+			return nil, err
+		}
+	}
+	return afs, nil
+}
+
+// TarFS reads a tar archive and returns a file system containing its contents
+// which is suitable for passing to New. If the archive is gzip-compressed (as
+// with .tar.gz files), TarFS decompresses it.
+//
+// As with [ZipFS], the archive is read into memory, only regular files and
+// directories are included, and the file system is treated as immutable.
+func TarFS(r io.Reader) (fs.FS, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+	tr := tar.NewReader(r)
+	afs := newArchiveFS()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := afs.addDir(hdr.Name, hdr.ModTime); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("assetserver: error reading %s from tar archive: %w", hdr.Name, err)
+			}
+			if err := afs.addFile(hdr.Name, data, hdr.FileInfo().Mode(), hdr.ModTime); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return afs, nil
+}
+
+// An archiveFS is an in-memory file system holding the contents of an
+// archive.
+type archiveFS struct {
+	files map[string]*archiveFile // includes directories
+}
+
+type archiveFile struct {
+	name    string // full slash-separated path
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+	entries []fs.DirEntry // for directories, sorted by name
+}
+
+func newArchiveFS() *archiveFS {
+	afs := &archiveFS{files: make(map[string]*archiveFile)}
+	afs.files["."] = &archiveFile{name: ".", mode: fs.ModeDir | 0o555}
+	return afs
+}
+
+func cleanArchiveName(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "/"))
+	if !fs.ValidPath(clean) {
+		return "", fmt.Errorf("assetserver: invalid file name %q in archive", name)
+	}
+	return clean, nil
+}
+
+func (afs *archiveFS) addFile(name string, data []byte, mode fs.FileMode, modTime time.Time) error {
+	name, err := cleanArchiveName(name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return errors.New("assetserver: archive contains a file with an empty name")
+	}
+	if f, ok := afs.files[name]; ok && f.mode.IsDir() {
+		return fmt.Errorf("assetserver: archive contains both a file and a directory named %q", name)
+	}
+	if err := afs.addDir(path.Dir(name), time.Time{}); err != nil {
+		return err
+	}
+	f := &archiveFile{name: name, data: data, mode: mode.Perm(), modTime: modTime}
+	if _, ok := afs.files[name]; !ok {
+		afs.addEntry(f)
+	} else {
+		afs.replaceEntry(f)
+	}
+	afs.files[name] = f
+	return nil
+}
+
+func (afs *archiveFS) addDir(name string, modTime time.Time) error {
+	name, err := cleanArchiveName(name)
+	if err != nil {
+		return err
+	}
+	if f, ok := afs.files[name]; ok {
+		if !f.mode.IsDir() {
+			return fmt.Errorf("assetserver: archive contains both a file and a directory named %q", name)
+		}
+		if !modTime.IsZero() {
+			f.modTime = modTime
+		}
+		return nil
+	}
+	if err := afs.addDir(path.Dir(name), time.Time{}); err != nil {
+		return err
+	}
+	f := &archiveFile{name: name, mode: fs.ModeDir | 0o555, modTime: modTime}
+	afs.files[name] = f
+	afs.addEntry(f)
+	return nil
+}
+
+// addEntry adds f to the entries of its (existing) parent directory.
+func (afs *archiveFS) addEntry(f *archiveFile) {
+	parent := afs.files[path.Dir(f.name)]
+	i := sort.Search(len(parent.entries), func(i int) bool {
+		return parent.entries[i].Name() >= f.Name()
+	})
+	parent.entries = append(parent.entries, nil)
+	copy(parent.entries[i+1:], parent.entries[i:])
+	parent.entries[i] = f
+}
+
+// replaceEntry replaces the entry with the same name as f (which occurs if an
+// archive contains duplicate names).
+func (afs *archiveFS) replaceEntry(f *archiveFile) {
+	parent := afs.files[path.Dir(f.name)]
+	for i, e := range parent.entries {
+		if e.Name() == f.Name() {
+			parent.entries[i] = f
+			return
+		}
+	}
+}
+
+func (afs *archiveFS) Open(name string) (fs.File, error) {
+	f, err := afs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.mode.IsDir() {
+		return &openArchiveDir{archiveFile: f}, nil
+	}
+	return &openArchiveFile{Reader: bytes.NewReader(f.data), f: f}, nil
+}
+
+func (afs *archiveFS) Stat(name string) (fs.FileInfo, error) {
+	return afs.lookup("stat", name)
+}
+
+func (afs *archiveFS) ReadFile(name string) ([]byte, error) {
+	f, err := afs.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return bytes.Clone(f.data), nil
+}
+
+func (afs *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := afs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return append([]fs.DirEntry(nil), f.entries...), nil
+}
+
+func (afs *archiveFS) lookup(op, name string) (*archiveFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := afs.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+// archiveFile implements fs.FileInfo and fs.DirEntry.
+
+func (f *archiveFile) Name() string               { return path.Base(f.name) }
+func (f *archiveFile) Size() int64                { return int64(len(f.data)) }
+func (f *archiveFile) Mode() fs.FileMode          { return f.mode }
+func (f *archiveFile) Type() fs.FileMode          { return f.mode.Type() }
+func (f *archiveFile) ModTime() time.Time         { return f.modTime }
+func (f *archiveFile) IsDir() bool                { return f.mode.IsDir() }
+func (f *archiveFile) Sys() any                   { return nil }
+func (f *archiveFile) Info() (fs.FileInfo, error) { return f, nil }
+
+type openArchiveFile struct {
+	*bytes.Reader
+	f *archiveFile
+}
+
+func (f *openArchiveFile) Stat() (fs.FileInfo, error) { return f.f, nil }
+func (f *openArchiveFile) Close() error               { return nil }
+
+type openArchiveDir struct {
+	*archiveFile
+	offset int
+}
+
+func (d *openArchiveDir) Stat() (fs.FileInfo, error) { return d.archiveFile, nil }
+func (d *openArchiveDir) Close() error               { return nil }
+
+func (d *openArchiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *openArchiveDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.offset:]
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if n < len(entries) {
+			entries = entries[:n]
+		}
+	}
+	d.offset += len(entries)
+	return append([]fs.DirEntry(nil), entries...), nil
+}
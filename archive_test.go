@@ -0,0 +1,109 @@
+package assetserver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/webtest"
+)
+
+var testAssetNames = []string{"a.js", "b.min.js", "d/style.css", "d/sub/noext"}
+
+func TestZipFS(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range testAssetNames {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(readTestAsset(t, name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := ZipFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testArchiveFS(t, fsys)
+}
+
+func TestTarFS(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		var gw *gzip.Writer
+		tw := tar.NewWriter(&buf)
+		if compress {
+			gw = gzip.NewWriter(&buf)
+			tw = tar.NewWriter(gw)
+		}
+		// Include an explicit directory entry as well as implicit ones.
+		hdr := &tar.Header{Name: "d/", Typeflag: tar.TypeDir, Mode: 0o755}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range testAssetNames {
+			b := readTestAsset(t, name)
+			hdr := &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(b))}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			tw.Write(b)
+		}
+		err := tw.Close()
+		if err == nil && gw != nil {
+			err = gw.Close()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys, err := TarFS(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testArchiveFS(t, fsys)
+	}
+}
+
+func readTestAsset(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/assets/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testArchiveFS(t *testing.T, fsys fs.FS) {
+	t.Helper()
+	if err := fstest.TestFS(fsys, testAssetNames...); err != nil {
+		t.Fatal(err)
+	}
+	s := New(fsys)
+	if !s.immutable {
+		t.Error("Server using archive is not marked immutable")
+	}
+	webtest.TestHandler(t, "testdata/servehttp.txt", s)
+}
+
+func TestArchiveBadName(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{Name: "../evil.js", Typeflag: tar.TypeReg, Mode: 0o644}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TarFS(&buf); err == nil {
+		t.Fatal("TarFS returned nil error for archive containing ../evil.js")
+	}
+}
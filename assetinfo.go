@@ -0,0 +1,80 @@
+package assetserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// An AssetInfo describes the file that a Server resolved a request to.
+// See [AssetInfoFromContext].
+type AssetInfo struct {
+	// Name is the name of the file in the file system, without any tag
+	// and after any negotiation of image formats or languages.
+	Name string
+	// Tag is the file's tag. It may differ from the tag given in the
+	// request path, if any (for instance, when an image variant is
+	// served). It is empty if the file was served while being hashed
+	// (see [WithStreamingHash]) and hashing didn't finish.
+	Tag string
+	// Size is the length of the file's content, after any
+	// transformations but before any compression.
+	Size        int64
+	ContentType string
+}
+
+type assetInfoKey struct{}
+
+// An assetInfoSlot is where a Server records the AssetInfo for a request.
+type assetInfoSlot struct {
+	info *AssetInfo
+}
+
+// ContextWithAssetInfo returns a copy of ctx in which a Server records the
+// AssetInfo for a request with that context. Middleware which wraps a Server
+// can use it to report details about the file that was served without
+// parsing the URL:
+//
+//	func logAssets(h http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			ctx := assetserver.ContextWithAssetInfo(r.Context())
+//			h.ServeHTTP(w, r.WithContext(ctx))
+//			if info := assetserver.AssetInfoFromContext(ctx); info != nil {
+//				log.Printf("served %s (%d bytes)", info.Name, info.Size)
+//			}
+//		})
+//	}
+//
+// A Server adds such a context to each request that doesn't have one
+// already, so handlers that the Server calls (such as the one given to
+// [WithMethodNotAllowedHandler]) can use AssetInfoFromContext directly.
+func ContextWithAssetInfo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, assetInfoKey{}, new(assetInfoSlot))
+}
+
+// AssetInfoFromContext returns the AssetInfo recorded in ctx, which must be
+// from ContextWithAssetInfo, by a Server. It returns nil if no file was
+// served, as when the request was rejected before the file was found (with
+// 404 Not Found, for example) or the response was a resized image.
+func AssetInfoFromContext(ctx context.Context) *AssetInfo {
+	slot, _ := ctx.Value(assetInfoKey{}).(*assetInfoSlot)
+	if slot == nil {
+		return nil
+	}
+	return slot.info
+}
+
+// withAssetInfoSlot returns r, or a shallow copy of it with an assetInfoSlot
+// in its context if it doesn't already have one.
+func withAssetInfoSlot(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(assetInfoKey{}).(*assetInfoSlot); ok {
+		return r
+	}
+	return r.WithContext(ContextWithAssetInfo(r.Context()))
+}
+
+// setAssetInfo records info for the request with context ctx.
+func setAssetInfo(ctx context.Context, info *AssetInfo) {
+	if slot, _ := ctx.Value(assetInfoKey{}).(*assetInfoSlot); slot != nil {
+		slot.info = info
+	}
+}
@@ -0,0 +1,56 @@
+package assetserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetInfoFromContext(t *testing.T) {
+	fsys := fstest.MapFS{
+		"style.css": &fstest.MapFile{Data: []byte("body{}")},
+		"hello.txt": &fstest.MapFile{Data: []byte("hello, world")},
+	}
+	s := New(fsys, WithTransform(TransformFunc(upper), "*.txt"))
+	defer s.Close()
+	tagged, err := s.Tag("style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.currentInfo(context.Background(), "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	helloInfo, err := s.currentInfo(context.Background(), "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path string
+		want *AssetInfo
+	}{
+		{"/style.css", &AssetInfo{Name: "style.css", Tag: info.tag, Size: 6, ContentType: "text/css; charset=utf-8"}},
+		{"/" + tagged, &AssetInfo{Name: "style.css", Tag: info.tag, Size: 6, ContentType: "text/css; charset=utf-8"}},
+		{"/hello.txt", &AssetInfo{Name: "hello.txt", Tag: helloInfo.tag, Size: 12, ContentType: "text/plain; charset=utf-8"}},
+		{"/missing.css", nil},
+		{"/style.1234567890.css", nil},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		ctx := ContextWithAssetInfo(req.Context())
+		s.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+		got := AssetInfoFromContext(ctx)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s: got AssetInfo %+v; want %+v", tt.path, got, tt.want)
+		}
+	}
+
+	// Without ContextWithAssetInfo, the caller's context has no info.
+	req := httptest.NewRequest("GET", "/style.css", nil)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if got := AssetInfoFromContext(req.Context()); got != nil {
+		t.Errorf("AssetInfoFromContext without ContextWithAssetInfo: got %+v; want nil", got)
+	}
+}
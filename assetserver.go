@@ -1,20 +1,65 @@
 // Package assetserver provides a file server for web assets.
+//
+// # Patterns
+//
+// Several options select files using path patterns. A pattern is a
+// slash-separated path which is matched against file names (as given to the
+// underlying [fs.FS], without a leading slash). Each element of the pattern
+// uses the syntax of [path.Match], except that an element consisting of "**"
+// matches zero or more path elements. For example, "js/*.js" matches
+// "js/app.js" but not "js/vendor/lib.js", whereas "js/**" matches both.
+//
+// As a convenience, a leading slash in a pattern is ignored.
+//
+// # File names and URLs
+//
+// A Server finds the file for a request by taking the request's URL path,
+// which net/http has already percent-decoded, cleaning it with [path.Clean],
+// removing the leading slash, and removing any tag. So a request for
+// /my%20file.txt is served the file named "my file.txt", and a request for
+// /caf%C3%A9.css is served "café.css". A plus sign only stands for a space
+// in query strings, not paths, so /a+b.txt and /a%2Bb.txt are both served
+// "a+b.txt".
+//
+// Requests for names which can't be the names of files, because they aren't
+// valid according to [fs.ValidPath] (for example, because they aren't valid
+// UTF-8) or they contain NUL bytes, receive 404 Not Found without the file
+// system being consulted.
+//
+// [Server.Tag] and the other methods which take and return names deal in
+// file names, not URLs, so names containing characters such as spaces, "%",
+// "?", and "#" must be escaped (for instance, with [net/url.PathEscape] for
+// each element) to be used in URLs. The URLs that a Server generates itself, such
+// as those in redirects, Link and SourceMap headers, import maps, srcset
+// values, and precache manifests, are escaped.
+//
+// # WebAssembly
+//
+// A Server always serves .wasm files as application/wasm, whatever the
+// system's MIME tables say, so that browsers can compile them while they
+// download using WebAssembly.instantiateStreaming. Since WebAssembly modules
+// (particularly those built by Go) are large, consider compressing them ahead
+// of time and serving the compressed versions with [WithPrecompressed].
 package assetserver
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
+	"embed"
 	"errors"
+	"hash"
 	"io"
 	"io/fs"
+	"log/slog"
 	"math/big"
-	"mime"
 	"net/http"
 	"path"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // A Server serves HTTP requests with the contents of a file system.
@@ -30,6 +75,8 @@ import (
 //
 //	public, max-age=31536000, immutable
 //
+// (The max-age can be changed with [WithImmutableMaxAge].)
+//
 // If the Server was created with NewNoCache, all assets are instead served
 // with Cache-Control: no-cache.
 //
@@ -40,15 +87,120 @@ import (
 //   - If the requested name is tagged but the tag does not match the
 //     corresponding file
 //
-// For other errors, Server sends a 500 Internal Server Error response.
+// For other errors, Server sends a 500 Internal Server Error response (see
+// [WithErrorMapper] to change this).
+//
+// If the Server was created with the [WithBasicAuth] option, requests for
+// protected files that lack valid credentials receive a 401 Unauthorized
+// response before the file system is consulted.
 type Server struct {
-	fsys    fs.FS
-	noCache bool
+	fsys          fs.FS
+	noCache       bool
+	immutable     bool // never revalidate cached info
+	auth          *basicAuth
+	rateLimit     *rateLimit
+	pruneInterval time.Duration
+	revalidate    time.Duration // if > 0, trust the cache and revalidate in the background
+	pretag        time.Duration // if > 0, hash changed files in the background
+	hashSem       chan struct{} // if non-nil, limits concurrent readInfo calls
+	partialHash   *partialHash
+	transforms    *transforms // nil if there are no transformers
+
+	cacheFile         string
+	cacheSaveInterval time.Duration
+
+	streamingHash bool
+	serverTiming  bool
+	healthFile    string
+	liveReload    *liveReload
+	injectURL     string // if non-empty, add a live reload script tag to HTML files
+	proxy         http.Handler
+	proxyAll      bool // forward all requests to proxy, not just unmatched ones
+	refreshHeader string
+	releaseHeader string
+	release       string // the value of releaseHeader
+	globalVersion string
+	duplicates    *duplicateIndex
+	changeHook    func(name, oldTag, newTag string)
+	history       *versionHistory
+	sourceMaps    bool // add SourceMap headers
+	precache      *PrecacheManifestOptions
+	preloadLinks  map[string][]preloadLink // by entry name
+	imageVariants []imageVariant           // in order of preference
+	languages     *languageNegotiation
+	imageResizer  *imageResizer
+	precompressed *precompressed
+	noRanges      *noRanges
+	throttles     []*throttle
+	buildManifest *buildManifest
+	preHashed     []string // patterns
+	immutableDirs []string // patterns
+
+	immutableMaxAges []immutableMaxAge
+
+	caseInsensitive bool // resolve missing paths ignoring case
+	symlinkPolicy   SymlinkPolicy
+	canonicalLink   CanonicalLink // 0 if none
+
+	sniffLen int // if > 0, the number of bytes to sniff
+	sniffer  func(name string, head []byte) string
+
+	typeInTag bool // mix the content type into tags
+
+	wellKnown *WellKnownOptions
 
-	// An rwmutex seems appropriate here: once we've loaded all the assets,
-	// we never lock the mutex again.
-	mu    sync.RWMutex
-	cache map[string]*atomic.Pointer[fileInfo]
+	redirectStatus    int // 0 means 308
+	absoluteRedirects bool
+
+	timingAllowOrigin string // if non-empty, the Timing-Allow-Origin header
+	crossOrigin       CrossOriginPolicy
+	cors              *cors
+
+	metrics    []MetricsHooks
+	serveHooks []ServeHooks
+	logger     *slog.Logger
+	traces     []TraceHooks
+
+	methods          []string // accepted methods; if nil, GET and HEAD
+	allow            string   // the Allow header for methods
+	methodNotAllowed http.Handler
+
+	accessLog   func(AccessEvent)
+	errorHook   func(r *http.Request, name string, err error)
+	errorMapper func(err error) int
+
+	recoverPanics bool
+	panicHook     func(r *http.Request, v any, stack []byte)
+
+	maxFileSize         int64 // if > 0, the size of the largest file to serve
+	maxFileSizeNotFound bool  // respond to requests for larger files with 404
+
+	maxEntries  int // if > 0, the maximum number of cache entries
+	cache       *infoCache
+	sharedCache *SharedCache
+
+	verifyOnServe bool // check served contents against their tags
+
+	opts       []Option // for creating tenants
+	fsSelector func(r *http.Request) fs.FS
+	tenantsMu  sync.Mutex
+	tenants    map[fs.FS]*Server
+
+	generationGrace time.Duration
+	generations     generations
+
+	// done is closed by Close to stop background goroutines.
+	closeOnce sync.Once
+	done      chan struct{}
+	bg        sync.WaitGroup
+
+	// loads coalesces concurrent readInfo calls for the same file so that
+	// a file is only hashed once even if many requests for it arrive
+	// right after it changes. It belongs to the SharedCache, if any.
+	loads *singleflight.Group
+
+	// healthChecks coalesces concurrent calls to Healthy.
+	healthChecks singleflight.Group
 }
 
 type fileInfo struct {
@@ -58,30 +210,234 @@ type fileInfo struct {
 
 	tag         string
 	contentType string
+
+	// body, if non-nil, is the content to serve in place of the file's,
+	// as produced by the Server's transformers.
+	body []byte
+	// deps lists the files referenced by the transformed body, along with
+	// the tags used for them. The info is outdated if any of these tags
+	// change.
+	deps []fileDep
+}
+
+// contentLength returns the length of the content served for the file.
+func (info *fileInfo) contentLength() int64 {
+	if info.body != nil {
+		return int64(len(info.body))
+	}
+	return info.size
+}
+
+// matches reports whether info is up to date with respect to fi.
+func (info *fileInfo) matches(fi fs.FileInfo) bool {
+	return fi.Size() == info.size && fi.ModTime().UnixNano() == info.mtime
 }
 
+// An Option configures a Server. Options are passed to New or NewNoCache.
+type Option func(*Server)
+
 // New creates a Server from a file system.
 //
-// The files that are opened from the file system must implement [io.Seeker].
-// The [fs.FS] implementations which satisfy this requirement include [embed.FS]
-// and the result of calling [os.DirFS].
-func New(fsys fs.FS) *Server {
-	return &Server{
-		fsys:  fsys,
-		cache: make(map[string]*atomic.Pointer[fileInfo]),
-	}
+// The Server works best if the files that are opened from the file system
+// implement [io.Seeker]. The [fs.FS] implementations which satisfy this
+// requirement include [embed.FS] and the result of calling [os.DirFS].
+// Other files are served using [io.ReaderAt], if they implement it, or else
+// by reading the entire file into a buffer (in memory or, for large files, in
+// a temporary file) each time it is opened, which is much more expensive.
+func New(fsys fs.FS, opts ...Option) *Server {
+	return newServer(fsys, false, opts)
 }
 
 // NewNoCache is like New, but the returned Server serves all assets with
 // Cache-Control: no-cache.
 //
 // NewNoCache is intended for non-production settings (such as local development).
-func NewNoCache(fsys fs.FS) *Server {
-	s := New(fsys)
-	s.noCache = true
+func NewNoCache(fsys fs.FS, opts ...Option) *Server {
+	return newServer(fsys, true, opts)
+}
+
+// NewWithFallback is like New, but the returned Server passes the requests
+// that it cannot serve from its file system to next (see [WithFallback]).
+// This lets the Server wrap an entire application, serving assets where
+// they exist:
+//
+//	http.ListenAndServe(addr, assetserver.NewWithFallback(assets, app))
+func NewWithFallback(fsys fs.FS, next http.Handler, opts ...Option) *Server {
+	opts = append(opts[:len(opts):len(opts)], WithFallback(next))
+	return newServer(fsys, false, opts)
+}
+
+// WithImmutableFS indicates that the contents of the file system never change.
+// The Server computes the information about each file (such as its tag) once
+// and thereafter trusts it without calling Stat to check whether the file has
+// been modified.
+//
+// This option is implied if the file system is an [embed.FS] or was created
+// by [ZipFS] or [TarFS].
+func WithImmutableFS() Option {
+	return func(s *Server) {
+		s.immutable = true
+	}
+}
+
+// WithMaxConcurrentHashes limits the number of files that the Server reads and
+// hashes at the same time to n. Further files wait their turn. This prevents a
+// cold start under load (or a deploy which changes many large files) from
+// saturating the CPU and disk.
+func WithMaxConcurrentHashes(n int) Option {
+	return func(s *Server) {
+		if n <= 0 {
+			panic("assetserver: WithMaxConcurrentHashes called with n <= 0")
+		}
+		s.hashSem = make(chan struct{}, n)
+	}
+}
+
+// WithErrorHook makes the Server call fn whenever an error opening, reading,
+// or hashing a file causes it to respond with 500 Internal Server Error (or
+// another 5xx status; see [WithErrorMapper]). The client sees only a generic
+// error message, so this is the place to report the underlying cause (to an
+// error tracking service, for instance). The name is the name of the
+// requested file.
+//
+// If the client goes away while the Server is hashing a file for its request,
+// the Server stops hashing and calls fn with an error wrapping
+// [context.Canceled]; fn may want to ignore such errors.
+func WithErrorHook(fn func(r *http.Request, name string, err error)) Option {
+	return func(s *Server) {
+		s.errorHook = fn
+	}
+}
+
+// WithErrorMapper makes the Server call fn to choose the status code of the
+// response when opening, reading, or hashing a file fails, since different
+// file systems report problems in different ways. For example, a Server
+// whose file system times out might use
+//
+//	WithErrorMapper(func(err error) int {
+//		if errors.Is(err, context.DeadlineExceeded) {
+//			return http.StatusGatewayTimeout
+//		}
+//		return 0
+//	})
+//
+// If fn returns 0 (or anything other than a 4xx or 5xx code), the default
+// status is used: 404 Not Found for errors wrapping [fs.ErrNotExist] and 500
+// Internal Server Error otherwise. Errors which result in a 5xx status are
+// logged and passed to the [WithErrorHook] function, and requests which
+// result in 404 Not Found are forwarded to the [WithProxyFallback] upstream,
+// if any.
+func WithErrorMapper(fn func(err error) int) Option {
+	return func(s *Server) {
+		s.errorMapper = fn
+	}
+}
+
+func newServer(fsys fs.FS, noCache bool, opts []Option) *Server {
+	var immutable bool
+	switch fsys.(type) {
+	case embed.FS, *archiveFS:
+		immutable = true
+	}
+	s := &Server{
+		fsys:      fsys,
+		noCache:   noCache,
+		immutable: immutable,
+		opts:      opts,
+		done:      make(chan struct{}),
+
+		generationGrace: defaultGenerationGrace,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.symlinkPolicy != FollowSymlinks {
+		links, ok := fsys.(ReadLinkFS)
+		if !ok {
+			panic("assetserver: WithSymlinkPolicy used with a file system that does not implement ReadLinkFS")
+		}
+		s.fsys = &symlinkFS{FS: s.fsys, links: links, policy: s.symlinkPolicy}
+	}
+	if s.liveReload != nil && !noCache {
+		panic("assetserver: WithLiveReload used without NewNoCache")
+	}
+	if s.injectURL != "" && s.liveReload == nil {
+		panic("assetserver: WithLiveReloadInjection used without WithLiveReload")
+	}
+	if c := s.sharedCache; c != nil {
+		if s.maxEntries > 0 {
+			panic("assetserver: WithSharedCache used with WithMaxCacheEntries")
+		}
+		if s.cacheFile != "" {
+			panic("assetserver: WithSharedCache used with WithCacheFile")
+		}
+		c.attach(s, fsys)
+		s.cache = c.cache
+		s.loads = &c.loads
+	} else {
+		s.cache = newInfoCache(s.maxEntries)
+		s.loads = new(singleflight.Group)
+	}
+	if ts := s.transforms; ts != nil {
+		s.cache.addEvictHook(ts.forget)
+	}
+	if s.cacheFile != "" {
+		s.loadCache()
+	}
+	s.start()
 	return s
 }
 
+// start launches the background goroutines required by the Server's options.
+func (s *Server) start() {
+	if s.pruneInterval > 0 {
+		s.goBackground(func() { s.pruneLoop(s.pruneInterval) })
+	}
+	if s.cacheFile != "" && s.cacheSaveInterval > 0 {
+		s.goBackground(func() { s.saveCacheLoop(s.cacheSaveInterval) })
+	}
+	if s.revalidate > 0 && !s.immutable {
+		s.goBackground(func() { s.revalidateLoop(s.revalidate) })
+	}
+	if s.pretag > 0 && !s.immutable {
+		files := s.snapshotFiles()
+		s.goBackground(func() { s.pretagLoop(s.pretag, files) })
+	}
+	if s.liveReload != nil {
+		s.liveReload.files = s.snapshotFiles()
+		s.goBackground(s.watchLoop)
+	}
+}
+
+func (s *Server) goBackground(fn func()) {
+	s.bg.Add(1)
+	go func() {
+		defer s.bg.Done()
+		fn()
+	}()
+}
+
+// Close stops any background goroutines started on behalf of the Server's
+// options and waits for them to exit. A Server may continue to serve requests
+// after Close is called, but no further background work will be done.
+//
+// If the Server was created with [WithCacheFile], Close saves the cache to the
+// file and returns any error from doing so. Otherwise, Close always returns
+// nil. It is safe to call Close more than once.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	for _, t := range s.allTenants() {
+		t.Close()
+	}
+	s.closeGenerations()
+	s.bg.Wait()
+	s.history.close()
+	if s.cacheFile != "" {
+		return s.SaveCache()
+	}
+	return nil
+}
+
 // Tag modifies the provided file name to include an asset tag preceding the
 // first dot. The tag is based on a hash of the file contents.
 // File names are slash-separated paths as given to the underlying [fs.FS].
@@ -90,43 +446,113 @@ func NewNoCache(fsys fs.FS) *Server {
 // If s is a no-cache server, Tag returns the original path so that the
 // application uses untagged names in a development environment. However, in
 // this case Tag still verifies that the file exists and can be read in order to
-// catch bugs.
+// catch bugs. The same goes for files whose names already include a hash (see
+// [WithPreHashed]) and for well-known files (see [WithWellKnown]).
 func (s *Server) Tag(name string) (string, error) {
+	if g := s.Current(); g != s {
+		return g.Tag(name)
+	}
 	origName := name
 	name = strings.TrimPrefix(name, "/")
-	// Happy path: only call stat.
-	info, err := s.tryCachedInfo(name)
-	if err != nil {
-		if err != errNoInfo {
+	if s.isWellKnown(name) {
+		if _, err := fs.Stat(s.wellKnownFS(), name); err != nil {
 			return "", err
 		}
-		// No cached info (or it's out of date). Recompute.
-		var f seekerFile
-		f, info, err = s.openWithInfo(name)
-		if err != nil {
+		return origName, nil
+	}
+	info, err := s.currentInfo(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+	if s.noCache || s.isPreHashed(name) {
+		return origName, nil
+	}
+	if s.duplicates != nil {
+		if name, err = s.canonicalName(context.Background(), name, info); err != nil {
 			return "", err
 		}
-		f.Close()
 	}
-	if s.noCache {
-		return origName, nil
+	tagged := name
+	if !s.isPreHashed(name) {
+		tagged = insertTag(name, info.tag)
+	}
+	if strings.HasPrefix(origName, "/") {
+		tagged = "/" + tagged
+	}
+	return tagged, nil
+}
+
+// ReadFile returns the contents of the named file as the Server serves them,
+// after any transformations (see [WithTransform]). This is useful for
+// exporting the Server's output, such as HTML rewritten to use tagged names,
+// at build time. As with Tag, a leading slash is removed from the name.
+func (s *Server) ReadFile(name string) ([]byte, error) {
+	if g := s.Current(); g != s {
+		return g.ReadFile(name)
 	}
+	b, _, err := s.readFile(context.Background(), strings.TrimPrefix(name, "/"))
+	return b, err
+}
+
+// readFile returns the served contents of the named file and its info.
+func (s *Server) readFile(ctx context.Context, name string) ([]byte, *fileInfo, error) {
+	f, info, err := s.openWithInfo(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if info.body != nil {
+		return bytes.Clone(info.body), info, nil
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, info, nil
+}
+
+// currentInfo returns up-to-date info for the named file, computing it if
+// necessary.
+func (s *Server) currentInfo(ctx context.Context, name string) (*fileInfo, error) {
+	// Happy path: only call stat.
+	info, fi, err := s.statCachedInfo(ctx, name)
+	if err == nil {
+		s.reportCacheLookup(ctx, true)
+		return info, nil
+	}
+	if err != errNoInfo {
+		return nil, err
+	}
+	// No cached info (or it's out of date). Recompute, without opening
+	// the file if the file system can read it whole.
+	if fi != nil && s.readsWhole(fi) {
+		if info, err := s.readWithInfo(ctx, name, fi); err != errNoInfo {
+			return info, err
+		}
+	}
+	f, info, err := s.openWithInfo(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return info, nil
+}
+
+// insertTag inserts tag into the last element of the slash-separated path
+// name.
+func insertTag(name, tag string) string {
 	dir, base := path.Split(name)
 	// We place the tag before the first dot (rather than before the last
 	// dot) because files may have multiple extensions: "x.tar.gz",
 	// "lib.min.js", etc.
 	if head, tail, ok := strings.Cut(base, "."); ok {
 		// head.xxxxxxxxxxxxx.tail
-		base = head + "." + info.tag + "." + tail
+		base = head + "." + tag + "." + tail
 	} else {
 		// head.xxxxxxxxxxxxx
-		base += "." + info.tag
+		base += "." + tag
 	}
-	tagged := path.Join(dir, base)
-	if strings.HasPrefix(origName, "/") {
-		tagged = "/" + tagged
-	}
-	return tagged, nil
+	return dir + base
 }
 
 // removeTag looks for an asset tag as part of a file name and returns the tag
@@ -208,35 +634,89 @@ func isTag(s string) bool {
 	return true
 }
 
-type seekerFile interface {
-	fs.File
-	io.Seeker
-}
-
 var errNoInfo = errors.New("cached info for file is out of date or nonexistent")
 
 // tryCachedInfo returns the cached info for the named file if it matches the
 // contents of the file as gauged by the size and mtime.
 // Otherwise it returns errNoInfo.
-func (s *Server) tryCachedInfo(name string) (*fileInfo, error) {
+func (s *Server) tryCachedInfo(ctx context.Context, name string) (*fileInfo, error) {
+	info, _, err := s.statCachedInfo(ctx, name)
+	return info, err
+}
+
+// statCachedInfo is like tryCachedInfo, but if it returns errNoInfo it also
+// returns the result of its Stat call (if it made one) describing the file.
+func (s *Server) statCachedInfo(ctx context.Context, name string) (*fileInfo, fs.FileInfo, error) {
+	if s.trustCache() {
+		if info := s.cachedInfo(name); info != nil {
+			return info, nil, nil
+		}
+	}
+	timing := s.timing(ctx)
+	start := timing.start()
 	fi, err := fs.Stat(s.fsys, name)
+	timing.add(phaseStat, start)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, fs.ErrNotExist) {
+			s.evict(name)
+		}
+		return nil, nil, err
 	}
 	if fi.IsDir() {
-		return nil, fs.ErrNotExist
+		s.evict(name)
+		return nil, nil, fs.ErrNotExist
 	}
-	s.mu.RLock()
-	p, ok := s.cache[name]
-	s.mu.RUnlock()
-	if !ok {
-		return nil, errNoInfo
+	p := s.cache.lookup(name)
+	if p == nil {
+		return nil, fi, errNoInfo
 	}
 	info := p.Load()
-	if info == nil || fi.Size() != info.size || fi.ModTime().UnixNano() != info.mtime {
-		return nil, errNoInfo
+	if info == nil || !info.matches(fi) || !s.depsCurrent(ctx, info) {
+		return nil, fi, errNoInfo
 	}
-	return info, nil
+	p.markValidated()
+	return info, nil, nil
+}
+
+// readWithInfo computes and caches the info for the named file, described by
+// fi, reading the file with ReadFile (see readsWhole) rather than opening it.
+// It returns errNoInfo if it could not do so and the caller should use
+// openWithInfo instead.
+func (s *Server) readWithInfo(ctx context.Context, name string, fi fs.FileInfo) (*fileInfo, error) {
+	if err := s.checkSize(ctx, name, fi.Size()); err != nil {
+		return nil, err
+	}
+	s.reportCacheLookup(ctx, false)
+	p := s.cache.entry(name)
+	if info := p.Load(); info != nil {
+		s.log(ctx, slog.LevelInfo, "file changed; cached info is outdated",
+			"name", name, "tag", info.tag)
+	}
+	timing := s.timing(ctx)
+	start := timing.start()
+	defer timing.add(phaseHash, start)
+	v, err, shared := s.loads.Do(name, func() (any, error) {
+		f, err := s.openWhole(name, fi)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		info, err := s.readInfo(ctx, name, f)
+		if err != nil {
+			return nil, err
+		}
+		s.storeInfo(p, name, info)
+		return info, nil
+	})
+	if err != nil {
+		if shared && isContextError(err) && ctx.Err() == nil {
+			// A concurrent load was canceled because its client
+			// went away; hash the file ourselves.
+			return nil, errNoInfo
+		}
+		return nil, err
+	}
+	return v.(*fileInfo), nil
 }
 
 // openWithInfo opens the named file and also retrieves its fileInfo summary,
@@ -244,56 +724,108 @@ func (s *Server) tryCachedInfo(name string) (*fileInfo, error) {
 // The info matches the contents of the file, as gauged by the size and mtime,
 // unless the file is changing as it is being read (in which case all bets are
 // off).
-func (s *Server) openWithInfo(name string) (f seekerFile, info *fileInfo, err error) {
+func (s *Server) openWithInfo(ctx context.Context, name string) (f seekerFile, info *fileInfo, err error) {
+	timing := s.timing(ctx)
+	start := timing.start()
 	fv, err := s.fsys.Open(name)
+	timing.add(phaseOpen, start)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			s.evict(name)
+		}
 		return nil, nil, err
 	}
+	var toClose io.Closer = fv
 	defer func() {
 		if err != nil {
-			fv.Close()
+			toClose.Close()
 		}
 	}()
+	if s.trustCache() {
+		if info = s.cachedInfo(name); info != nil {
+			if f, err = toSeeker(fv); err != nil {
+				return nil, nil, err
+			}
+			s.reportCacheLookup(ctx, true)
+			return f, info, nil
+		}
+	}
+	start = timing.start()
 	fi, err := fv.Stat()
+	timing.add(phaseStat, start)
 	if err != nil {
 		return nil, nil, err
 	}
 	if fi.IsDir() {
+		s.evict(name)
 		return nil, nil, fs.ErrNotExist
 	}
-	f = fv.(seekerFile)
-	s.mu.RLock()
-	p, ok := s.cache[name]
-	s.mu.RUnlock()
-	if !ok {
-		s.mu.Lock()
-		p, ok = s.cache[name]
-		if !ok {
-			p = new(atomic.Pointer[fileInfo])
-			s.cache[name] = p
-		}
-		s.mu.Unlock()
+	if err := s.checkSize(ctx, name, fi.Size()); err != nil {
+		return nil, nil, err
+	}
+	if f, err = toSeeker(fv); err != nil {
+		return nil, nil, err
 	}
+	toClose = f
+	p := s.cache.entry(name)
 
 	info = p.Load()
-	if info != nil && fi.Size() == info.size && fi.ModTime().UnixNano() == info.mtime {
+	if info != nil && info.matches(fi) && s.depsCurrent(ctx, info) {
+		p.markValidated()
+		s.reportCacheLookup(ctx, true)
 		return f, info, nil
 	}
+	s.reportCacheLookup(ctx, false)
+	if info != nil {
+		s.log(ctx, slog.LevelInfo, "file changed; cached info is outdated",
+			"name", name, "tag", info.tag)
+	}
 
 	// The info doesn't match. Reload it from the file and then store it in
 	// the cache.
-	info, err = s.readInfo(f)
-	if err != nil {
+	start = timing.start()
+	defer timing.add(phaseHash, start)
+	v, err, shared := s.loads.Do(name, func() (any, error) {
+		info, err := s.readInfo(ctx, name, f)
+		if err != nil {
+			return nil, err
+		}
+		s.storeInfo(p, name, info)
+		return info, nil
+	})
+	if err != nil && !(shared && isContextError(err) && ctx.Err() == nil) {
 		return nil, nil, err
 	}
+	if err == nil {
+		info = v.(*fileInfo)
+	}
+	if err != nil || !info.matches(fi) {
+		// We shared the result of a concurrent load which was either
+		// canceled (because its client went away) or read a different
+		// version of the file than the one we opened. Hash our own
+		// copy.
+		info, err = s.readInfo(ctx, name, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.storeInfo(p, name, info)
+	}
 	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return nil, nil, err
 	}
-	p.Store(info)
 	return f, info, nil
 }
 
-func (s *Server) readInfo(f seekerFile) (*fileInfo, error) {
+func (s *Server) readInfo(ctx context.Context, name string, f seekerFile) (info *fileInfo, err error) {
+	if s.transforms.applies(name) {
+		return s.readTransformedInfo(ctx, name, f)
+	}
+	release, err := s.acquireHashSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer s.reportHash(ctx, name)(&err)
 	stat, err := f.Stat()
 	if err != nil {
 		return nil, err
@@ -303,38 +835,164 @@ func (s *Server) readInfo(f seekerFile) (*fileInfo, error) {
 		size:  stat.Size(),
 	}
 
-	h := sha256.New()
-	fi.contentType = mime.TypeByExtension(path.Ext(stat.Name()))
-	if fi.contentType != "" {
-		if _, err := io.Copy(h, f); err != nil {
+	hs := hashStatePool.Get().(*hashState)
+	defer hs.release()
+	h := hs.h
+	var r io.Reader = ctxReader{ctx, f}
+	partial := s.partialHash != nil && s.partialHash.applies(name, fi.size)
+	if partial {
+		s.partialHash.writeHeader(h, fi)
+		r = io.LimitReader(f, s.partialHash.n)
+	}
+	fi.contentType = typeByExtension(path.Ext(stat.Name()))
+	if fi.contentType == "" {
+		sniff := s.sniffBuffer(hs)
+		n, err := io.ReadFull(r, sniff)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return nil, err
 		}
-	} else {
-		var sniffBuf bytes.Buffer
-		// http.DetectContentType uses at most 512 bytes.
-		_, err := io.CopyN(io.MultiWriter(h, &sniffBuf), f, 512)
-		switch err {
-		case nil:
-			// There's more data to hash.
-			if _, err := io.Copy(h, f); err != nil {
-				return nil, err
-			}
-		case io.EOF:
-			// Read the whole file.
-		default:
+		h.Write(sniff[:n])
+		fi.contentType = s.sniffContentType(name, sniff[:n])
+	}
+	if err := hs.copy(r); err != nil {
+		return nil, err
+	}
+	if partial {
+		if err := s.partialHash.hashTail(hs, f); err != nil {
 			return nil, err
 		}
-		fi.contentType = http.DetectContentType(sniffBuf.Bytes())
 	}
-	fi.tag = makeTag(h.Sum(nil))
+	fi.tag = s.typedTag(makeTag(h.Sum(nil)), fi.contentType)
 	return fi, nil
 }
 
+// acquireHashSlot waits until the number of concurrent hashes is below the
+// limit set by WithMaxConcurrentHashes, if any. It returns a function to
+// call when the hash is done, or ctx's error if ctx is done first.
+func (s *Server) acquireHashSlot(ctx context.Context) (release func(), err error) {
+	if s.hashSem == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.hashSem <- struct{}{}:
+		return func() { <-s.hashSem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// A ctxReader is a Reader which fails with the context's error once the
+// context is done. Hashing a large file for a request reads through a
+// ctxReader so that it stops promptly if the client goes away.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// A hashState holds the reusable state for hashing a file in readInfo.
+// Pooling these avoids allocations during revalidation storms (such as when
+// many files change at once after a deploy).
+type hashState struct {
+	h   hash.Hash
+	buf []byte
+}
+
+var hashStatePool = sync.Pool{
+	New: func() any {
+		return &hashState{
+			h:   sha256.New(),
+			buf: make([]byte, 32*1024),
+		}
+	},
+}
+
+// copy writes the contents of r into the hash.
+func (hs *hashState) copy(r io.Reader) error {
+	// Hide any WriteTo method (such as *os.File's) so that io.CopyBuffer
+	// uses our buffer rather than allocating its own.
+	_, err := io.CopyBuffer(hs.h, struct{ io.Reader }{r}, hs.buf)
+	return err
+}
+
+func (hs *hashState) release() {
+	hs.h.Reset()
+	hashStatePool.Put(hs)
+}
+
 // ServeHTTP serves file system contents matching the request.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" && r.Method != "HEAD" {
-		w.Header().Set("Allow", "GET,HEAD")
-		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+	if t := s.tenant(r); t != nil {
+		t.ServeHTTP(w, r)
+		return
+	}
+	if g := s.generationFor(r); g != s {
+		g.ServeHTTP(w, r)
+		return
+	}
+	r = withAssetInfoSlot(r)
+	var rec *responseRecorder
+	if len(s.metrics) > 0 || len(s.traces) > 0 || s.accessLog != nil || s.recoverPanics || len(s.serveHooks) > 0 {
+		rec = &responseRecorder{ResponseWriter: w}
+		if s.accessLog != nil {
+			defer s.logAccess(r, r.URL.Path, rec, time.Now())
+		}
+		defer s.reportRequest(rec)
+		if len(s.serveHooks) > 0 {
+			defer s.watchServe(r, rec)()
+		}
+		if len(s.traces) > 0 {
+			var endTrace func()
+			r, endTrace = s.startTrace(r, rec)
+			defer endTrace()
+		}
+		w = rec
+	}
+	if s.serverTiming {
+		t := new(serverTiming)
+		r = r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, t))
+		w = &timingWriter{ResponseWriter: w, t: t}
+	}
+	if s.recoverPanics {
+		defer s.recoverPanic(w, r, rec)
+	}
+	s.serveHTTP(w, r, rec)
+}
+
+// serveHTTP serves a request. If rec is non-nil, it records the response
+// (and is either w or wrapped by w).
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request, rec *responseRecorder) {
+	if s.releaseHeader != "" {
+		w.Header().Set(s.releaseHeader, s.release)
+	}
+	if s.timingAllowOrigin != "" {
+		w.Header().Set("Timing-Allow-Origin", s.timingAllowOrigin)
+	}
+	s.addCrossOriginHeaders(w.Header())
+	s.addCORSHeaders(w, r)
+	if s.proxyAll {
+		s.forward(w, r)
+		return
+	}
+	if !s.acceptsMethod(r.Method) {
+		if s.proxyUnmatched(w, r) {
+			return
+		}
+		s.serveMethodNotAllowed(w, r)
+		return
+	}
+	if r.Method == "OPTIONS" {
+		s.serveOptions(w)
 		return
 	}
 
@@ -346,20 +1004,113 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	pth = path.Clean(pth)
 
 	if pth == "/" {
-		http.NotFound(w, r)
+		if !s.proxyUnmatched(w, r) {
+			http.NotFound(w, r)
+		}
 		return
 	}
 
-	tag, taglessPath := removeTag(pth)
-	f, info, err := s.openWithInfo(taglessPath[1:]) // trim leading /
+	var tag, taglessPath string
+	if s.isWellKnown(pth[1:]) {
+		taglessPath = pth
+	} else {
+		tag, taglessPath = removeTag(pth)
+	}
+	name := taglessPath[1:] // trim leading /
+	if rec != nil {
+		rec.name, rec.tag = name, tag
+	}
+	if !validName(name) {
+		s.writeFSError(w, r, name, errInvalidName)
+		return
+	}
+	s.setTraceAttribute(r.Context(), "assetserver.name", name)
+	if !s.rateLimit.check(w, r, name) {
+		return
+	}
+	if s.auth != nil && !s.auth.check(w, r, name) {
+		return
+	}
+	if s.isWellKnown(name) {
+		s.serveWellKnown(w, r, name)
+		return
+	}
+	if s.precache != nil && tag == "" && name == s.precache.Name {
+		s.servePrecacheManifest(w, r)
+		return
+	}
+	s.maybeRefresh(r, name)
+	if s.imageResizer.requested(r, name) {
+		s.serveResizedImage(w, r, name, tag)
+		return
+	}
+	if variant, variantTag := s.negotiateImage(r.Context(), r, w.Header(), name, tag); variant != "" {
+		name, tag = variant, variantTag
+		if rec != nil {
+			rec.name = name
+		}
+	}
+	if tag == "" {
+		if variant := s.negotiateLanguage(r.Context(), r, w.Header(), name); variant != "" {
+			name = variant
+			if rec != nil {
+				rec.name = name
+			}
+		}
+	}
+	// Errors (and so the fallback handler, if any) get the request and
+	// ResponseWriter as they were, since they aren't for the named file.
+	origW, origR := w, r
+	if s.noRanges.applies(name) {
+		r = withoutRangeHeaders(r)
+		w = &noRangesWriter{ResponseWriter: w}
+	}
+	if limiters := s.throttleLimiters(name); len(limiters) > 0 {
+		w = newThrottledWriter(r.Context(), w, limiters)
+	}
+	// If possible, answer using only the cached info without opening the
+	// file. Otherwise, f is non-nil.
+	var f seekerFile
+	info, err := s.infoWithoutOpen(r, name)
 	if err != nil {
-		writeFSError(w, r, err)
+		if errors.Is(err, fs.ErrNotExist) && (s.serveVersion(w, r, name, tag) || s.redirectCase(w, r, name, tag)) {
+			return
+		}
+		s.writeFSError(origW, origR, name, err)
 		return
 	}
-	defer f.Close()
+	// Files which are already compressed aren't compressed again.
+	inherent := inherentEncoding(name)
+	compress := s.precompressed.applies(name) && inherent == ""
+	if info != nil && r.Method == "HEAD" && (s.injects(info) || compress) {
+		// The file's size may not match the response's.
+		info = nil
+	}
+	if info == nil && s.streamingHash && s.injectURL == "" && !compress && tag == "" && !strings.HasSuffix(r.URL.Path, "/") && isPlainGet(r) {
+		if s.serveStreamingHash(w, r, name, name != taglessPath[1:]) {
+			return
+		}
+	}
+	if info == nil {
+		f, info, err = s.openWithInfo(r.Context(), name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && (s.serveVersion(w, r, name, tag) || s.redirectCase(w, r, name, tag)) {
+				return
+			}
+			s.writeFSError(origW, origR, name, err)
+			return
+		}
+		defer f.Close()
+	}
+	s.setFileTraceAttributes(r.Context(), info)
 	// If the tag is wrong/outdated, 404.
 	if tag != "" && tag != info.tag {
-		http.NotFound(w, r)
+		if s.serveVersion(w, r, name, tag) {
+			return
+		}
+		s.log(r.Context(), slog.LevelInfo, "request tag does not match file",
+			"name", name, "tag", tag, "current_tag", info.tag)
+		s.serveErrorPage(w, r, http.StatusNotFound, name, tag, errTagMismatch)
 		return
 	}
 
@@ -367,28 +1118,72 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if strings.HasSuffix(r.URL.Path, "/") {
 		// We cannot use http.Redirect because it changes the path to be
 		// absolute and that doesn't work if we're running under http.StripPrefix.
-		target := "../" + path.Base(pth)
+		// (s.redirect makes it absolute using the original request URI
+		// if WithAbsoluteRedirects is set.)
+		target := "../" + escapePath(path.Base(pth))
 		if q := r.URL.RawQuery; q != "" {
 			target += "?" + q
 		}
-		w.Header().Set("Location", target)
-		w.WriteHeader(http.StatusPermanentRedirect)
+		s.redirect(w, r, target)
 		return
 	}
 
+	setAssetInfo(r.Context(), &AssetInfo{
+		Name:        name,
+		Tag:         info.tag,
+		Size:        info.contentLength(),
+		ContentType: info.contentType,
+	})
 	h := w.Header()
-	var cc string
-	if s.noCache {
-		cc = "no-cache"
-	} else {
-		if tag == "" {
-			cc = "public, max-age=60"
-		} else {
-			cc = "public, max-age=31536000, immutable"
+	s.setFileHeaders(r, h, name, tag, info, name != taglessPath[1:])
+	verify := s.verifies(name, info)
+	if compress && info.body == nil && !s.injects(info) {
+		h.Add("Vary", "Accept-Encoding")
+		if f != nil {
+			if cf, encoding := s.openPrecompressed(r, name, info); cf != nil {
+				defer cf.Close()
+				h.Set("Content-Encoding", encoding)
+				h.Set("ETag", `"`+info.tag+"-"+encoding+`"`)
+				f = cf
+				verify = false
+			}
 		}
 	}
-	h.Set("Cache-Control", cc)
-	h.Set("ETag", `"`+info.tag+`"`)
+
+	if f == nil {
+		serveWithoutBody(w, r, info)
+		return
+	}
+	// Unless it was transformed or is checked (see WithVerifyOnServe),
+	// pass the file to ServeContent as-is: when it is an *os.File (as with
+	// os.DirFS), net/http can then use sendfile to copy it to the
+	// connection without the contents passing through user space.
+	var content io.ReadSeeker = f
+	if info.body != nil {
+		content = bytes.NewReader(info.body)
+	} else if verify {
+		content = s.newVerifyingReader(r, name, info, f)
+	}
+	if s.injects(info) {
+		s.serveInjected(w, r, name, info, content)
+		return
+	}
+	http.ServeContent(w, r, pth, time.Unix(0, info.mtime), content)
+}
+
+// setFileHeaders sets the headers of a response for the named file, which
+// was requested with the given tag (if any) and is described by info: the
+// Cache-Control, ETag (unless info has no tag yet), Content-Type, and
+// Content-Encoding (for files which are compressed themselves) headers, along
+// with the SourceMap and Link headers called for by the Server's options. If
+// negotiated is true, the file is a variant (see WithImageNegotiation and
+// WithLanguageNegotiation) served at the URL of the original, so it has no
+// canonical link of its own.
+func (s *Server) setFileHeaders(r *http.Request, h http.Header, name, tag string, info *fileInfo, negotiated bool) {
+	h.Set("Cache-Control", s.responseCacheControl(r, name, tag))
+	if info.tag != "" {
+		h.Set("ETag", `"`+info.tag+`"`)
+	}
 	// Only set Content-Type if it wasn't set by the caller.
 	if _, ok := h["Content-Type"]; !ok {
 		if info.contentType != "" {
@@ -397,17 +1192,61 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h["Content-Type"] = nil // prevent ServeContent from sniffing
 		}
 	}
+	if encoding := inherentEncoding(name); encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	if s.sourceMaps {
+		if u := s.sourceMapURL(r.Context(), name); u != "" {
+			h.Set("SourceMap", u)
+		}
+	}
+	s.addPreloadLinks(r.Context(), h, name)
+	if !negotiated {
+		s.addCanonicalLink(r.Context(), h, name, tag, info.tag)
+	}
+}
+
+// cacheControl returns the Cache-Control header value for a response for the
+// named file. The tag is the one given in the request, if any.
+func (s *Server) cacheControl(name, tag string) string {
+	if s.noCache {
+		return "no-cache"
+	}
+	if tag == "" && !s.isImmutable(name) {
+		return "public, max-age=60"
+	}
+	return s.immutableCacheControl(name)
+}
 
-	http.ServeContent(w, r, pth, time.Unix(0, info.mtime), f)
+func (s *Server) writeFSError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	code := s.errorStatus(err)
+	switch {
+	case code == http.StatusNotFound:
+		if s.proxyUnmatched(w, r) {
+			return
+		}
+	case code >= 500:
+		s.log(r.Context(), slog.LevelError, "error serving file", "name", name, "err", err)
+		if s.errorHook != nil {
+			s.errorHook(r, name, err)
+		}
+	}
+	s.serveErrorPage(w, r, code, name, "", err)
 }
 
-func writeFSError(w http.ResponseWriter, r *http.Request, err error) {
+// errorStatus returns the status code of the response for a file system
+// error.
+func (s *Server) errorStatus(err error) int {
+	if s.errorMapper != nil {
+		if code := s.errorMapper(err); code >= 400 && code <= 599 {
+			return code
+		}
+	}
 	if errors.Is(err, fs.ErrNotExist) {
-		http.NotFound(w, r)
-		return
+		return http.StatusNotFound
 	}
 	// Don't turn permission errors into 403s here like FileServer does.
 	// That generally isn't helpful in this domain and it leaks information
 	// about a misconfiguration in the system.
-	http.Error(w, "500 Internal Server Error", 500)
+	return http.StatusInternalServerError
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"embed"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -13,6 +14,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -132,6 +134,38 @@ func TestServeHTTPEmbed(t *testing.T) {
 	webtest.TestHandler(t, "testdata/servehttp.txt", s)
 }
 
+func TestEmbedImpliesImmutable(t *testing.T) {
+	if !New(embedFS).immutable {
+		t.Error("Server using embed.FS is not marked immutable")
+	}
+}
+
+func TestImmutableFS(t *testing.T) {
+	for _, immutable := range []bool{false, true} {
+		fsys := fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+		}
+		var opts []Option
+		if immutable {
+			opts = append(opts, WithImmutableFS())
+		}
+		s := New(fsys, opts...)
+		tag0, err := s.Tag("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["a.txt"].Data = []byte("b")
+		fsys["a.txt"].ModTime = time.Now()
+		tag1, err := s.Tag("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed := tag0 != tag1; changed == immutable {
+			t.Errorf("immutable=%t: tag changed from %q to %q", immutable, tag0, tag1)
+		}
+	}
+}
+
 func TestServeHTTPNoCache(t *testing.T) {
 	s := NewNoCache(os.DirFS("testdata/assets"))
 	webtest.TestHandler(t, "testdata/nocache.txt", s)
@@ -314,6 +348,353 @@ func TestServeLargeFiles(t *testing.T) {
 	checkResponseHeader(t, resp, "ETag", `"`+tag+`"`)
 }
 
+// Check that concurrent loads of the same file only hash it once.
+func TestConcurrentLoads(t *testing.T) {
+	fsys := &blockingFS{
+		FS: fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("hello")},
+		},
+		release: make(chan struct{}),
+	}
+	s := New(fsys)
+	const n = 10
+	var eg errgroup.Group
+	for i := 0; i < n; i++ {
+		eg.Go(func() error {
+			_, err := s.Tag("a.txt")
+			return err
+		})
+	}
+	for fsys.opens.Load() < n {
+		time.Sleep(time.Millisecond)
+	}
+	// Give the goroutines a moment to reach the point of hashing.
+	time.Sleep(10 * time.Millisecond)
+	close(fsys.release)
+	if err := eg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.reads.Load(); got != 1 {
+		t.Fatalf("file was read by %d callers; want 1", got)
+	}
+}
+
+func TestMaxConcurrentHashes(t *testing.T) {
+	fsys := &blockingFS{
+		FS: fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+			"b.txt": &fstest.MapFile{Data: []byte("b")},
+		},
+		release: make(chan struct{}),
+	}
+	s := New(fsys, WithMaxConcurrentHashes(1))
+	var eg errgroup.Group
+	for _, name := range []string{"a.txt", "b.txt"} {
+		name := name
+		eg.Go(func() error {
+			_, err := s.Tag(name)
+			return err
+		})
+	}
+	for fsys.opens.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := fsys.reads.Load(); got != 1 {
+		t.Fatalf("%d files being read concurrently; want 1", got)
+	}
+	close(fsys.release)
+	if err := eg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.reads.Load(); got != 2 {
+		t.Fatalf("%d files were read; want 2", got)
+	}
+}
+
+// blockingFS is an fs.FS whose files block on their first Read until release
+// is closed.
+type blockingFS struct {
+	fs.FS
+	release chan struct{}
+	opens   atomic.Int64
+	reads   atomic.Int64
+}
+
+func (fsys *blockingFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fsys.opens.Add(1)
+	return &blockingFile{seekerFile: f.(seekerFile), fsys: fsys}, nil
+}
+
+type blockingFile struct {
+	seekerFile
+	fsys *blockingFS
+	read bool
+}
+
+func (f *blockingFile) Read(b []byte) (int, error) {
+	if !f.read {
+		f.read = true
+		f.fsys.reads.Add(1)
+		<-f.fsys.release
+	}
+	return f.seekerFile.Read(b)
+}
+
+func TestErrorHook(t *testing.T) {
+	errDisk := errors.New("disk on fire")
+	errRead := errors.New("bad sector")
+	fsys := errorFS{
+		FS: readErrorFS{
+			FS: fstest.MapFS{
+				"a.txt": &fstest.MapFile{Data: []byte("a")},
+				"b.txt": &fstest.MapFile{Data: []byte("b")},
+				"c.txt": &fstest.MapFile{Data: []byte("c")},
+			},
+			errs: map[string]error{"c.txt": errRead},
+		},
+		errs: map[string]error{"b.txt": errDisk},
+	}
+	type hookCall struct {
+		path string
+		name string
+		err  error
+	}
+	var calls []hookCall
+	s := New(fsys, WithErrorHook(func(r *http.Request, name string, err error) {
+		calls = append(calls, hookCall{r.URL.Path, name, err})
+	}))
+	for _, target := range []string{"/a.txt", "/b.txt", "/c.txt", "/d.txt"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	if len(calls) != 2 {
+		t.Fatalf("got %d error hook calls; want 2", len(calls))
+	}
+	for i, want := range []hookCall{
+		{"/b.txt", "b.txt", errDisk},
+		{"/c.txt", "c.txt", errRead},
+	} {
+		got := calls[i]
+		if got.path != want.path || got.name != want.name || !errors.Is(got.err, want.err) {
+			t.Errorf("call %d: got (%q, %q, %v); want (%q, %q, %v)",
+				i, got.path, got.name, got.err, want.path, want.name, want.err)
+		}
+	}
+}
+
+func TestErrorMapper(t *testing.T) {
+	fsys := errorFS{
+		FS: fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+		},
+		errs: map[string]error{
+			"perm.txt":    fs.ErrPermission,
+			"timeout.txt": context.DeadlineExceeded,
+			"other.txt":   errors.New("disk on fire"),
+		},
+	}
+	var hookCalls []string
+	s := New(fsys,
+		WithErrorMapper(func(err error) int {
+			switch {
+			case errors.Is(err, fs.ErrPermission):
+				return http.StatusForbidden
+			case errors.Is(err, context.DeadlineExceeded):
+				return http.StatusGatewayTimeout
+			case errors.Is(err, fs.ErrNotExist):
+				return 200 // invalid; ignored
+			}
+			return 0
+		}),
+		WithErrorHook(func(r *http.Request, name string, err error) {
+			hookCalls = append(hookCalls, name)
+		}),
+	)
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{"/a.txt", 200},
+		{"/perm.txt", 403},
+		{"/timeout.txt", 504},
+		{"/other.txt", 500},
+		{"/nonexistent.txt", 404},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.code)
+		}
+	}
+	if diff := cmp.Diff([]string{"timeout.txt", "other.txt"}, hookCalls); diff != "" {
+		t.Errorf("error hook calls (-want +got):\n%s", diff)
+	}
+}
+
+func TestHashCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var reads atomic.Int64
+	fsys := cancelFS{
+		FS: fstest.MapFS{
+			"big.bin": &fstest.MapFile{Data: make([]byte, 10<<20)},
+		},
+		read: func() {
+			// The client goes away after the first read.
+			reads.Add(1)
+			cancel()
+		},
+	}
+	var hookErr error
+	s := New(fsys, WithErrorHook(func(r *http.Request, name string, err error) {
+		hookErr = err
+	}))
+	r := httptest.NewRequest("GET", "/big.bin", nil).WithContext(ctx)
+	s.ServeHTTP(httptest.NewRecorder(), r)
+	if n := reads.Load(); n != 1 {
+		t.Errorf("got %d reads after cancellation; want 1", n)
+	}
+	if !errors.Is(hookErr, context.Canceled) {
+		t.Errorf("error hook got %v; want context.Canceled", hookErr)
+	}
+
+	// The canceled hash isn't cached, so another request works.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/big.bin", nil))
+	if w.Code != 200 || w.Body.Len() != 10<<20 {
+		t.Errorf("got status %d and %d bytes; want 200 and %d bytes", w.Code, w.Body.Len(), 10<<20)
+	}
+}
+
+// A cancelFS calls read before each read from its files.
+type cancelFS struct {
+	fs.FS
+	read func()
+}
+
+func (fsys cancelFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return cancelFile{f.(seekerFile), fsys.read}, nil
+}
+
+type cancelFile struct {
+	seekerFile
+	read func()
+}
+
+func (f cancelFile) Read(p []byte) (int, error) {
+	f.read()
+	return f.seekerFile.Read(p)
+}
+
+// readErrorFS wraps an FS so that reading certain files fails.
+type readErrorFS struct {
+	fs.FS
+	errs map[string]error
+}
+
+func (fsys readErrorFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := fsys.errs[name]; ok {
+		return readErrorFile{f, err}, nil
+	}
+	return f, nil
+}
+
+type readErrorFile struct {
+	fs.File
+	err error
+}
+
+func (f readErrorFile) Read([]byte) (int, error) { return 0, f.err }
+
+// Check that files from os.DirFS reach the ResponseWriter as *os.Files so
+// that net/http can use sendfile.
+func TestServeOSFile(t *testing.T) {
+	s := New(os.DirFS("testdata/assets"))
+	w := &readFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	req := httptest.NewRequest("GET", "/a.js", nil)
+	s.ServeHTTP(w, req)
+	checkResponseCode(t, w.Result(), 200)
+	lr, ok := w.src.(*io.LimitedReader)
+	if !ok {
+		t.Fatalf("ReadFrom called with %T; want *io.LimitedReader", w.src)
+	}
+	if _, ok := lr.R.(*os.File); !ok {
+		t.Fatalf("ReadFrom called with a LimitedReader wrapping %T; want *os.File", lr.R)
+	}
+}
+
+// readFromRecorder is a ResponseRecorder which implements io.ReaderFrom (like
+// the ResponseWriter of net/http's server) and records its argument.
+type readFromRecorder struct {
+	*httptest.ResponseRecorder
+	src io.Reader
+}
+
+func (w *readFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.src = r
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func BenchmarkServeLargeFile(b *testing.B) {
+	dir := b.TempDir()
+	const size = 64 << 20
+	if err := os.WriteFile(filepath.Join(dir, "large.bin"), make([]byte, size), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	server := httptest.NewServer(New(os.DirFS(dir)))
+	defer server.Close()
+	url := server.URL + "/large.bin"
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := http.Get(url)
+		if err != nil {
+			b.Fatal(err)
+		}
+		n, err := io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n != size {
+			b.Fatalf("read %d bytes; want %d", n, size)
+		}
+	}
+}
+
+func BenchmarkReadInfo(b *testing.B) {
+	s := New(os.DirFS("testdata/assets"))
+	for _, name := range []string{"a.js", "d/sub/noext"} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f, err := s.fsys.Open(name)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := s.readInfo(context.Background(), name, f.(seekerFile)); err != nil {
+					b.Fatal(err)
+				}
+				f.Close()
+			}
+		})
+	}
+}
+
 func checkResponseCode(t *testing.T, resp *http.Response, want int) {
 	t.Helper()
 	if resp.StatusCode != want {
@@ -0,0 +1,129 @@
+// Package assetservermetrics provides Prometheus metrics for assetserver.
+//
+// Create a Collector, register it, and pass its Option to assetserver.New:
+//
+//	c := assetservermetrics.NewCollector()
+//	prometheus.MustRegister(c)
+//	assets := assetserver.New(fsys, c.Option())
+package assetservermetrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cespare/assetserver"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A Collector is a prometheus.Collector which reports metrics about the
+// Servers it is bound to (using Option).
+//
+// The metrics are:
+//
+//   - assetserver_requests_total (counter, labeled by code): requests served
+//   - assetserver_response_bytes_total (counter): response body bytes written
+//   - assetserver_cache_lookups_total (counter, labeled by result, which is
+//     "hit" or "miss"): lookups of cached file information
+//   - assetserver_hash_duration_seconds (histogram): the time taken to hash
+//     files
+//   - assetserver_hash_errors_total (counter): failed hash operations
+//   - assetserver_hashes_in_flight (gauge): files currently being hashed
+type Collector struct {
+	requests     *prometheus.CounterVec
+	bytes        prometheus.Counter
+	cacheLookups *prometheus.CounterVec
+	hashDuration prometheus.Histogram
+	hashErrors   prometheus.Counter
+	inFlight     prometheus.Gauge
+
+	cacheHits   prometheus.Counter
+	cacheMisses prometheus.Counter
+}
+
+// NewCollector creates a Collector.
+func NewCollector() *Collector {
+	c := &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "assetserver_requests_total",
+			Help: "Number of HTTP requests served, by status code.",
+		}, []string{"code"}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "assetserver_response_bytes_total",
+			Help: "Number of response body bytes written.",
+		}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "assetserver_cache_lookups_total",
+			Help: "Number of lookups of cached file information, by result (hit or miss).",
+		}, []string{"result"}),
+		hashDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "assetserver_hash_duration_seconds",
+			Help:    "Time taken to hash files.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10), // 100µs to ~26s
+		}),
+		hashErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "assetserver_hash_errors_total",
+			Help: "Number of failed attempts to hash a file.",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "assetserver_hashes_in_flight",
+			Help: "Number of files currently being hashed.",
+		}),
+	}
+	c.cacheHits = c.cacheLookups.WithLabelValues("hit")
+	c.cacheMisses = c.cacheLookups.WithLabelValues("miss")
+	return c
+}
+
+// Option returns an assetserver.Option that binds a Server to c so that c
+// reports metrics for it. A Collector may be bound to more than one Server,
+// in which case it reports the combined metrics of all of them.
+func (c *Collector) Option() assetserver.Option {
+	return assetserver.WithMetricsHooks(assetserver.MetricsHooks{
+		Request: func(status int, bytes int64) {
+			c.requests.WithLabelValues(strconv.Itoa(status)).Inc()
+			c.bytes.Add(float64(bytes))
+		},
+		CacheLookup: func(hit bool) {
+			if hit {
+				c.cacheHits.Inc()
+			} else {
+				c.cacheMisses.Inc()
+			}
+		},
+		HashStart: func() {
+			c.inFlight.Inc()
+		},
+		HashDone: func(d time.Duration, err error) {
+			c.inFlight.Dec()
+			c.hashDuration.Observe(d.Seconds())
+			if err != nil {
+				c.hashErrors.Inc()
+			}
+		},
+	})
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.requests,
+		c.bytes,
+		c.cacheLookups,
+		c.hashDuration,
+		c.hashErrors,
+		c.inFlight,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range c.collectors() {
+		col.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, col := range c.collectors() {
+		col.Collect(ch)
+	}
+}
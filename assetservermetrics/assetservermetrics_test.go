@@ -0,0 +1,55 @@
+package assetservermetrics
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cespare/assetserver"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	s := assetserver.New(os.DirFS("../testdata/assets"), c.Option())
+	for _, target := range []string{"/a.js", "/a.js", "/nope.js"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+
+	want := `
+# HELP assetserver_cache_lookups_total Number of lookups of cached file information, by result (hit or miss).
+# TYPE assetserver_cache_lookups_total counter
+assetserver_cache_lookups_total{result="hit"} 1
+assetserver_cache_lookups_total{result="miss"} 1
+# HELP assetserver_hash_errors_total Number of failed attempts to hash a file.
+# TYPE assetserver_hash_errors_total counter
+assetserver_hash_errors_total 0
+# HELP assetserver_hashes_in_flight Number of files currently being hashed.
+# TYPE assetserver_hashes_in_flight gauge
+assetserver_hashes_in_flight 0
+# HELP assetserver_requests_total Number of HTTP requests served, by status code.
+# TYPE assetserver_requests_total counter
+assetserver_requests_total{code="200"} 2
+assetserver_requests_total{code="404"} 1
+# HELP assetserver_response_bytes_total Number of response body bytes written.
+# TYPE assetserver_response_bytes_total counter
+assetserver_response_bytes_total 27
+`
+	names := []string{
+		"assetserver_cache_lookups_total",
+		"assetserver_hash_errors_total",
+		"assetserver_hashes_in_flight",
+		"assetserver_requests_total",
+		"assetserver_response_bytes_total",
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), names...); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(c, "assetserver_hash_duration_seconds"); n != 1 {
+		t.Fatalf("got %d hash duration metrics; want 1", n)
+	}
+}
@@ -0,0 +1,39 @@
+// Package assetserverminify minifies assets served by assetserver using
+// github.com/tdewolff/minify.
+//
+// Pass its Option to assetserver.New:
+//
+//	assets := assetserver.New(fsys, assetserverminify.Option())
+package assetserverminify
+
+import (
+	"regexp"
+
+	"github.com/cespare/assetserver"
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/json"
+	"github.com/tdewolff/minify/v2/svg"
+)
+
+// New returns a minifier for CSS, HTML, JavaScript, JSON, and SVG with the
+// default settings.
+func New() *minify.M {
+	m := minify.New()
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("text/html", html.Minify)
+	m.AddFunc("image/svg+xml", svg.Minify)
+	m.AddFuncRegexp(regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$"), js.Minify)
+	m.AddFuncRegexp(regexp.MustCompile(`^(application|text)/(x-|.+\+)?json$`), json.Minify)
+	return m
+}
+
+// Option returns an assetserver.Option which minifies the files matching any
+// of the given patterns (or all of the supported kinds of files, if there are
+// no patterns) using the minifier returned by New.
+// See assetserver.WithMinifier for details.
+func Option(patterns ...string) assetserver.Option {
+	return assetserver.WithMinifier(New(), patterns...)
+}
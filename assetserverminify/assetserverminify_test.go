@@ -0,0 +1,39 @@
+package assetserverminify
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/assetserver"
+)
+
+func TestOption(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":     &fstest.MapFile{Data: []byte("function f(a) {\n\treturn a + 1;\n}\n")},
+		"app.css":    &fstest.MapFile{Data: []byte("body {\n\tcolor: #ff0000;\n}\n")},
+		"index.html": &fstest.MapFile{Data: []byte("<html>\n  <body>\n    <p>hi</p>\n  </body>\n</html>\n")},
+		"data.json":  &fstest.MapFile{Data: []byte("{\n  \"a\": 1\n}\n")},
+		"notes.txt":  &fstest.MapFile{Data: []byte("a  b\n")},
+	}
+	s := assetserver.New(fsys, Option())
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{"/app.js", "function f(e){return e+1}"},
+		{"/app.css", "body{color:red}"},
+		{"/index.html", "<p>hi"},
+		{"/data.json", `{"a":1}`},
+		{"/notes.txt", "a  b\n"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s: got %q; want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package assetservertrace records OpenTelemetry spans for assetserver.
+//
+// Pass the Option for a TracerProvider to assetserver.New:
+//
+//	assets := assetserver.New(fsys, assetservertrace.Option(tp))
+package assetservertrace
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cespare/assetserver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/cespare/assetserver"
+
+// Option returns an assetserver.Option that makes a Server record spans using
+// a tracer from tp.
+//
+// Each request is recorded as an "assetserver.ServeHTTP" span which is a child
+// of any span in the request's context (such as one created by otelhttp). It
+// has these attributes, where applicable:
+//
+//   - assetserver.name: the name of the requested file, without any tag
+//   - assetserver.tag: the file's current tag
+//   - assetserver.cache_hit: whether the file's information was found in the
+//     cache (if false, the file was hashed)
+//   - assetserver.size: the size of the file
+//   - assetserver.content_encoding: the Content-Encoding of the response
+//   - http.request.method, http.response.status_code, and
+//     http.response.body.size
+//
+// Hashing a file is recorded as an "assetserver.hash" span. Hashes done to
+// serve a request are children of the request's span; hashes done by
+// [assetserver.Server.Preload] are children of any span in its context.
+func Option(tp trace.TracerProvider) assetserver.Option {
+	tracer := tp.Tracer(tracerName)
+	return assetserver.WithTraceHooks(assetserver.TraceHooks{
+		StartRequest: func(r *http.Request) (context.Context, func(int, int64, http.Header)) {
+			ctx, span := tracer.Start(r.Context(), "assetserver.ServeHTTP",
+				trace.WithAttributes(attribute.String("http.request.method", r.Method)))
+			return ctx, func(status int, bytes int64, h http.Header) {
+				span.SetAttributes(
+					attribute.Int("http.response.status_code", status),
+					attribute.Int64("http.response.body.size", bytes),
+				)
+				if ce := h.Get("Content-Encoding"); ce != "" {
+					span.SetAttributes(attribute.String("assetserver.content_encoding", ce))
+				}
+				if status >= 500 {
+					span.SetStatus(codes.Error, http.StatusText(status))
+				}
+				span.End()
+			}
+		},
+		SetAttribute: func(ctx context.Context, key string, value any) {
+			var kv attribute.KeyValue
+			switch v := value.(type) {
+			case string:
+				kv = attribute.String(key, v)
+			case bool:
+				kv = attribute.Bool(key, v)
+			case int64:
+				kv = attribute.Int64(key, v)
+			default:
+				return
+			}
+			trace.SpanFromContext(ctx).SetAttributes(kv)
+		},
+		StartHash: func(ctx context.Context, name string) func(error) {
+			_, span := tracer.Start(ctx, "assetserver.hash",
+				trace.WithAttributes(attribute.String("assetserver.name", name)))
+			return func(err error) {
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+				}
+				span.End()
+			}
+		},
+	})
+}
@@ -0,0 +1,152 @@
+package assetservertrace
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/assetserver"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello")},
+	}
+	s := assetserver.New(fsys, Option(tp))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+		if w.Code != 200 {
+			t.Fatalf("got status %d", w.Code)
+		}
+	}
+
+	spans := sr.Ended()
+	var got []string
+	for _, span := range spans {
+		got = append(got, span.Name())
+	}
+	want := []string{"assetserver.hash", "assetserver.ServeHTTP", "assetserver.ServeHTTP"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("spans (-want +got):\n%s", diff)
+	}
+	tagged, err := s.Tag("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := strings.TrimSuffix(strings.TrimPrefix(tagged, "a."), ".txt")
+	hash, first, second := spans[0], spans[1], spans[2]
+	if hash.Parent().SpanID() != first.SpanContext().SpanID() {
+		t.Error("hash span is not a child of the first request's span")
+	}
+	checkSpanAttrs(t, hash, map[string]any{"assetserver.name": "a.txt"})
+	for i, tt := range []struct {
+		span sdktrace.ReadOnlySpan
+		hit  bool
+	}{
+		{first, false},
+		{second, true},
+	} {
+		t.Logf("request %d", i)
+		checkSpanAttrs(t, tt.span, map[string]any{
+			"http.request.method":       "GET",
+			"assetserver.name":          "a.txt",
+			"assetserver.tag":           tag,
+			"assetserver.cache_hit":     tt.hit,
+			"assetserver.size":          int64(5),
+			"http.response.status_code": int64(200),
+			"http.response.body.size":   int64(5),
+		})
+	}
+}
+
+func TestTracingErrors(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	s := assetserver.New(errorFS{
+		FS:   fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}},
+		errs: map[string]error{"a.txt": errors.New("disk on fire")},
+	}, Option(tp))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a.txt", nil))
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans; want 1", len(spans))
+	}
+	serve := spans[0]
+	if serve.Name() != "assetserver.ServeHTTP" {
+		t.Fatalf("got span %q; want assetserver.ServeHTTP", serve.Name())
+	}
+	if serve.Status().Code != codes.Error {
+		t.Errorf("got status %v; want Error", serve.Status())
+	}
+	checkSpanAttrs(t, serve, map[string]any{"http.response.status_code": int64(500)})
+}
+
+func TestTracingPreload(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	s := assetserver.New(fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+	}, Option(tp))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "startup")
+	if err := s.Preload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans; want 2", len(spans))
+	}
+	if spans[0].Name() != "assetserver.hash" {
+		t.Fatalf("got span %q; want assetserver.hash", spans[0].Name())
+	}
+	if spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("hash span is not a child of the span passed to Preload")
+	}
+}
+
+// errorFS wraps an FS so that opening certain files fails.
+type errorFS struct {
+	fs.FS
+	errs map[string]error
+}
+
+func (fsys errorFS) Open(name string) (fs.File, error) {
+	if err, ok := fsys.errs[name]; ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return fsys.FS.Open(name)
+}
+
+func checkSpanAttrs(t *testing.T, span sdktrace.ReadOnlySpan, want map[string]any) {
+	t.Helper()
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		gv, ok := got[attribute.Key(k)]
+		if !ok {
+			t.Errorf("span %s: missing attribute %s", span.Name(), k)
+			continue
+		}
+		if gv.AsInterface() != v {
+			t.Errorf("span %s: attribute %s = %v; want %v", span.Name(), k, gv.AsInterface(), v)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package assettest provides helpers for testing applications that serve
+// assets with an assetserver.Server.
+//
+// A typical test checks that the application's handler serves an asset by
+// its tagged name, with the right contents and headers:
+//
+//	func TestAssets(t *testing.T) {
+//		s := assetserver.New(os.DirFS("static"))
+//		h := newHandler(s) // the application's handler
+//		style := "/static/" + assettest.Tag(t, s, "css/style.css")
+//		assettest.AssertServes(t, h, style, 200, "static/css/style.css")
+//		assettest.AssertHeaders(t, h, style, "testdata/style.headers")
+//	}
+//
+// AssertHeaders compares headers against a golden file. Run the tests with
+// the -assettest.update flag to create or update golden files.
+package assettest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cespare/assetserver"
+)
+
+var update = flag.Bool("assettest.update", false, "update assettest golden files")
+
+// Get makes a GET request for path to h and returns the response. The
+// optional headers are added to the request and must be given in pairs of
+// name and value.
+func Get(h http.Handler, path string, headers ...string) *http.Response {
+	if len(headers)%2 != 0 {
+		panic("assettest: Get called with an odd number of header arguments")
+	}
+	r := httptest.NewRequest("GET", path, nil)
+	for i := 0; i < len(headers); i += 2 {
+		r.Header.Add(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Result()
+}
+
+// Tag returns s.Tag(name), failing the test if there is an error.
+func Tag(t testing.TB, s *assetserver.Server, name string) string {
+	t.Helper()
+	tagged, err := s.Tag(name)
+	if err != nil {
+		t.Fatalf("assettest: %s", err)
+	}
+	return tagged
+}
+
+// AssertServes checks that h responds to a GET request for path with the
+// given status code and, if wantBodyFile is not empty, with the contents of
+// that file as the body.
+func AssertServes(t testing.TB, h http.Handler, path string, wantStatus int, wantBodyFile string) {
+	t.Helper()
+	var want []byte
+	if wantBodyFile != "" {
+		var err error
+		want, err = os.ReadFile(wantBodyFile)
+		if err != nil {
+			t.Fatalf("assettest: %s", err)
+		}
+	}
+	resp := Get(h, path)
+	body := readBody(resp)
+	if resp.StatusCode != wantStatus {
+		t.Errorf("GET %s: got status %d; want %d", path, resp.StatusCode, wantStatus)
+		return
+	}
+	if wantBodyFile != "" && !bytes.Equal(body, want) {
+		t.Errorf("GET %s: body (%d bytes) doesn't match %s (%d bytes)", path, len(body), wantBodyFile, len(want))
+	}
+}
+
+// ignoredHeaders lists the headers which AssertHeaders leaves out because
+// they vary from run to run.
+var ignoredHeaders = []string{"Date", "Last-Modified"}
+
+// AssertHeaders checks that the headers of h's response to a GET request for
+// path match the golden file, which lists the status code followed by one
+// header per line in sorted order, such as
+//
+//	200
+//	Cache-Control: public, max-age=31536000, immutable
+//	Content-Type: text/css; charset=utf-8
+//
+// The Date and Last-Modified headers are left out, since they vary from run
+// to run. If the -assettest.update flag is given, AssertHeaders writes the
+// golden file instead.
+func AssertHeaders(t testing.TB, h http.Handler, path, goldenFile string) {
+	t.Helper()
+	resp := Get(h, path)
+	readBody(resp)
+	got := formatHeaders(resp)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0o755); err != nil {
+			t.Fatalf("assettest: %s", err)
+		}
+		if err := os.WriteFile(goldenFile, []byte(got), 0o644); err != nil {
+			t.Fatalf("assettest: %s", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("assettest: %s (run with -assettest.update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("GET %s: headers don't match %s:\ngot:\n%swant:\n%s", path, goldenFile, got, want)
+	}
+}
+
+func formatHeaders(resp *http.Response) string {
+	var lines []string
+	for name, vs := range resp.Header {
+		if containsFold(ignoredHeaders, name) {
+			continue
+		}
+		for _, v := range vs {
+			lines = append(lines, name+": "+v)
+		}
+	}
+	sort.Strings(lines)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n", resp.StatusCode)
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func readBody(resp *http.Response) []byte {
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	resp.Body.Close()
+	return buf.Bytes()
+}
+
+func containsFold(s []string, v string) bool {
+	for _, w := range s {
+		if strings.EqualFold(w, v) {
+			return true
+		}
+	}
+	return false
+}
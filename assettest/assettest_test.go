@@ -0,0 +1,108 @@
+package assettest
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cespare/assetserver"
+)
+
+// recordingTB records failures rather than failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.failures = append(tb.failures, fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) Fatalf(format string, args ...any) {
+	tb.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// record calls fn with a recordingTB in a new goroutine (so that Fatalf can
+// stop it) and returns the failures.
+func record(fn func(tb testing.TB)) []string {
+	var tb recordingTB
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(&tb)
+	}()
+	<-done
+	return tb.failures
+}
+
+func newServer() *assetserver.Server {
+	return assetserver.New(os.DirFS("testdata"))
+}
+
+func TestAssertServes(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	tagged := "/" + Tag(t, s, "style.css")
+	for _, tt := range []struct {
+		path     string
+		status   int
+		bodyFile string
+		fail     string // substring of the failure message, if any
+	}{
+		{tagged, 200, "testdata/style.css", ""},
+		{"/style.css", 200, "", ""},
+		{"/nonexistent.css", 404, "", ""},
+		{"/nonexistent.css", 200, "", "got status 404; want 200"},
+		{"/style.css", 200, "testdata/style.headers", "doesn't match testdata/style.headers"},
+		{"/style.css", 200, "testdata/nonexistent", "no such file"},
+	} {
+		failures := record(func(tb testing.TB) {
+			AssertServes(tb, s, tt.path, tt.status, tt.bodyFile)
+		})
+		checkFailures(t, failures, tt.fail)
+	}
+}
+
+func TestAssertHeaders(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	// The golden file has the headers for the tagged name of style.css.
+	tagged := "/" + Tag(t, s, "style.css")
+	AssertHeaders(t, s, tagged, "testdata/style.headers")
+	if *update {
+		return
+	}
+
+	failures := record(func(tb testing.TB) {
+		AssertHeaders(tb, s, "/style.css", "testdata/style.headers")
+	})
+	checkFailures(t, failures, "Cache-Control: public, max-age=60")
+}
+
+func TestGet(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	etag := Get(s, "/style.css").Header.Get("ETag")
+	if resp := Get(s, "/style.css", "If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional GET: got status %d; want 304", resp.StatusCode)
+	}
+}
+
+func checkFailures(t *testing.T, failures []string, want string) {
+	t.Helper()
+	if want == "" {
+		if len(failures) > 0 {
+			t.Errorf("got unexpected failures: %q", failures)
+		}
+		return
+	}
+	if len(failures) != 1 || !strings.Contains(failures[0], want) {
+		t.Errorf("got failures %q; want one containing %q", failures, want)
+	}
+}
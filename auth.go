@@ -0,0 +1,72 @@
+package assetserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+type basicAuth struct {
+	realm    string
+	users    map[string][sha256.Size]byte // username -> SHA-256 of password
+	patterns []string                     // if empty, everything is protected
+}
+
+// WithBasicAuth protects the Server with HTTP basic authentication. This is
+// intended for cases such as staging deployments of static sites where
+// standing up a separate authenticating proxy is more trouble than it's worth.
+//
+// The users map gives the password for each allowed username. The realm is
+// sent to clients in the WWW-Authenticate header.
+//
+// If any patterns are given, only files matching at least one of the patterns
+// require authentication; otherwise, every request does. See the package
+// documentation for the pattern syntax. WithBasicAuth panics if a pattern is
+// malformed.
+//
+// Authentication applies to serving HTTP requests only (including those
+// which the Server forwards to an upstream server or fallback handler; see
+// [WithProxyFallback] and [WithFallback]); it does not affect [Server.Tag].
+func WithBasicAuth(realm string, users map[string]string, patterns ...string) Option {
+	a := &basicAuth{
+		realm:    realm,
+		users:    make(map[string][sha256.Size]byte),
+		patterns: compilePatterns(patterns),
+	}
+	for user, pass := range users {
+		a.users[user] = sha256.Sum256([]byte(pass))
+	}
+	return func(s *Server) {
+		s.auth = a
+	}
+}
+
+// check reports whether the request for the named file may proceed.
+// If not, check writes a 401 response.
+func (a *basicAuth) check(w http.ResponseWriter, r *http.Request, name string) bool {
+	if len(a.patterns) > 0 && !matchAny(a.patterns, name) {
+		return true
+	}
+	if a.authorized(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", "Basic realm="+strconv.Quote(a.realm)+`, charset="UTF-8"`)
+	http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+func (a *basicAuth) authorized(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, ok := a.users[user]
+	if !ok {
+		return false
+	}
+	// Compare hashes so that the comparison time doesn't depend on the
+	// length of the password.
+	got := sha256.Sum256([]byte(pass))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
@@ -0,0 +1,82 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	users := map[string]string{"alice": "hunter2"}
+	for _, tt := range []struct {
+		patterns []string
+		path     string
+		user     string
+		pass     string
+		want     int
+	}{
+		{nil, "/a.js", "", "", 401},
+		{nil, "/a.js", "alice", "hunter2", 200},
+		{nil, "/a.js", "alice", "hunter3", 401},
+		{nil, "/a.js", "bob", "hunter2", 401},
+		{nil, "/noexist.js", "", "", 401},
+		{nil, "/noexist.js", "alice", "hunter2", 404},
+		{[]string{"d/**"}, "/a.js", "", "", 200},
+		{[]string{"d/**"}, "/d/style.css", "", "", 401},
+		{[]string{"d/**"}, "/d/style.EI7Zfw9kFp.css", "", "", 401},
+		{[]string{"d/**"}, "/d/style.EI7Zfw9kFp.css", "alice", "hunter2", 200},
+	} {
+		s := New(os.DirFS("testdata/assets"), WithBasicAuth("staging", users, tt.patterns...))
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+		s.ServeHTTP(w, req)
+		resp := w.Result()
+		if resp.StatusCode != tt.want {
+			t.Errorf("patterns=%q, GET %s as %q: got status %d; want %d",
+				tt.patterns, tt.path, tt.user, resp.StatusCode, tt.want)
+		}
+		if resp.StatusCode == 401 {
+			checkResponseHeader(t, resp, "WWW-Authenticate", `Basic realm="staging", charset="UTF-8"`)
+		}
+	}
+}
+
+func TestBasicAuthForwarded(t *testing.T) {
+	users := map[string]string{"alice": "hunter2"}
+	var forwarded int
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded++
+	})
+	for _, tt := range []struct {
+		opt    Option
+		method string
+		path   string
+	}{
+		{WithProxyAll(newTestUpstream(t)), "GET", "/a.js"},
+		{WithFallback(fallback), "POST", "/a.js"},
+		{WithFallback(fallback), "GET", "/"},
+		{WithFallback(fallback), "GET", "/noexist.js"},
+	} {
+		s := New(os.DirFS("testdata/assets"), tt.opt, WithBasicAuth("staging", users))
+		forwarded = 0
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != 401 {
+			t.Errorf("%s %s without credentials: got status %d; want 401", tt.method, tt.path, w.Code)
+		}
+		if forwarded > 0 {
+			t.Errorf("%s %s without credentials: request was forwarded", tt.method, tt.path)
+		}
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.SetBasicAuth("alice", "hunter2")
+		s.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Errorf("%s %s as alice: got status %d; want 200", tt.method, tt.path, w.Code)
+		}
+	}
+}
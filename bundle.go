@@ -0,0 +1,157 @@
+package assetserver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// WithBundle makes the Server serve a file with the given name whose contents
+// are the concatenation of the named files, in order. For example,
+//
+//	WithBundle("css/vendor.css", "css/normalize.css", "css/grid.css")
+//
+// combines two stylesheets so that pages can load them with one request. A
+// bundle is like any other file: it can be tagged with [Server.Tag], and its
+// tag changes whenever any of its members changes. A bundle hides any file of
+// the same name, and it does not appear in directory listings (such as the
+// walk done by [Server.Preload]).
+//
+// A newline is added after each member. For JavaScript bundles (those with a
+// .js or .mjs extension), a semicolon is added before the newline as well, so
+// that a statement at the end of one file can't run into the start of the
+// next.
+//
+// The members are read into memory each time the bundle is opened, so
+// bundles are best suited to small sites with modest assets. If a member
+// doesn't exist, requests for the bundle receive 404 Not Found.
+func WithBundle(name string, files ...string) Option {
+	name = strings.TrimPrefix(name, "/")
+	members := make([]string, len(files))
+	for i, f := range files {
+		members[i] = strings.TrimPrefix(f, "/")
+	}
+	return func(s *Server) {
+		bfs, ok := s.fsys.(*bundleFS)
+		if !ok {
+			bfs = &bundleFS{FS: s.fsys, bundles: make(map[string][]string)}
+			s.fsys = bfs
+		}
+		bfs.bundles[name] = members
+	}
+}
+
+// A bundleFS adds bundles (see WithBundle) to a file system.
+type bundleFS struct {
+	fs.FS
+	bundles map[string][]string // bundle name -> members
+}
+
+func (bfs *bundleFS) Open(name string) (fs.File, error) {
+	members, ok := bfs.bundles[name]
+	if !ok {
+		return bfs.FS.Open(name)
+	}
+	fi, err := bfs.stat(name, members)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, member := range members {
+		b, err := fs.ReadFile(bfs.FS, member)
+		if err != nil {
+			return nil, bundleError(name, err)
+		}
+		buf.Write(b)
+		buf.WriteString(bundleSeparator(name))
+	}
+	// Report the size of what was actually read, in case a member changed
+	// after the call to stat.
+	fi.size = int64(buf.Len())
+	return &bundleFile{Reader: bytes.NewReader(buf.Bytes()), fi: fi}, nil
+}
+
+func (bfs *bundleFS) Stat(name string) (fs.FileInfo, error) {
+	members, ok := bfs.bundles[name]
+	if !ok {
+		return fs.Stat(bfs.FS, name)
+	}
+	return bfs.stat(name, members)
+}
+
+func (bfs *bundleFS) ReadFile(name string) ([]byte, error) {
+	if _, ok := bfs.bundles[name]; !ok {
+		return fs.ReadFile(bfs.FS, name)
+	}
+	f, err := bfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(f)
+}
+
+func (bfs *bundleFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(bfs.FS, name)
+}
+
+// stat returns the info for the named bundle. Its size is the total size of
+// its members plus separators and its modification time is that of the
+// most recently modified member, so the Server notices when any member
+// changes.
+func (bfs *bundleFS) stat(name string, members []string) (*bundleFileInfo, error) {
+	fi := &bundleFileInfo{name: path.Base(name)}
+	for _, member := range members {
+		mfi, err := fs.Stat(bfs.FS, member)
+		if err != nil {
+			return nil, bundleError(name, err)
+		}
+		if mfi.IsDir() {
+			return nil, bundleError(name, fmt.Errorf("%s is a directory: %w", member, fs.ErrNotExist))
+		}
+		fi.size += mfi.Size() + int64(len(bundleSeparator(name)))
+		if mt := mfi.ModTime(); mt.After(fi.modTime) {
+			fi.modTime = mt
+		}
+	}
+	return fi, nil
+}
+
+func bundleError(name string, err error) error {
+	return fmt.Errorf("assetserver: bundle %s: %w", name, err)
+}
+
+// bundleSeparator returns the text to add after each member of the named
+// bundle.
+func bundleSeparator(name string) string {
+	switch path.Ext(name) {
+	case ".js", ".mjs":
+		return ";\n"
+	}
+	return "\n"
+}
+
+// A bundleFile is an open bundle.
+type bundleFile struct {
+	*bytes.Reader
+	fi *bundleFileInfo
+}
+
+func (f *bundleFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *bundleFile) Close() error               { return nil }
+
+type bundleFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *bundleFileInfo) Name() string       { return fi.name }
+func (fi *bundleFileInfo) Size() int64        { return fi.size }
+func (fi *bundleFileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi *bundleFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *bundleFileInfo) IsDir() bool        { return false }
+func (fi *bundleFileInfo) Sys() any           { return nil }
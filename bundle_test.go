@@ -0,0 +1,69 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestBundle(t *testing.T) {
+	t0 := time.Now().Truncate(time.Second)
+	fsys := fstest.MapFS{
+		"css/normalize.css": &fstest.MapFile{Data: []byte("html{}\n"), ModTime: t0},
+		"css/grid.css":      &fstest.MapFile{Data: []byte(".grid{}"), ModTime: t0},
+		"js/a.js":           &fstest.MapFile{Data: []byte("a()"), ModTime: t0},
+		"js/b.js":           &fstest.MapFile{Data: []byte("(b)()"), ModTime: t0},
+		"vendor.css":        &fstest.MapFile{Data: []byte("hidden"), ModTime: t0},
+	}
+	s := New(fsys,
+		WithBundle("/vendor.css", "css/normalize.css", "/css/grid.css"),
+		WithBundle("js/all.js", "js/a.js", "js/b.js"),
+		WithBundle("broken.css", "css/grid.css", "css/missing.css"),
+	)
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"vendor.css", "html{}\n\n.grid{}\n"},
+		{"js/all.js", "a();\n(b)();\n"},
+	} {
+		tagged, err := s.Tag(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := insertTag(tt.name, hashTag(tt.want)); tagged != want {
+			t.Errorf("Tag(%q) = %q; want %q", tt.name, tagged, want)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tagged, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tagged, w.Code)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s: got body %q; want %q", tagged, got, tt.want)
+		}
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("HEAD", "/"+tt.name, nil))
+		if got, want := w.Header().Get("Content-Length"), strconv.Itoa(len(tt.want)); got != want {
+			t.Errorf("HEAD %s: got Content-Length %s; want %s", tt.name, got, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/broken.css", nil))
+	if w.Code != 404 {
+		t.Errorf("GET bundle with missing member: got status %d; want 404", w.Code)
+	}
+
+	// Changing a member changes the bundle's tag.
+	fsys["css/grid.css"] = &fstest.MapFile{Data: []byte(".grid{display:grid}"), ModTime: t0.Add(time.Second)}
+	tagged, err := s.Tag("vendor.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := insertTag("vendor.css", hashTag("html{}\n\n.grid{display:grid}\n")); tagged != want {
+		t.Errorf("after change, Tag = %q; want %q", tagged, want)
+	}
+}
@@ -0,0 +1,314 @@
+package assetserver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"hash/maphash"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A cacheEntry holds the cached info for a single file.
+type cacheEntry struct {
+	atomic.Pointer[fileInfo]
+	elem *list.Element // in cacheShard.lru; nil if the cache is unbounded
+
+	// validated is the time (as unix nano) at which the info was last
+	// computed or found to match the file, or 0 if it has not been
+	// checked since it was loaded from a cache file.
+	validated atomic.Int64
+}
+
+// markValidated records that the entry's info was just computed or found to
+// match the file.
+func (e *cacheEntry) markValidated() {
+	now := time.Now().UnixNano()
+	// This is only for diagnostics, so avoid writing to memory that is
+	// shared by concurrent requests for the same file more than once per
+	// second.
+	if now-e.validated.Load() >= int64(time.Second) {
+		e.validated.Store(now)
+	}
+}
+
+// An infoCache maps file names to cache entries.
+//
+// To avoid contention between requests for different files, the cache is
+// split into shards, each protected by its own lock. An rwmutex seems
+// appropriate for each shard: once we've loaded all the assets, we never
+// write-lock the mutexes again (unless the cache is bounded, in which case
+// each lookup updates the LRU list).
+type infoCache struct {
+	seed   maphash.Seed
+	shards []cacheShard
+
+	hooksMu sync.Mutex
+	onEvict []func(name string) // called after entries are removed
+}
+
+type cacheShard struct {
+	mu         sync.RWMutex
+	m          map[string]*cacheEntry
+	maxEntries int        // if > 0, the maximum len(m)
+	lru        *list.List // of names, most recently used first; only if maxEntries > 0
+
+	_ [64]byte // avoid false sharing between adjacent shards
+}
+
+// numCacheShards is the number of shards used for an unbounded cache.
+const numCacheShards = 64
+
+// newInfoCache creates an infoCache which holds at most maxEntries entries
+// (or an unlimited number, if maxEntries is 0).
+func newInfoCache(maxEntries int) *infoCache {
+	if maxEntries > 0 {
+		// The LRU order is global, so a bounded cache has a single shard.
+		return newShardedInfoCache(1, maxEntries)
+	}
+	return newShardedInfoCache(numCacheShards, 0)
+}
+
+func newShardedInfoCache(numShards, maxEntries int) *infoCache {
+	c := &infoCache{
+		seed:   maphash.MakeSeed(),
+		shards: make([]cacheShard, numShards),
+	}
+	for i := range c.shards {
+		c.shards[i].m = make(map[string]*cacheEntry)
+		if maxEntries > 0 {
+			c.shards[i].maxEntries = maxEntries
+			c.shards[i].lru = list.New()
+		}
+	}
+	return c
+}
+
+func (c *infoCache) shard(name string) *cacheShard {
+	if len(c.shards) == 1 {
+		return &c.shards[0]
+	}
+	return &c.shards[maphash.String(c.seed, name)%uint64(len(c.shards))]
+}
+
+// WithMaxCacheEntries limits the number of files for which the Server caches
+// information (such as the tag) to n. When the limit is reached, the least
+// recently used entry is discarded to make room for a new one; the Server will
+// have to hash that file again if it is requested later.
+//
+// This is useful for Servers that may be asked for arbitrarily many distinct
+// files. Note that bounding the cache makes each lookup somewhat more
+// expensive, since it must record the use.
+func WithMaxCacheEntries(n int) Option {
+	return func(s *Server) {
+		if n <= 0 {
+			panic("assetserver: WithMaxCacheEntries called with n <= 0")
+		}
+		s.maxEntries = n
+	}
+}
+
+// lookup returns the cache entry for the named file, or nil if there is none.
+func (c *infoCache) lookup(name string) *cacheEntry {
+	sh := c.shard(name)
+	if sh.maxEntries > 0 {
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+		e := sh.m[name]
+		if e != nil {
+			sh.lru.MoveToFront(e.elem)
+		}
+		return e
+	}
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.m[name]
+}
+
+// peek is like lookup, but it does not count as a use of the entry for the
+// purposes of LRU eviction.
+func (c *infoCache) peek(name string) *cacheEntry {
+	sh := c.shard(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.m[name]
+}
+
+// entry returns the cache entry for the named file, creating it (and evicting
+// the least recently used entry, if necessary) if it does not exist.
+func (c *infoCache) entry(name string) *cacheEntry {
+	if e := c.lookup(name); e != nil {
+		return e
+	}
+	sh := c.shard(name)
+	sh.mu.Lock()
+	if e, ok := sh.m[name]; ok {
+		sh.mu.Unlock()
+		return e
+	}
+	e := new(cacheEntry)
+	sh.m[name] = e
+	var evicted []string
+	if sh.maxEntries > 0 {
+		e.elem = sh.lru.PushFront(name)
+		for sh.lru.Len() > sh.maxEntries {
+			oldest := sh.lru.Remove(sh.lru.Back()).(string)
+			delete(sh.m, oldest)
+			evicted = append(evicted, oldest)
+		}
+	}
+	sh.mu.Unlock()
+	for _, name := range evicted {
+		c.evicted(name)
+	}
+	return e
+}
+
+// evict removes the entry for the named file, if there is one, and reports
+// whether it did so.
+func (c *infoCache) evict(name string) bool {
+	sh := c.shard(name)
+	sh.mu.RLock()
+	_, ok := sh.m[name]
+	sh.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	sh.mu.Lock()
+	e, ok := sh.m[name]
+	if !ok {
+		sh.mu.Unlock()
+		return false
+	}
+	delete(sh.m, name)
+	if e.elem != nil {
+		sh.lru.Remove(e.elem)
+	}
+	sh.mu.Unlock()
+	c.evicted(name)
+	return true
+}
+
+// addEvictHook arranges for fn to be called with the name of each file whose
+// entry is removed from the cache, whether by evict or to make room for
+// another entry.
+func (c *infoCache) addEvictHook(fn func(name string)) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.onEvict = append(c.onEvict, fn)
+}
+
+// evicted calls the evict hooks for the named file.
+func (c *infoCache) evicted(name string) {
+	c.hooksMu.Lock()
+	hooks := c.onEvict
+	c.hooksMu.Unlock()
+	for _, fn := range hooks {
+		fn(name)
+	}
+}
+
+// names returns the names of all the files in the cache.
+func (c *infoCache) names() []string {
+	var names []string
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.RLock()
+		for name := range sh.m {
+			names = append(names, name)
+		}
+		sh.mu.RUnlock()
+	}
+	return names
+}
+
+// each calls fn for each entry in the cache. The order is unspecified. fn
+// must not modify the cache.
+func (c *infoCache) each(fn func(name string, e *cacheEntry)) {
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.RLock()
+		for name, e := range sh.m {
+			fn(name, e)
+		}
+		sh.mu.RUnlock()
+	}
+}
+
+// len returns the number of entries in the cache.
+func (c *infoCache) len() int {
+	var n int
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.RLock()
+		n += len(sh.m)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// cachedInfo returns the cached info for the named file without checking
+// whether it is up to date. It returns nil if there is no cached info.
+func (s *Server) cachedInfo(name string) *fileInfo {
+	e := s.cache.lookup(name)
+	if e == nil {
+		return nil
+	}
+	return e.Load()
+}
+
+// evict removes any cached info for the named file, which no longer exists.
+func (s *Server) evict(name string) {
+	if s.cache.evict(name) {
+		s.log(context.Background(), slog.LevelInfo, "forgot cached info for deleted file", "name", name)
+	}
+}
+
+// Prune removes the cached information for files which no longer exist in
+// the file system and returns the number of entries removed.
+//
+// The Server automatically forgets a file when a request or a call to Tag
+// finds that it does not exist, but files that are deleted and never
+// requested again stay in the cache until Prune is called. Servers for
+// directories whose contents change over time should call Prune periodically
+// (or use [WithPruneInterval]) to keep the cache from growing without bound.
+func (s *Server) Prune() int {
+	var pruned int
+	for _, name := range s.cache.names() {
+		fi, err := fs.Stat(s.fsys, name)
+		switch {
+		case err == nil && !fi.IsDir():
+			continue
+		case err == nil, errors.Is(err, fs.ErrNotExist):
+			s.evict(name)
+			pruned++
+		}
+		// Leave the entry alone for other errors; they may be transient.
+	}
+	return pruned
+}
+
+// WithPruneInterval makes the Server call [Server.Prune] in the background
+// every interval d. The background goroutine runs until [Server.Close] is
+// called.
+func WithPruneInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.pruneInterval = d
+	}
+}
+
+func (s *Server) pruneLoop(d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Prune()
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package assetserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEvictOnNotExist(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+	}
+	s := New(fsys)
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if s.cachedInfo("a.txt") == nil {
+		t.Fatal("no cached info after Tag")
+	}
+	delete(fsys, "a.txt")
+	if _, err := s.Tag("a.txt"); err == nil {
+		t.Fatal("Tag of deleted file: got nil error")
+	}
+	if s.cache.lookup("a.txt") != nil {
+		t.Fatal("cache entry remains for deleted file")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":   &fstest.MapFile{Data: []byte("a")},
+		"b.txt":   &fstest.MapFile{Data: []byte("b")},
+		"d/c.txt": &fstest.MapFile{Data: []byte("c")},
+	}
+	s := New(fsys)
+	for name := range fsys {
+		if _, err := s.Tag(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	delete(fsys, "a.txt")
+	delete(fsys, "d/c.txt")
+	if n := s.Prune(); n != 2 {
+		t.Fatalf("Prune: got %d; want 2", n)
+	}
+	if s.cache.len() != 1 || s.cachedInfo("b.txt") == nil {
+		t.Fatalf("after Prune, cache contains %d entries; want only b.txt", s.cache.len())
+	}
+}
+
+func TestPruneInterval(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(name, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithPruneInterval(time.Millisecond))
+	defer s.Close()
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if s.cache.len() == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for background prune")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxCacheEntries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+		"b.txt": &fstest.MapFile{Data: []byte("b")},
+		"c.txt": &fstest.MapFile{Data: []byte("c")},
+	}
+	s := New(fsys, WithMaxCacheEntries(2))
+	for _, name := range []string{"a.txt", "b.txt", "a.txt", "c.txt"} {
+		if _, err := s.Tag(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// b.txt was the least recently used when c.txt was added.
+	lru := s.cache.shards[0].lru
+	if s.cache.len() != 2 || lru.Len() != 2 {
+		t.Fatalf("cache has %d entries (%d in LRU list); want 2", s.cache.len(), lru.Len())
+	}
+	for _, name := range []string{"a.txt", "c.txt"} {
+		if s.cache.lookup(name) == nil {
+			t.Errorf("cache is missing %s", name)
+		}
+	}
+
+	delete(fsys, "a.txt")
+	if _, err := s.Tag("a.txt"); err == nil {
+		t.Fatal("Tag of deleted file: got nil error")
+	}
+	if s.cache.len() != 1 || lru.Len() != 1 {
+		t.Fatalf("after eviction, cache has %d entries (%d in LRU list); want 1", s.cache.len(), lru.Len())
+	}
+}
+
+func BenchmarkCacheLookupParallel(b *testing.B) {
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = fmt.Sprintf("d%d/f%d.js", i%10, i)
+	}
+	for _, numShards := range []int{1, numCacheShards} {
+		b.Run(fmt.Sprintf("shards=%d", numShards), func(b *testing.B) {
+			c := newShardedInfoCache(numShards, 0)
+			for _, name := range names {
+				c.entry(name)
+			}
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+				for pb.Next() {
+					if c.lookup(names[i%len(names)]) == nil {
+						panic("missing entry")
+					}
+					i++
+				}
+			})
+		})
+	}
+}
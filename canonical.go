@@ -0,0 +1,78 @@
+package assetserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// A CanonicalLink selects which URL of a file a Server declares to be
+// canonical. See [WithCanonicalLinks].
+type CanonicalLink int
+
+const (
+	// CanonicalUntagged makes responses for tagged names link to the
+	// file's untagged name.
+	CanonicalUntagged CanonicalLink = iota + 1
+	// CanonicalTagged makes responses for untagged names link to the
+	// file's current tagged name.
+	CanonicalTagged
+)
+
+// WithCanonicalLinks makes the Server add a header such as
+//
+//	Link: <style.css>; rel="canonical"
+//
+// to responses, declaring which of a file's URLs is canonical. Each change to
+// a file gives it a new tagged URL, and crawlers that find several of them
+// (along with the untagged one) can use the header to consolidate them.
+// With CanonicalUntagged, responses for tagged names link to the untagged
+// name; with CanonicalTagged, responses for untagged names link to the
+// current tagged name (which, with [WithCanonicalDuplicates], may be the
+// name of another file with the same contents).
+//
+// The links are relative to the request's URL, so they work however the
+// Server is mounted. A no-cache Server, or a file whose name includes a hash
+// (see [WithPreHashed]), has no tagged names, so CanonicalTagged adds no
+// links for it. Neither does a response which is sent while the file is
+// hashed (see [WithStreamingHash]).
+func WithCanonicalLinks(c CanonicalLink) Option {
+	if c != CanonicalUntagged && c != CanonicalTagged {
+		panic(fmt.Sprintf("assetserver: WithCanonicalLinks called with unknown value %d", c))
+	}
+	return func(s *Server) {
+		s.canonicalLink = c
+	}
+}
+
+// addCanonicalLink adds the canonical Link header, if any, to the response
+// for the named file requested with the given tag (if any). The file's
+// current tag is curTag, or "" if it isn't known yet.
+func (s *Server) addCanonicalLink(ctx context.Context, h http.Header, name, tag, curTag string) {
+	var target string
+	switch s.canonicalLink {
+	case CanonicalUntagged:
+		if tag == "" {
+			return
+		}
+		target = name
+	case CanonicalTagged:
+		if tag != "" || curTag == "" || s.noCache || s.isPreHashed(name) {
+			return
+		}
+		target = name
+		if s.duplicates != nil {
+			info, err := s.currentInfo(ctx, name)
+			if err != nil {
+				return
+			}
+			if target, err = s.canonicalName(ctx, name, info); err != nil {
+				return
+			}
+		}
+		target = insertTag(target, curTag)
+	default:
+		return
+	}
+	h.Add("Link", "<"+escapeRelative(relativeURL(name, target))+`>; rel="canonical"`)
+}
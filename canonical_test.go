@@ -0,0 +1,43 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCanonicalLinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css":      &fstest.MapFile{Data: []byte("body{}")},
+		"css/my style.css":   &fstest.MapFile{Data: []byte("p{}")},
+		"a/copy.css":         &fstest.MapFile{Data: []byte("body{}")},
+		"assets/app.1a2b.js": &fstest.MapFile{Data: []byte("app")},
+	}
+	tag := hashTag("body{}")
+	spaceTag := hashTag("p{}")
+	for _, tt := range []struct {
+		c    CanonicalLink
+		opts []Option
+		path string
+		want string
+	}{
+		{CanonicalUntagged, nil, "/css/style.css", ""},
+		{CanonicalUntagged, nil, "/css/style." + tag + ".css", `<style.css>; rel="canonical"`},
+		{CanonicalUntagged, nil, "/css/my%20style." + spaceTag + ".css", `<my%20style.css>; rel="canonical"`},
+		{CanonicalTagged, nil, "/css/style.css", `<style.` + tag + `.css>; rel="canonical"`},
+		{CanonicalTagged, nil, "/css/style." + tag + ".css", ""},
+		{CanonicalTagged, []Option{WithPreHashed("assets/**")}, "/assets/app.1a2b.js", ""},
+		{CanonicalTagged, []Option{WithCanonicalDuplicates()}, "/css/style.css", `<../a/copy.` + tag + `.css>; rel="canonical"`},
+	} {
+		s := New(fsys, append(tt.opts, WithCanonicalLinks(tt.c))...)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Errorf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Header().Get("Link"); got != tt.want {
+			t.Errorf("canonical %d: GET %s: got Link %q; want %q", tt.c, tt.path, got, tt.want)
+		}
+		s.Close()
+	}
+}
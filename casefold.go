@@ -0,0 +1,81 @@
+package assetserver
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WithCaseInsensitivePaths makes the Server resolve request paths which
+// don't match any file exactly by ignoring case, and redirect (see
+// [WithRedirectStatus]) to the file's actual name. This helps sites that
+// moved from a case-insensitive origin (such as IIS on Windows), where links
+// like /Images/Logo.PNG for images/logo.png exist in the wild.
+//
+// A path is only redirected if exactly one file matches it. Resolving a path
+// reads each of the directories along it, so requests for missing files are
+// more expensive with this option.
+func WithCaseInsensitivePaths() Option {
+	return func(s *Server) {
+		s.caseInsensitive = true
+	}
+}
+
+// redirectCase redirects a request for the named file, which doesn't exist,
+// to the file whose name matches it ignoring case, if there is one, and
+// reports whether it did.
+func (s *Server) redirectCase(w http.ResponseWriter, r *http.Request, name, tag string) bool {
+	if !s.caseInsensitive {
+		return false
+	}
+	actual, ok := resolveCase(s.fsys, name)
+	if !ok || actual == name {
+		return false
+	}
+	if tag != "" {
+		actual = insertTag(actual, tag)
+	}
+	// Use a relative target so that it works under http.StripPrefix
+	// (see the trailing slash redirect in serveHTTP).
+	target := "./"
+	if depth := strings.Count(r.URL.Path, "/") - 1; depth > 0 {
+		target = strings.Repeat("../", depth)
+	}
+	target += escapePath(actual)
+	if q := r.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	s.redirect(w, r, target)
+	return true
+}
+
+// resolveCase returns the name of the file in fsys which matches name
+// ignoring case. An exact match for a path element is preferred; otherwise,
+// the element must match exactly one directory entry.
+func resolveCase(fsys fs.FS, name string) (string, bool) {
+	dir := "."
+	for _, elem := range strings.Split(name, "/") {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return "", false
+		}
+		var match string
+		var n int // the number of case-insensitive matches
+		for _, e := range entries {
+			if e.Name() == elem {
+				match, n = elem, 1
+				break
+			}
+			if strings.EqualFold(e.Name(), elem) {
+				match = e.Name()
+				n++
+			}
+		}
+		if n != 1 {
+			return "", false
+		}
+		dir = path.Join(dir, match)
+	}
+	return dir, true
+}
@@ -0,0 +1,61 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCaseInsensitivePaths(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/logo.png":   &fstest.MapFile{Data: []byte("logo")},
+		"images/Photo.jpg":  &fstest.MapFile{Data: []byte("photo")},
+		"dup/a.txt":         &fstest.MapFile{Data: []byte("a")},
+		"dup/A.txt":         &fstest.MapFile{Data: []byte("A")},
+		"Readme.txt":        &fstest.MapFile{Data: []byte("readme")},
+		"css/Site/main.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	s := New(fsys, WithCaseInsensitivePaths())
+	defer s.Close()
+	tagged, err := s.Tag("images/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, _ := removeTag(tagged)
+
+	for _, tt := range []struct {
+		path     string
+		status   int
+		location string
+	}{
+		{"/images/logo.png", 200, ""},
+		{"/Images/Logo.PNG", 308, "../images/logo.png"},
+		{"/IMAGES/photo.JPG?x=1", 308, "../images/Photo.jpg?x=1"},
+		{"/Images/Logo." + tag + ".PNG", 308, "../images/logo." + tag + ".png"},
+		{"/readme.txt", 308, "./Readme.txt"},
+		{"/CSS/site/MAIN.css", 308, "../../css/Site/main.css"},
+		{"/dup/a.txt", 200, ""},
+		{"/DUP/a.txt", 308, "../dup/a.txt"},
+		{"/dup/a.TXT", 404, ""}, // ambiguous
+		{"/images/missing.png", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.status)
+			continue
+		}
+		if got := w.Header().Get("Location"); got != tt.location {
+			t.Errorf("GET %s: got Location %q; want %q", tt.path, got, tt.location)
+		}
+	}
+
+	// Without the option, such paths are not found.
+	s2 := New(fsys)
+	defer s2.Close()
+	w := httptest.NewRecorder()
+	s2.ServeHTTP(w, httptest.NewRequest("GET", "/Images/Logo.PNG", nil))
+	if w.Code != 404 {
+		t.Errorf("GET /Images/Logo.PNG without WithCaseInsensitivePaths: got status %d; want 404", w.Code)
+	}
+}
@@ -0,0 +1,35 @@
+package assetserver
+
+import "context"
+
+// WithChangeHook makes the Server call fn whenever it finds that a file whose
+// information it had cached has new contents: when a request or a call to
+// [Server.Tag] finds the file changed, when a background check finds it
+// changed (see [WithRevalidateInterval] and [WithLiveReload]), or when the
+// information was loaded from a cache file (see [WithCacheFile]) and is out
+// of date. The application can use this to discard things derived from the
+// old contents, such as rendered HTML which refers to the old tagged name or
+// the CDN's copy of the old file.
+//
+// The oldTag and newTag are the file's previous and current tags. Files
+// which were never cached (including files requested for the first time) and
+// files which were deleted don't cause calls to fn. The Server calls fn
+// synchronously, possibly while serving a request and possibly from several
+// goroutines at once, so fn should be quick and safe for concurrent use.
+func WithChangeHook(fn func(name, oldTag, newTag string)) Option {
+	return func(s *Server) {
+		s.changeHook = fn
+	}
+}
+
+// storeInfo stores info in e, the cache entry for the named file, calls the
+// change hook if the file's tag changed, and records the file's contents in
+// the version history (see WithVersionHistory).
+func (s *Server) storeInfo(e *cacheEntry, name string, info *fileInfo) {
+	old := e.Swap(info)
+	e.markValidated()
+	s.recordVersion(context.Background(), name, info)
+	if s.changeHook != nil && old != nil && old.tag != info.tag {
+		s.changeHook(name, old.tag, info.tag)
+	}
+}
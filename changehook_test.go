@@ -0,0 +1,56 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChangeHook(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a1")},
+		"b.css": &fstest.MapFile{Data: []byte("b1")},
+	}
+	type change struct{ name, oldTag, newTag string }
+	var (
+		mu      sync.Mutex
+		changes []change
+	)
+	s := New(fsys, WithChangeHook(func(name, oldTag, newTag string) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change{name, oldTag, newTag})
+	}))
+	get := func(name string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+name, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", name, w.Code)
+		}
+	}
+
+	// First requests don't count as changes.
+	get("a.css")
+	get("b.css")
+	// Neither does a change to a file's modification time only.
+	fsys["a.css"] = &fstest.MapFile{Data: []byte("a1"), ModTime: time.Unix(100, 0)}
+	get("a.css")
+	fsys["a.css"] = &fstest.MapFile{Data: []byte("a22")}
+	get("a.css")
+	fsys["b.css"] = &fstest.MapFile{Data: []byte("b22")}
+	if err := s.revalidateFile("b.css"); err != nil {
+		t.Fatal(err)
+	}
+	want := []change{
+		{"a.css", hashTag("a1"), hashTag("a22")},
+		{"b.css", hashTag("b1"), hashTag("b22")},
+	}
+	if diff := cmp.Diff(want, changes, cmp.AllowUnexported(change{})); diff != "" {
+		t.Errorf("changes (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,248 @@
+// Command assetcompress writes compressed versions of the files in an asset
+// directory, for serving with assetserver's WithPrecompressed. Compressing at
+// build time allows the highest compression levels, which are far too slow to
+// use for each request.
+//
+// For each compressible file (such as a stylesheet, script, SVG image, or
+// WebAssembly module) of at least the minimum size, assetcompress writes a
+// Brotli-compressed version with ".br" appended to the name and a
+// gzip-compressed version with ".gz" appended. A compressed version is only
+// written if it is smaller than the original, and it is left alone if it is
+// already at least as new as the original (unless -f is given), so running
+// assetcompress again after changing a few files is quick.
+//
+// Usage:
+//
+//	assetcompress [flags] [dir]
+//
+// The directory defaults to the current directory. The flags are:
+//
+//	-f
+//		rewrite compressed versions even if they are up to date
+//	-min-size int
+//		the size in bytes below which files are not compressed (default 256)
+//	-v
+//		print the name and sizes of each file as it is compressed
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/google/renameio"
+	"golang.org/x/sync/errgroup"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assetcompress: ")
+	var cfg config
+	flag.BoolVar(&cfg.force, "f", false, "rewrite compressed versions even if they are up to date")
+	flag.Int64Var(&cfg.minSize, "min-size", 256, "the size in bytes below which files are not compressed")
+	verbose := flag.Bool("v", false, "print the name and sizes of each file as it is compressed")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: assetcompress [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *verbose {
+		cfg.log = func(name string, size, compressed int64) {
+			log.Printf("%s: %d -> %d bytes (%.1f%%)", name, size, compressed, 100*float64(compressed)/float64(size))
+		}
+	}
+	if err := compressDir(context.Background(), dir, cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type config struct {
+	force   bool
+	minSize int64
+	// log, if non-nil, is called for each compressed version written.
+	log func(name string, size, compressed int64)
+}
+
+// encodings lists the compressed versions to write, matching the suffixes
+// that WithPrecompressed looks for.
+var encodings = []struct {
+	suffix   string
+	compress func(b []byte) ([]byte, error)
+}{
+	{".br", compressBrotli},
+	{".gz", compressGzip},
+}
+
+// compressDir writes compressed versions of the compressible files in dir.
+func compressDir(ctx context.Context, dir string, cfg config) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+	var logMu sync.Mutex
+	walkErr := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if name != dir && strings.HasPrefix(d.Name(), ".") {
+			// Skip hidden files and directories.
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !compressible(name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < cfg.minSize {
+			return nil
+		}
+		eg.Go(func() error {
+			return compressFile(name, info, cfg, &logMu)
+		})
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// compressFile writes the compressed versions of the named file which are
+// missing or out of date.
+func compressFile(name string, info fs.FileInfo, cfg config, logMu *sync.Mutex) error {
+	var b []byte
+	for _, enc := range encodings {
+		if !cfg.force {
+			// WithPrecompressed only serves a version which is at least as
+			// new as the original, so such a version is up to date.
+			ci, err := os.Stat(name + enc.suffix)
+			if err == nil && !ci.ModTime().Before(info.ModTime()) {
+				continue
+			}
+		}
+		if b == nil {
+			var err error
+			b, err = os.ReadFile(name)
+			if err != nil {
+				return err
+			}
+		}
+		cb, err := enc.compress(b)
+		if err != nil {
+			return fmt.Errorf("error compressing %s: %w", name, err)
+		}
+		if len(cb) >= len(b) {
+			continue
+		}
+		if err := renameio.WriteFile(name+enc.suffix, cb, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if cfg.log != nil {
+			logMu.Lock()
+			cfg.log(name+enc.suffix, int64(len(b)), int64(len(cb)))
+			logMu.Unlock()
+		}
+	}
+	return nil
+}
+
+func compressBrotli(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func compressGzip(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressibleTypes lists the media types, other than text/*, of files
+// which are worth compressing. Most other formats (such as PNG, WOFF2, and
+// MP4) are compressed already.
+var compressibleTypes = map[string]bool{
+	"application/javascript":        true,
+	"application/json":              true,
+	"application/manifest+json":     true,
+	"application/wasm":              true,
+	"application/xml":               true,
+	"application/vnd.ms-fontobject": true,
+	"image/svg+xml":                 true,
+	"image/x-icon":                  true,
+	"image/vnd.microsoft.icon":      true,
+	"font/otf":                      true,
+	"font/ttf":                      true,
+}
+
+// compressibleExts covers extensions which aren't in the mime package's
+// built-in table.
+var compressibleExts = map[string]bool{
+	".map":         true,
+	".mjs":         true,
+	".wasm":        true,
+	".webmanifest": true,
+	".ttf":         true,
+	".otf":         true,
+	".eot":         true,
+	".ico":         true,
+}
+
+// compressible reports whether the named file is of a type which is worth
+// compressing.
+func compressible(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if compressibleExts[ext] {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(mime.TypeByExtension(ext), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if mediaType == "" {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		compressibleTypes[mediaType] ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressDir(t *testing.T) {
+	dir := t.TempDir()
+	css := strings.Repeat("body { color: red; }\n", 100)
+	files := map[string]string{
+		"css/style.css": css,
+		"small.js":      "f()",
+		"img/logo.png":  strings.Repeat("x", 1000),
+		".cache/a.css":  css,
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var written []string
+	cfg := config{
+		minSize: 256,
+		log: func(name string, size, compressed int64) {
+			rel, _ := filepath.Rel(dir, name)
+			written = append(written, filepath.ToSlash(rel))
+		},
+	}
+	if err := compressDir(context.Background(), dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"css/style.css.br", "css/style.css.gz"}
+	if strings.Join(written, " ") != strings.Join(want, " ") {
+		t.Fatalf("wrote %q; want %q", written, want)
+	}
+
+	for _, tt := range []struct {
+		suffix string
+		reader func(io.Reader) (io.Reader, error)
+	}{
+		{".br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, "css/style.css"+tt.suffix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := tt.reader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != css {
+			t.Errorf("%s version doesn't decompress to the original", tt.suffix)
+		}
+	}
+
+	// Up-to-date versions are left alone.
+	written = nil
+	if err := compressDir(context.Background(), dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) > 0 {
+		t.Errorf("second run wrote %q; want nothing", written)
+	}
+
+	// Changing a file makes its versions out of date.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "css/style.css"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressDir(context.Background(), dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 {
+		t.Errorf("after change, wrote %q; want both versions", written)
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want bool
+	}{
+		{"a.css", true},
+		{"a.JS", true},
+		{"a.mjs", true},
+		{"a.html", true},
+		{"a.svg", true},
+		{"a.json", true},
+		{"a.js.map", true},
+		{"app.wasm", true},
+		{"font.ttf", true},
+		{"a.png", false},
+		{"a.woff2", false},
+		{"a.css.gz", false},
+		{"a.css.br", false},
+		{"noext", false},
+	} {
+		if got := compressible(tt.name); got != tt.want {
+			t.Errorf("compressible(%q) = %t; want %t", tt.name, got, tt.want)
+		}
+	}
+}
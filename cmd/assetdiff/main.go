@@ -0,0 +1,168 @@
+// Command assetdiff compares two manifests of asset files and reports the
+// assets that were added, removed, or changed, with the change in size of
+// each. It is useful for writing release notes and, with -max-growth, for
+// failing CI builds whose assets grow unexpectedly.
+//
+// Usage:
+//
+//	assetdiff [flags] old new
+//
+// Each of old and new is either a JSON manifest, as served by the
+// "/integrity" endpoint of assetserver's AdminHandler, or an asset directory,
+// whose manifest assetdiff computes. So a deploy can be compared with its
+// build output using
+//
+//	curl -H "$AUTH" https://example.com/admin/integrity > serving.json
+//	assetdiff serving.json dist
+//
+// The flags are:
+//
+//	-json
+//		print the changes as JSON rather than as text
+//	-max-growth int
+//		exit with status 1 if the total size of the assets grows by more
+//		than this many bytes (default -1, meaning no limit)
+//
+// The text output has a line for each changed asset and a summary:
+//
+//	changed  js/app.js  1000 -> 1200  +200
+//	added    js/new.js  70            +70
+//	removed  js/old.js  50            -50
+//	3 assets: 1 added, 1 removed, 1 changed; total size +220 bytes
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cespare/assetserver"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assetdiff: ")
+	var (
+		asJSON    = flag.Bool("json", false, "print the changes as JSON rather than as text")
+		maxGrowth = flag.Int64("max-growth", -1, "exit with status 1 if the total size of the assets grows by more than this many bytes (-1 means no limit)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: assetdiff [flags] old new\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	old, err := readManifest(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := readManifest(flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes := assetserver.DiffManifests(old, new)
+	if *asJSON {
+		err = writeJSON(os.Stdout, changes)
+	} else {
+		err = writeText(os.Stdout, changes)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if growth := totalDelta(changes); *maxGrowth >= 0 && growth > *maxGrowth {
+		log.Printf("total size grew by %d bytes (more than %d)", growth, *maxGrowth)
+		os.Exit(1)
+	}
+}
+
+// readManifest reads the manifest in the named file or computes the manifest
+// of the named directory.
+func readManifest(name string) ([]assetserver.IntegrityEntry, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		s := assetserver.New(os.DirFS(name))
+		defer s.Close()
+		return s.Integrity(context.Background())
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var entries []assetserver.IntegrityEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return entries, nil
+}
+
+func totalDelta(changes []assetserver.AssetChange) int64 {
+	var delta int64
+	for _, c := range changes {
+		delta += c.SizeDelta()
+	}
+	return delta
+}
+
+// writeText writes a line for each change and a summary to w.
+func writeText(w io.Writer, changes []assetserver.AssetChange) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	counts := make(map[assetserver.ChangeKind]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+		var sizes string
+		switch c.Kind {
+		case assetserver.AssetAdded:
+			sizes = fmt.Sprint(c.NewSize)
+		case assetserver.AssetRemoved:
+			sizes = fmt.Sprint(c.OldSize)
+		default:
+			sizes = fmt.Sprintf("%d -> %d", c.OldSize, c.NewSize)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%+d\n", c.Kind, c.Path, sizes, c.SizeDelta())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d assets: %d added, %d removed, %d changed; total size %+d bytes\n",
+		len(changes), counts[assetserver.AssetAdded], counts[assetserver.AssetRemoved],
+		counts[assetserver.AssetChanged], totalDelta(changes))
+	return err
+}
+
+// writeJSON writes the changes to w as a JSON array.
+func writeJSON(w io.Writer, changes []assetserver.AssetChange) error {
+	type change struct {
+		Path    string `json:"path"`
+		Kind    string `json:"kind"`
+		OldSize int64  `json:"old_size"`
+		NewSize int64  `json:"new_size"`
+		Delta   int64  `json:"delta"`
+	}
+	out := make([]change, len(changes))
+	for i, c := range changes {
+		out[i] = change{
+			Path:    c.Path,
+			Kind:    c.Kind.String(),
+			OldSize: c.OldSize,
+			NewSize: c.NewSize,
+			Delta:   c.SizeDelta(),
+		}
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cespare/assetserver"
+)
+
+func TestDiff(t *testing.T) {
+	tmp := t.TempDir()
+	old := []assetserver.IntegrityEntry{
+		{Path: "css/style.css", Tag: "aaaaaaaaaa", SHA256: "a1", Size: 100},
+		{Path: "js/app.js", Tag: "bbbbbbbbbb", SHA256: "b1", Size: 1000},
+		{Path: "js/old.js", Tag: "cccccccccc", SHA256: "c1", Size: 50},
+	}
+	b, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFile := filepath.Join(tmp, "old.json")
+	if err := os.WriteFile(oldFile, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	newDir := filepath.Join(tmp, "dist")
+	for name, size := range map[string]int{
+		"js/app.js": 1200,
+		"js/new.js": 70,
+	} {
+		p := filepath.Join(newDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, bytes.Repeat([]byte("x"), size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldEntries, err := readManifest(oldFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newEntries, err := readManifest(newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := assetserver.DiffManifests(oldEntries, newEntries)
+
+	var buf bytes.Buffer
+	if err := writeText(&buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	want := `removed  css/style.css  100           -100
+changed  js/app.js      1000 -> 1200  +200
+added    js/new.js      70            +70
+removed  js/old.js      50            -50
+4 assets: 1 added, 2 removed, 1 changed; total size +120 bytes
+`
+	if got := buf.String(); got != want {
+		t.Errorf("text output: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := writeJSON(&buf, changes[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	want = `[
+  {
+    "path": "js/app.js",
+    "kind": "changed",
+    "old_size": 1000,
+    "new_size": 1200,
+    "delta": 200
+  }
+]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("JSON output: got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := totalDelta(changes); got != 120 {
+		t.Errorf("got total delta %d; want 120", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Command assetgen generates a Go file with a constant for each file in an
+// asset directory, so that references to assets are checked by the compiler
+// rather than failing at run time with 404 Not Found.
+//
+// It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/cespare/assetserver/cmd/assetgen -dir static
+//
+// For a directory containing css/style.css and js/main.js, the generated file
+// declares
+//
+//	const (
+//		AssetCSSStyleCSS = "css/style.css"
+//		AssetJSMainJS    = "js/main.js"
+//	)
+//
+// which can be passed to (*assetserver.Server).Tag.
+//
+// With -tags, assetgen also declares a constant with the tagged name of each
+// file (such as TaggedCSSStyleCSS), as computed by assetserver. This is
+// useful when the assets are embedded in the binary, since their tags can
+// then never change.
+//
+// Usage:
+//
+//	assetgen [flags]
+//
+// The flags are:
+//
+//	-dir string
+//		the asset directory (default ".")
+//	-o string
+//		the output file (default "assets_gen.go")
+//	-pkg string
+//		the package name (default $GOPACKAGE)
+//	-prefix string
+//		the prefix for constant names (default "Asset")
+//	-tags
+//		also generate constants for the tagged names
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cespare/assetserver"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assetgen: ")
+	var (
+		dir    = flag.String("dir", ".", "the asset directory")
+		out    = flag.String("o", "assets_gen.go", "the output file")
+		pkg    = flag.String("pkg", os.Getenv("GOPACKAGE"), "the package name (default $GOPACKAGE)")
+		prefix = flag.String("prefix", "Asset", "the prefix for constant names")
+		tags   = flag.Bool("tags", false, "also generate constants for the tagged names")
+	)
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		log.Fatal("no package name given (use -pkg or run with go generate)")
+	}
+	// Don't generate a constant for the output file if it's among the
+	// assets.
+	ignore, err := filepath.Rel(*dir, *out)
+	if err != nil {
+		ignore = ""
+	}
+	src, err := generate(os.DirFS(*dir), config{
+		pkg:    *pkg,
+		prefix: *prefix,
+		tags:   *tags,
+		ignore: filepath.ToSlash(ignore),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type config struct {
+	pkg    string
+	prefix string
+	tags   bool
+	ignore string // a file name to leave out (the output file)
+}
+
+// generate returns the Go source declaring constants for the files in fsys.
+func generate(fsys fs.FS, cfg config) ([]byte, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			// Skip hidden files and directories.
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || name == cfg.ignore {
+			return nil
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	idents := make(map[string]string) // identifier -> name
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by assetgen; DO NOT EDIT.\n\npackage %s\n\n", cfg.pkg)
+	buf.WriteString("// Asset names.\nconst (\n")
+	for _, name := range names {
+		id := identifier(cfg.prefix, name)
+		if other, ok := idents[id]; ok {
+			return nil, fmt.Errorf("%s and %s both map to the identifier %s", other, name, id)
+		}
+		idents[id] = name
+		fmt.Fprintf(&buf, "\t%s = %s\n", id, strconv.Quote(name))
+	}
+	buf.WriteString(")\n")
+
+	if cfg.tags {
+		s := assetserver.New(fsys)
+		defer s.Close()
+		buf.WriteString("\n// Tagged asset names.\nconst (\n")
+		for _, name := range names {
+			tagged, err := s.Tag(name)
+			if err != nil {
+				return nil, err
+			}
+			id := "Tagged" + strings.TrimPrefix(identifier(cfg.prefix, name), cfg.prefix)
+			if other, ok := idents[id]; ok {
+				return nil, fmt.Errorf("%s and the tagged name of %s both map to the identifier %s", other, name, id)
+			}
+			idents[id] = name
+			fmt.Fprintf(&buf, "\t%s = %s\n", id, strconv.Quote(tagged))
+		}
+		buf.WriteString(")\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// commonInitialisms are written in all caps in identifiers, following Go
+// naming conventions.
+var commonInitialisms = map[string]bool{
+	"css": true, "js": true, "html": true, "json": true, "svg": true,
+	"xml": true, "id": true, "url": true, "api": true, "ui": true,
+}
+
+// identifier returns the Go identifier for the named file: the prefix
+// followed by each alphanumeric word of the name, capitalized.
+func identifier(prefix, name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, w := range words {
+		if commonInitialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/assetserver"
+)
+
+func TestGenerate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css":      &fstest.MapFile{Data: []byte("body{}")},
+		"js/main.js":         &fstest.MapFile{Data: []byte("main()")},
+		"img/hero-image.png": &fstest.MapFile{Data: []byte("png")},
+		"assets_gen.go":      &fstest.MapFile{Data: []byte("package x")},
+		".hidden/x.js":       &fstest.MapFile{Data: []byte("x")},
+	}
+	got, err := generate(fsys, config{pkg: "web", prefix: "Asset", ignore: "assets_gen.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by assetgen; DO NOT EDIT.
+
+package web
+
+// Asset names.
+const (
+	AssetCSSStyleCSS     = "css/style.css"
+	AssetImgHeroImagePng = "img/hero-image.png"
+	AssetJSMainJS        = "js/main.js"
+)
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGenerateTags(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	got, err := generate(fsys, config{pkg: "web", prefix: "Asset", tags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagged, err := assetserver.New(fsys).Tag("css/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `TaggedCSSStyleCSS = "` + tagged + `"`
+	if !strings.Contains(string(got), want) {
+		t.Errorf("output doesn't contain %q:\n%s", want, got)
+	}
+}
+
+func TestGenerateCollision(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a-b.css": &fstest.MapFile{Data: []byte("1")},
+		"a_b.css": &fstest.MapFile{Data: []byte("2")},
+	}
+	if _, err := generate(fsys, config{pkg: "web", prefix: "Asset"}); err == nil {
+		t.Error("got nil error for colliding identifiers")
+	}
+}
@@ -0,0 +1,132 @@
+// Command assethtml rewrites the references to assets in static HTML files to
+// use their tagged names, as assetserver's WithHTMLRewriting does at run
+// time. It copies an asset directory to an output directory, rewriting the
+// HTML files along the way; the output is ready to be embedded in a binary
+// and served by an assetserver.Server which doesn't need to do any rewriting.
+//
+// Usage:
+//
+//	assethtml [flags] -o outdir [dir]
+//
+// The directory defaults to the current directory. The flags are:
+//
+//	-css
+//		also rewrite url(...) references in CSS files (in which case
+//		references from HTML files to stylesheets are left unchanged)
+//	-integrity
+//		add integrity attributes to rewritten <script> and <link> elements
+//	-o string
+//		the output directory (required)
+//	-prefix string
+//		the URL path at which the files will be served, such as "/static/";
+//		root-relative references starting with it are rewritten as well
+//
+// Hidden files and directories (those whose names begin with ".") are not
+// copied.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cespare/assetserver"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assethtml: ")
+	var (
+		out = flag.String("o", "", "the output directory (required)")
+		cfg config
+	)
+	flag.BoolVar(&cfg.css, "css", false, "also rewrite url(...) references in CSS files")
+	flag.BoolVar(&cfg.integrity, "integrity", false, "add integrity attributes to rewritten <script> and <link> elements")
+	flag.StringVar(&cfg.prefix, "prefix", "", "the URL path at which the files will be served, such as \"/static/\"")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: assethtml [flags] -o outdir [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	// Don't copy the output directory into itself if it's inside dir.
+	if rel, err := filepath.Rel(dir, *out); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		if rel == "." {
+			log.Fatal("the output directory must differ from the input directory")
+		}
+		cfg.ignore = filepath.ToSlash(rel)
+	}
+	if err := build(os.DirFS(dir), *out, cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type config struct {
+	css       bool
+	integrity bool
+	prefix    string
+	ignore    string // a directory to leave out (the output directory)
+}
+
+// build copies the files in fsys to the directory out, rewriting HTML (and,
+// if cfg.css is set, CSS) files to refer to tagged names.
+func build(fsys fs.FS, out string, cfg config) error {
+	opts := []assetserver.Option{
+		assetserver.WithHTMLRewriting(assetserver.HTMLRewriteOptions{
+			Prefix:    cfg.prefix,
+			Integrity: cfg.integrity,
+		}),
+	}
+	if cfg.css {
+		opts = append(opts, assetserver.WithCSSRewriting())
+	}
+	s := assetserver.New(fsys, opts...)
+	defer s.Close()
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && (strings.HasPrefix(d.Name(), ".") || name == cfg.ignore) {
+			// Skip hidden files and directories.
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		dst := filepath.Join(out, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		var b []byte
+		switch ext := strings.ToLower(path.Ext(name)); {
+		case ext == ".html", cfg.css && ext == ".css":
+			b, err = s.ReadFile(name)
+		default:
+			b, err = fs.ReadFile(fsys, name)
+		}
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, b, 0o644)
+	})
+}
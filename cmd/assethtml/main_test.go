@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/assetserver"
+)
+
+func TestBuild(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    &fstest.MapFile{Data: []byte(`<link rel="stylesheet" href="/static/css/style.css"><img src="img/logo.png">`)},
+		"css/style.css": &fstest.MapFile{Data: []byte(`body{background:url(../img/logo.png)}`)},
+		"img/logo.png":  &fstest.MapFile{Data: []byte("png")},
+		".git/HEAD":     &fstest.MapFile{Data: []byte("ref")},
+		"out/old.html":  &fstest.MapFile{Data: []byte("old")},
+	}
+	s := assetserver.New(fsys)
+	defer s.Close()
+	tag := func(name string) string {
+		t.Helper()
+		tagged, err := s.Tag(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tagged
+	}
+	css, logo := tag("css/style.css"), tag("img/logo.png")
+
+	for _, tt := range []struct {
+		cfg  config
+		want map[string]string
+	}{
+		{
+			cfg: config{prefix: "/static/", ignore: "out"},
+			want: map[string]string{
+				"index.html":    `<link rel="stylesheet" href="/static/` + css + `"><img src="` + logo + `">`,
+				"css/style.css": `body{background:url(../img/logo.png)}`,
+				"img/logo.png":  "png",
+			},
+		},
+		{
+			// Since the stylesheet is rewritten as well, the reference to
+			// it is left alone.
+			cfg: config{css: true, prefix: "/static/", ignore: "out"},
+			want: map[string]string{
+				"index.html":    `<link rel="stylesheet" href="/static/css/style.css"><img src="` + logo + `">`,
+				"css/style.css": `body{background:url(../` + logo + `)}`,
+				"img/logo.png":  "png",
+			},
+		},
+	} {
+		out := t.TempDir()
+		if err := build(fsys, out, tt.cfg); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range tt.want {
+			b, err := os.ReadFile(filepath.Join(out, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != want {
+				t.Errorf("%+v: %s: got %q; want %q", tt.cfg, name, b, want)
+			}
+		}
+		for _, name := range []string{".git/HEAD", "out/old.html"} {
+			if _, err := os.Stat(filepath.Join(out, name)); !os.IsNotExist(err) {
+				t.Errorf("%+v: %s was copied", tt.cfg, name)
+			}
+		}
+	}
+}
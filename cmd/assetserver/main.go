@@ -0,0 +1,164 @@
+// Command assetserver serves a directory of static files over HTTP using the
+// assetserver package. It is useful for trying out the package's behavior,
+// such as the caching headers that a production deployment would send,
+// without writing a Go program, and for serving assets built by non-Go tools.
+//
+// Files are served at their plain names, which clients may cache for a
+// minute, and at their tagged names (such as style.1b2cf9aa.css), which are
+// cached forever. The tagged name of a file can be found by requesting the
+// plain name with HEAD and reading its ETag.
+//
+// With -dev, files are never cached, and HTML pages reload automatically when
+// any file changes.
+//
+// Usage:
+//
+//	assetserver [flags] [dir]
+//
+// The directory defaults to the current directory. The flags are:
+//
+//	-addr string
+//		the address to listen on (default "localhost:8080")
+//	-dev
+//		development mode: disable caching and reload pages when files change
+//	-log
+//		log each request to stderr
+//	-precompressed
+//		serve .br and .gz versions of files to clients that accept them
+//	-prefix string
+//		the URL path prefix under which the files are served (default "/")
+//	-preload
+//		hash all files before serving
+//	-prehashed patterns
+//		a comma-separated list of patterns matching files which have
+//		content hashes in their names, such as "assets/**", to be
+//		treated as immutable
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/cespare/assetserver"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assetserver: ")
+	var (
+		addr = flag.String("addr", "localhost:8080", "the address to listen on")
+		cfg  config
+	)
+	flag.BoolVar(&cfg.dev, "dev", false, "development mode: disable caching and reload pages when files change")
+	flag.BoolVar(&cfg.log, "log", false, "log each request to stderr")
+	flag.BoolVar(&cfg.precompressed, "precompressed", false, "serve .br and .gz versions of files to clients that accept them")
+	flag.StringVar(&cfg.prefix, "prefix", "/", "the URL path prefix under which the files are served")
+	flag.BoolVar(&cfg.preload, "preload", false, "hash all files before serving")
+	flag.Func("prehashed", "a comma-separated list of `patterns` matching files which have content hashes in their names", func(v string) error {
+		cfg.preHashed = append(cfg.preHashed, strings.Split(v, ",")...)
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: assetserver [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if fi, err := os.Stat(dir); err != nil {
+		log.Fatal(err)
+	} else if !fi.IsDir() {
+		log.Fatalf("%s is not a directory", dir)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s, h := newHandler(os.DirFS(dir), cfg)
+	defer s.Close()
+	if cfg.preload && !cfg.dev {
+		start := time.Now()
+		if err := s.Preload(ctx); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("preloaded %s in %s", dir, time.Since(start).Round(time.Millisecond))
+	}
+
+	hs := &http.Server{Addr: *addr, Handler: h}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		hs.Shutdown(shutdownCtx)
+	}()
+	log.Printf("serving %s at http://%s%s", dir, *addr, cfg.prefix)
+	if err := hs.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+type config struct {
+	dev           bool
+	log           bool
+	precompressed bool
+	prefix        string
+	preload       bool
+	preHashed     []string // patterns
+}
+
+// liveReloadPath is where the live reload client is served in dev mode.
+const liveReloadPath = "/_assetserver/livereload"
+
+// newHandler returns a Server for fsys configured according to cfg and the
+// handler that serves it.
+func newHandler(fsys fs.FS, cfg config) (*assetserver.Server, http.Handler) {
+	var opts []assetserver.Option
+	if cfg.log {
+		opts = append(opts, assetserver.WithAccessLog(logAccess))
+	}
+	if cfg.precompressed {
+		opts = append(opts, assetserver.WithPrecompressed())
+	}
+	if len(cfg.preHashed) > 0 {
+		opts = append(opts, assetserver.WithPreHashed(cfg.preHashed...))
+	}
+	mux := http.NewServeMux()
+	var s *assetserver.Server
+	if cfg.dev {
+		opts = append(opts,
+			assetserver.WithLiveReload(500*time.Millisecond),
+			assetserver.WithLiveReloadInjection(liveReloadPath),
+		)
+		s = assetserver.NewNoCache(fsys, opts...)
+		mux.Handle(liveReloadPath, s.LiveReloadHandler())
+	} else {
+		s = assetserver.New(fsys, opts...)
+	}
+	prefix := "/" + strings.Trim(cfg.prefix, "/")
+	if prefix == "/" {
+		mux.Handle("/", s)
+	} else {
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, s))
+	}
+	return s, mux
+}
+
+func logAccess(e assetserver.AccessEvent) {
+	log.Printf("%s %s %d %dB %s", e.Method, e.Path, e.Status, e.Bytes, e.Duration.Round(time.Microsecond))
+}
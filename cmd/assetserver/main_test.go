@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":           &fstest.MapFile{Data: []byte("<html><body>hi</body></html>")},
+		"css/style.css":        &fstest.MapFile{Data: []byte("body{}")},
+		"assets/app.3f9c2b.js": &fstest.MapFile{Data: []byte("app()")},
+	}
+}
+
+func get(t *testing.T, h http.Handler, path string) (*http.Response, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	resp := w.Result()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestHandler(t *testing.T) {
+	s, h := newHandler(testFS(), config{prefix: "/static/", preHashed: []string{"assets/**"}})
+	defer s.Close()
+	tagged, err := s.Tag("css/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path         string
+		status       int
+		cacheControl string
+	}{
+		{"/static/css/style.css", 200, "public, max-age=60"},
+		{"/static/" + tagged, 200, "public, max-age=31536000, immutable"},
+		{"/static/assets/app.3f9c2b.js", 200, "public, max-age=31536000, immutable"},
+		{"/css/style.css", 404, ""},
+	} {
+		resp, _ := get(t, h, tt.path)
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.status != 200 {
+			continue
+		}
+		if got := resp.Header.Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("GET %s: got Cache-Control %q; want %q", tt.path, got, tt.cacheControl)
+		}
+	}
+}
+
+func TestHandlerDev(t *testing.T) {
+	s, h := newHandler(testFS(), config{dev: true, prefix: "/"})
+	defer s.Close()
+	_, body := get(t, h, "/index.html")
+	if !strings.Contains(body, liveReloadPath) {
+		t.Errorf("dev mode page doesn't load the live reload client:\n%s", body)
+	}
+	resp, body := get(t, h, liveReloadPath)
+	if resp.StatusCode != 200 || !strings.Contains(resp.Header.Get("Content-Type"), "javascript") || body == "" {
+		t.Errorf("GET %s: got status %d, Content-Type %q", liveReloadPath, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
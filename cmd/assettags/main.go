@@ -0,0 +1,144 @@
+// Command assettags prints the tagged name of each file in an asset
+// directory, as computed by assetserver. The output is a manifest which can
+// be checked into version control and compared in CI to catch unexpected
+// asset changes, or fed to scripts which upload the tagged files to a CDN.
+//
+// Usage:
+//
+//	assettags [flags] [dir]
+//
+// The directory defaults to the current directory. The flags are:
+//
+//	-format string
+//		the output format: json or tsv (default "json")
+//	-o string
+//		the output file (default standard output)
+//
+// In JSON format, the output is an object mapping each name to its tagged
+// name:
+//
+//	{
+//	  "css/style.css": "css/style.1b2cf9aa.css",
+//	  "js/main.js": "js/main.0e4d7a31.js"
+//	}
+//
+// In TSV format, each line has a name and its tagged name, separated by a
+// tab. In both formats, the files are listed in order by name. Hidden files
+// and directories (those whose names begin with ".") are left out.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cespare/assetserver"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assettags: ")
+	var (
+		format = flag.String("format", "json", "the output format: json or tsv")
+		out    = flag.String("o", "", "the output file (default standard output)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: assettags [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format != "json" && *format != "tsv" {
+		log.Fatalf("unknown format %q (want json or tsv)", *format)
+	}
+	tags, err := readTags(os.DirFS(dir))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		if err := writeTags(os.Stdout, tags, *format); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeTags(f, tags, *format); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readTags returns the tagged name of each file in fsys, keyed by name.
+func readTags(fsys fs.FS) (map[string]string, error) {
+	s := assetserver.New(fsys)
+	defer s.Close()
+	tags := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			// Skip hidden files and directories.
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		tagged, err := s.Tag(name)
+		if err != nil {
+			return err
+		}
+		tags[name] = tagged
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// writeTags writes tags to w in the given format (json or tsv).
+func writeTags(w io.Writer, tags map[string]string, format string) error {
+	if format == "json" {
+		// Map keys are sorted by encoding/json.
+		b, err := json.MarshalIndent(tags, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		fmt.Fprintf(bw, "%s\t%s\n", name, tags[name])
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTags(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+		"js/main.js":    &fstest.MapFile{Data: []byte("main()")},
+		".git/HEAD":     &fstest.MapFile{Data: []byte("ref")},
+	}
+	tags, err := readTags(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("got %d tags; want 2: %v", len(tags), tags)
+	}
+	css, js := tags["css/style.css"], tags["js/main.js"]
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{"json", "{\n  \"css/style.css\": \"" + css + "\",\n  \"js/main.js\": \"" + js + "\"\n}\n"},
+		{"tsv", "css/style.css\t" + css + "\njs/main.js\t" + js + "\n"},
+	} {
+		var buf bytes.Buffer
+		if err := writeTags(&buf, tags, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tt.format, got, tt.want)
+		}
+	}
+	if css == "css/style.css" || js == "js/main.js" {
+		t.Errorf("names weren't tagged: %v", tags)
+	}
+}
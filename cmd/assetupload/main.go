@@ -0,0 +1,165 @@
+// Command assetupload uploads the files in an asset directory to an object
+// store or CDN under their tagged names, as computed by assetserver, using
+// HTTP PUT requests. Each request has Content-Type and Cache-Control headers
+// matching those that an assetserver.Server would send. This works with
+// stores that accept plain PUT uploads, such as S3 and GCS (through their
+// XML APIs, with credentials supplied with -header) and WebDAV servers.
+//
+// Since tagged names change whenever the contents do, an object that already
+// exists never needs to be uploaded again; with -skip-existing, assetupload
+// checks for each object with a HEAD request first.
+//
+// Usage:
+//
+//	assetupload [flags] -url baseurl [dir]
+//
+// The directory defaults to the current directory. The flags are:
+//
+//	-header value
+//		an extra header to send with each request, as "Name: value"
+//		(may be repeated)
+//	-manifest string
+//		a file to which to write the JSON manifest mapping each name to
+//		its tagged name
+//	-n int
+//		the maximum number of concurrent uploads (default 8)
+//	-skip-existing
+//		don't upload objects which already exist
+//	-url string
+//		the URL that object names are relative to (required)
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/cespare/assetserver"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("assetupload: ")
+	var (
+		u            httpUploader
+		manifestFile = flag.String("manifest", "", "a file to which to write the JSON manifest mapping each name to its tagged name")
+		concurrency  = flag.Int("n", 8, "the maximum number of concurrent uploads")
+	)
+	u.header = make(http.Header)
+	flag.StringVar(&u.baseURL, "url", "", "the URL that object names are relative to (required)")
+	flag.BoolVar(&u.skipExisting, "skip-existing", false, "don't upload objects which already exist")
+	flag.Func("header", "an extra header to send with each request, as \"Name: value\" (may be repeated)", func(v string) error {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok {
+			return fmt.Errorf("header %q is not of the form \"Name: value\"", v)
+		}
+		u.header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: assetupload [flags] -url baseurl [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if u.baseURL == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s := assetserver.New(os.DirFS(dir))
+	defer s.Close()
+	manifest, err := s.Upload(ctx, &u, assetserver.UploadOptions{Concurrency: *concurrency})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *manifestFile != "" {
+		b, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*manifestFile, append(b, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// An httpUploader uploads objects with PUT requests.
+type httpUploader struct {
+	baseURL      string
+	header       http.Header
+	skipExisting bool
+	client       *http.Client // if nil, http.DefaultClient is used
+}
+
+func (u *httpUploader) Upload(ctx context.Context, obj *assetserver.Object) error {
+	if u.skipExisting {
+		resp, err := u.do(ctx, "HEAD", obj.Name, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+	}
+	h := http.Header{
+		"Content-Type":  {obj.ContentType},
+		"Cache-Control": {obj.CacheControl},
+	}
+	if obj.ContentEncoding != "" {
+		h.Set("Content-Encoding", obj.ContentEncoding)
+	}
+	resp, err := u.do(ctx, "PUT", obj.Name, obj.Body, h)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s: unexpected status %s", resp.Request.URL, resp.Status)
+	}
+	return nil
+}
+
+// do sends a request for the named object with the given body and headers
+// in addition to u.header.
+func (u *httpUploader) do(ctx context.Context, method, name string, body []byte, h http.Header) (*http.Response, error) {
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		elems[i] = url.PathEscape(elem)
+	}
+	target := strings.TrimSuffix(u.baseURL, "/") + "/" + strings.Join(elems, "/")
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, vs := range u.header {
+		req.Header[name] = vs
+	}
+	for name, vs := range h {
+		req.Header[name] = vs
+	}
+	client := u.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/assetserver"
+)
+
+func TestUpload(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		objects = make(map[string]http.Header)
+		puts    int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xyz" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "HEAD":
+			if _, ok := objects[r.URL.Path]; !ok {
+				http.NotFound(w, r)
+			}
+		case "PUT":
+			b, _ := io.ReadAll(r.Body)
+			h := r.Header.Clone()
+			h.Set("Body", string(b))
+			objects[r.URL.Path] = h
+			puts++
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		}
+	}))
+	defer ts.Close()
+
+	s := assetserver.New(fstest.MapFS{
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+		"logo.svgz":     &fstest.MapFile{Data: []byte("gzipped")},
+	})
+	defer s.Close()
+	u := &httpUploader{
+		baseURL:      ts.URL + "/assets/",
+		header:       http.Header{"Authorization": {"Bearer xyz"}},
+		skipExisting: true,
+	}
+	for i := 0; i < 2; i++ {
+		manifest, err := s.Upload(context.Background(), u, assetserver.UploadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagged := manifest["css/style.css"]
+		h, ok := objects["/assets/"+tagged]
+		if !ok {
+			t.Fatalf("%s was not uploaded; have %v", tagged, objects)
+		}
+		for name, want := range map[string]string{
+			"Body":             "body{}",
+			"Content-Type":     "text/css; charset=utf-8",
+			"Cache-Control":    "public, max-age=31536000, immutable",
+			"Content-Encoding": "",
+		} {
+			if got := h.Get(name); got != want {
+				t.Errorf("%s: got %q; want %q", name, got, want)
+			}
+		}
+		h = objects["/assets/"+manifest["logo.svgz"]]
+		if got, want := h.Get("Content-Encoding"), "gzip"; got != want {
+			t.Errorf("logo.svgz: got Content-Encoding %q; want %q", got, want)
+		}
+	}
+	if puts != 2 {
+		t.Errorf("got %d PUT requests; want 2", puts)
+	}
+
+	u.header = nil
+	if _, err := s.Upload(context.Background(), u, assetserver.UploadOptions{}); err == nil {
+		t.Error("unauthorized upload: got nil error")
+	}
+}
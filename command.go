@@ -0,0 +1,100 @@
+package assetserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CommandOptions configures a transformer created by [NewCommandTransformer].
+type CommandOptions struct {
+	// Command is the program to run followed by its arguments, such as
+	// []string{"sass", "--stdin"}. Any occurrence of "{name}" in an
+	// argument is replaced by the name of the file being transformed.
+	Command []string
+	// Dir is the working directory of the command. If empty, the command
+	// runs in the current directory.
+	Dir string
+	// Env, if non-nil, is the environment of the command. If nil, the
+	// command inherits the environment of the current process.
+	Env []string
+	// MaxConcurrent limits the number of instances of the command which
+	// run at once. If zero, it is runtime.GOMAXPROCS(0).
+	MaxConcurrent int
+	// Timeout, if positive, limits how long each run of the command may
+	// take. A command which runs for longer is killed.
+	Timeout time.Duration
+}
+
+// NewCommandTransformer returns a Transformer which pipes the contents of
+// each file through an external command, such as a CSS preprocessor, and
+// uses what the command writes to standard output as the transformed
+// contents. This lets a Server (typically one created with [NewDev]) compile
+// source assets itself rather than relying on a separate build step.
+//
+// If the command exits with a non-zero status, the transformation fails
+// with an error that includes what the command wrote to standard error.
+//
+// NewCommandTransformer panics if opts.Command is empty.
+func NewCommandTransformer(opts CommandOptions) Transformer {
+	if len(opts.Command) == 0 {
+		panic("assetserver: NewCommandTransformer called with an empty command")
+	}
+	n := opts.MaxConcurrent
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &commandTransformer{
+		opts: opts,
+		sem:  make(chan struct{}, n),
+	}
+}
+
+type commandTransformer struct {
+	opts CommandOptions
+	sem  chan struct{}
+}
+
+func (ct *commandTransformer) Transform(name string, in []byte) ([]byte, error) {
+	ct.sem <- struct{}{}
+	out, err := ct.run(name, in)
+	<-ct.sem
+	return out, err
+}
+
+func (ct *commandTransformer) run(name string, in []byte) ([]byte, error) {
+	ctx := context.Background()
+	if ct.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ct.opts.Timeout)
+		defer cancel()
+	}
+	args := make([]string, len(ct.opts.Command))
+	for i, arg := range ct.opts.Command {
+		args[i] = strings.ReplaceAll(arg, "{name}", name)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = ct.opts.Dir
+	cmd.Env = ct.opts.Env
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait indefinitely for the output of any subprocesses that
+	// outlive a killed command.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", ct.opts.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
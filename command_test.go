@@ -0,0 +1,84 @@
+package assetserver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCommandTransformer(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	countFile := filepath.Join(t.TempDir(), "count")
+	ct := NewCommandTransformer(CommandOptions{
+		Command: []string{"sh", "-c", `echo x >> "$COUNT_FILE"; printf '%s: ' "$1"; tr a-z A-Z`, "sh", "{name}"},
+		Env:     []string{"COUNT_FILE=" + countFile, "PATH=" + os.Getenv("PATH")},
+	})
+	runs := func() int {
+		b, _ := os.ReadFile(countFile)
+		return strings.Count(string(b), "x")
+	}
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello"), ModTime: time.Unix(1e9, 0)},
+		"b.txt": &fstest.MapFile{Data: []byte("hello"), ModTime: time.Unix(1e9, 0)},
+	}
+	s := NewNoCache(fsys, WithTransform(ct))
+	defer s.Close()
+	// The Server's caching of transformer outputs means that the command
+	// isn't run again for unchanged input.
+	for _, tt := range []struct {
+		name     string
+		in       string
+		want     string
+		wantRuns int
+	}{
+		{"a.txt", "hello", "a.txt: HELLO", 1},
+		{"a.txt", "hello", "a.txt: HELLO", 1},
+		{"b.txt", "hello", "b.txt: HELLO", 2},
+		{"a.txt", "goodbye", "a.txt: GOODBYE", 3},
+	} {
+		if f := fsys[tt.name]; string(f.Data) != tt.in {
+			f.Data = []byte(tt.in)
+			f.ModTime = f.ModTime.Add(time.Second)
+		}
+		out, err := s.ReadFile(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("ReadFile(%q) with input %q = %q; want %q", tt.name, tt.in, out, tt.want)
+		}
+		if got := runs(); got != tt.wantRuns {
+			t.Errorf("after ReadFile(%q) with input %q: command ran %d times; want %d", tt.name, tt.in, got, tt.wantRuns)
+		}
+	}
+}
+
+func TestCommandTransformerError(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	for _, tt := range []struct {
+		opts CommandOptions
+		want string
+	}{
+		{
+			CommandOptions{Command: []string{"sh", "-c", "echo 'syntax error' >&2; exit 1"}},
+			"sh: exit status 1: syntax error",
+		},
+		{
+			CommandOptions{Command: []string{"sh", "-c", "sleep 5"}, Timeout: 50 * time.Millisecond},
+			"sh: timed out after 50ms",
+		},
+	} {
+		_, err := NewCommandTransformer(tt.opts).Transform("a.css", []byte("a {}"))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Transform with %q: got error %v; want %q", tt.opts.Command, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package assetserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// infoWithoutOpen returns the cached info for the named file if the request
+// can be answered using that info alone, without opening the file. This is
+// the case for a request whose preconditions (If-Match, If-Unmodified-Since,
+// If-None-Match, and If-Modified-Since) can be evaluated against the cached
+// tag and modification time to produce a 304 Not Modified or 412
+// Precondition Failed response (as for most requests from returning
+// visitors), and for a plain HEAD request.
+//
+// If the request requires opening the file, or there is no up-to-date cached
+// info, infoWithoutOpen returns nil, nil.
+func (s *Server) infoWithoutOpen(r *http.Request, name string) (*fileInfo, error) {
+	h := r.Header
+	conditional := h.Get("If-Match") != "" ||
+		h.Get("If-Unmodified-Since") != "" ||
+		h.Get("If-None-Match") != "" ||
+		h.Get("If-Modified-Since") != ""
+	plainHead := r.Method == "HEAD" && h.Get("Range") == ""
+	if !conditional && !plainHead {
+		return nil, nil
+	}
+	info, err := s.tryCachedInfo(r.Context(), name)
+	if err != nil {
+		if err == errNoInfo {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if plainHead || checkPreconditions(r, info) != 0 {
+		s.reportCacheLookup(r.Context(), true)
+		return info, nil
+	}
+	return nil, nil
+}
+
+// checkPreconditions evaluates the preconditions of r, which must be a GET or
+// HEAD request, against info as specified by RFC 9110, section 13.2.2. It
+// returns the status code with which to respond (304 or 412) or, if the
+// request should be served normally, 0. (If-Range is left to
+// http.ServeContent.)
+func checkPreconditions(r *http.Request, info *fileInfo) int {
+	h := r.Header
+	modtime := time.Unix(0, info.mtime)
+	if im := h.Get("If-Match"); im != "" {
+		if !etagStrongMatch(im, info.tag) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := h.Get("If-Unmodified-Since"); ius != "" && !isZeroTime(modtime) {
+		if t, err := http.ParseTime(ius); err == nil && modtime.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if inm := h.Get("If-None-Match"); inm != "" {
+		if etagMatch(inm, info.tag) {
+			return http.StatusNotModified
+		}
+	} else if ims := h.Get("If-Modified-Since"); ims != "" && !isZeroTime(modtime) {
+		if t, err := http.ParseTime(ims); err == nil && !modtime.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// isZeroTime reports whether t is an unspecified modification time (either
+// zero or the Unix epoch), as for the files of an [embed.FS].
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
+
+// serveWithoutBody responds to a request which was selected by
+// infoWithoutOpen: either with 304 Not Modified, 412 Precondition Failed, or,
+// for a HEAD request, with the same headers that http.ServeContent would have
+// sent. The Cache-Control, ETag, and Content-Type headers must already be
+// set, and they are left as they are except that, like http.ServeContent,
+// serveWithoutBody omits the Content-Type of a 304 response.
+func serveWithoutBody(w http.ResponseWriter, r *http.Request, info *fileInfo) {
+	h := w.Header()
+	switch checkPreconditions(r, info) {
+	case http.StatusNotModified:
+		delete(h, "Content-Type")
+		delete(h, "Content-Length")
+		delete(h, "Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	case http.StatusPreconditionFailed:
+		delete(h, "Content-Type")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	if modtime := time.Unix(0, info.mtime); !isZeroTime(modtime) {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Accept-Ranges", "bytes")
+	if h.Get("Content-Encoding") == "" {
+		h.Set("Content-Length", strconv.FormatInt(info.contentLength(), 10))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// etagMatch reports whether an If-None-Match header value matches the tag,
+// using the weak comparison that RFC 9110 specifies for If-None-Match. The
+// ETags of precompressed responses (see [WithPrecompressed]), which add the
+// encoding to the tag, match as well.
+func etagMatch(header, tag string) bool {
+	return etagListMatch(header, tag, false)
+}
+
+// etagStrongMatch is like etagMatch, but it uses the strong comparison that
+// RFC 9110 specifies for If-Match: weak ETags never match.
+func etagStrongMatch(header, tag string) bool {
+	return etagListMatch(header, tag, true)
+}
+
+func etagListMatch(header, tag string, strong bool) bool {
+	if header == "" {
+		return false
+	}
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" {
+			return true
+		}
+		if strings.HasPrefix(etag, "W/") {
+			if strong {
+				continue
+			}
+			etag = etag[2:]
+		}
+		if etagIsTag(etag, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagIsTag reports whether etag is the ETag for the tag, either as is or
+// with the suffix added for a precompressed encoding.
+func etagIsTag(etag, tag string) bool {
+	v, ok := strings.CutPrefix(etag, `"`+tag)
+	if !ok {
+		return false
+	}
+	if v == `"` {
+		return true
+	}
+	for _, pe := range precompressedEncodings {
+		if v == "-"+pe.encoding+`"` {
+			return true
+		}
+	}
+	return false
+}
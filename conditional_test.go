@@ -0,0 +1,188 @@
+package assetserver
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServeWithoutOpen(t *testing.T) {
+	fsys := &countingFS{FS: os.DirFS("testdata/assets")}
+	s := New(fsys)
+	serve := func(method, target string, header ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	// The first HEAD request must open the file.
+	want := serve("HEAD", "/a.js")
+	if fsys.opens.Load() != 1 {
+		t.Fatalf("first HEAD: got %d opens; want 1", fsys.opens.Load())
+	}
+
+	// Subsequent HEAD requests are answered from the cache with the same
+	// headers.
+	got := serve("HEAD", "/a.js")
+	if got.Code != 200 {
+		t.Fatalf("cached HEAD: got status %d; want 200", got.Code)
+	}
+	if diff := cmp.Diff(got.Header(), want.Header()); diff != "" {
+		t.Fatalf("cached HEAD: wrong headers (-got, +want):\n%s", diff)
+	}
+
+	// So are conditional requests that match the tag, for both tagged and
+	// untagged names.
+	for _, target := range []string{"/a.js", "/a.sI22qapGJ0.js"} {
+		got = serve("GET", target, "If-None-Match", `"sI22qapGJ0"`)
+		if got.Code != 304 {
+			t.Fatalf("GET %s: got status %d; want 304", target, got.Code)
+		}
+	}
+	got = serve("GET", "/a.js", "If-None-Match", `"xyz", W/"sI22qapGJ0"`)
+	if got.Code != 304 {
+		t.Fatalf("GET with multiple etags: got status %d; want 304", got.Code)
+	}
+	if n := fsys.opens.Load(); n != 1 {
+		t.Fatalf("got %d opens; want 1", n)
+	}
+
+	// A request with a stale tag must still 404 and a request with a
+	// non-matching If-None-Match must still get the content.
+	if got := serve("GET", "/a.sI22qapGJ1.js", "If-None-Match", `"sI22qapGJ0"`); got.Code != 404 {
+		t.Fatalf("GET with stale tag: got status %d; want 404", got.Code)
+	}
+	if got := serve("GET", "/a.js", "If-None-Match", `"sI22qapGJ1"`); got.Code != 200 {
+		t.Fatalf("GET with wrong etag: got status %d; want 200", got.Code)
+	}
+}
+
+func TestETagMatch(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"abd"`, false},
+		{`abc`, false},
+		{`"x", "abc"`, true},
+		{`"x","y"`, false},
+		{`*`, true},
+	} {
+		if got := etagMatch(tt.header, "abc"); got != tt.want {
+			t.Errorf("etagMatch(%q, \"abc\"): got %t; want %t", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestETagStrongMatch(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, false},
+		{`"abc-br"`, true},
+		{`"abc-gzip"`, true},
+		{`"abc-zz"`, false},
+		{`"abcd"`, false},
+		{`W/"x", "abc"`, true},
+		{`*`, true},
+	} {
+		if got := etagStrongMatch(tt.header, "abc"); got != tt.want {
+			t.Errorf("etagStrongMatch(%q, \"abc\"): got %t; want %t", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPreconditionsWithoutOpen(t *testing.T) {
+	mtime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	mfs := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("css"), ModTime: mtime},
+	}
+	fsys := &countingFS{FS: mfs}
+	s := New(fsys)
+	if _, err := s.Tag("a.css"); err != nil {
+		t.Fatal(err)
+	}
+	opens := fsys.opens.Load()
+	etag := `"` + hashTag("css") + `"`
+	before := mtime.Add(-time.Hour).Format(http.TimeFormat)
+	after := mtime.Add(time.Hour).Format(http.TimeFormat)
+	same := mtime.Format(http.TimeFormat)
+	for _, tt := range []struct {
+		method   string
+		header   []string
+		code     int
+		wantOpen bool
+	}{
+		{"GET", []string{"If-None-Match", etag}, 304, false},
+		{"GET", []string{"If-Modified-Since", same}, 304, false},
+		{"GET", []string{"If-Modified-Since", after}, 304, false},
+		{"GET", []string{"If-Modified-Since", before}, 200, true},
+		// If-None-Match takes precedence over If-Modified-Since.
+		{"GET", []string{"If-None-Match", `"other"`, "If-Modified-Since", after}, 200, true},
+		{"GET", []string{"If-Match", `"other"`}, 412, false},
+		{"GET", []string{"If-Match", `W/` + etag}, 412, false},
+		{"GET", []string{"If-Match", etag}, 200, true},
+		{"GET", []string{"If-Match", etag, "If-None-Match", etag}, 304, false},
+		{"GET", []string{"If-Unmodified-Since", before}, 412, false},
+		{"GET", []string{"If-Unmodified-Since", same}, 200, true},
+		// If-Match takes precedence over If-Unmodified-Since.
+		{"GET", []string{"If-Match", etag, "If-Unmodified-Since", before}, 200, true},
+		{"HEAD", []string{"If-Modified-Since", same}, 304, false},
+		{"HEAD", []string{"If-Match", `"other"`}, 412, false},
+		{"HEAD", []string{"If-Modified-Since", before}, 200, false},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, "/a.css", nil)
+		for i := 0; i < len(tt.header); i += 2 {
+			req.Header.Set(tt.header[i], tt.header[i+1])
+		}
+		s.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s with %q: got status %d; want %d", tt.method, tt.header, w.Code, tt.code)
+		}
+		if got, want := w.Header().Get("ETag"), etag; got != want {
+			t.Errorf("%s with %q: got ETag %s; want %s", tt.method, tt.header, got, want)
+		}
+		if got, want := w.Header().Get("Cache-Control"), "public, max-age=60"; got != want {
+			t.Errorf("%s with %q: got Cache-Control %q; want %q", tt.method, tt.header, got, want)
+		}
+		n := fsys.opens.Load()
+		if opened := n > opens; opened != tt.wantOpen {
+			t.Errorf("%s with %q: opened file: %t; want %t", tt.method, tt.header, opened, tt.wantOpen)
+		}
+		opens = n
+	}
+}
+
+// countingFS is an fs.FS which counts calls to Open.
+type countingFS struct {
+	fs.FS
+	opens atomic.Int64
+}
+
+func (fsys *countingFS) Open(name string) (fs.File, error) {
+	fsys.opens.Add(1)
+	return fsys.FS.Open(name)
+}
+
+func (fsys *countingFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(fsys.FS, name)
+}
@@ -0,0 +1,91 @@
+package assetserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORSOptions configures [WithCORS].
+type CORSOptions struct {
+	// AllowedOrigins lists the origins (such as "https://www.example.com")
+	// of pages which may read the Server's responses. An origin of "*"
+	// allows all pages.
+	AllowedOrigins []string
+	// ExposeHeaders lists the response headers, beyond the few that are
+	// always exposed, which scripts may read. If it is nil, the ETag and
+	// Content-Length headers are exposed, so that scripts which fetch
+	// assets can use them for their own caching; if it is empty but not
+	// nil, no headers are exposed.
+	ExposeHeaders []string
+}
+
+// defaultExposeHeaders is the Access-Control-Expose-Headers header used if
+// CORSOptions.ExposeHeaders is nil.
+const defaultExposeHeaders = "ETag, Content-Length"
+
+// WithCORS makes the Server add Cross-Origin Resource Sharing headers to its
+// responses to requests from the origins given in opts, so that scripts on
+// those pages can fetch its assets and read the responses. For example,
+//
+//	WithCORS(CORSOptions{AllowedOrigins: []string{"https://www.example.com"}})
+//
+// Requests with credentials are not supported. Unless all origins are
+// allowed, the responses have a Vary: Origin header.
+//
+// A browser sends a preflight OPTIONS request before some cross-origin
+// requests (such as those with custom headers); to answer them, the Server
+// must accept OPTIONS (see [WithMethods]).
+func WithCORS(opts CORSOptions) Option {
+	c := &cors{
+		origins: make(map[string]bool),
+		expose:  defaultExposeHeaders,
+	}
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			c.any = true
+		}
+		c.origins[o] = true
+	}
+	if opts.ExposeHeaders != nil {
+		c.expose = strings.Join(opts.ExposeHeaders, ", ")
+	}
+	return func(s *Server) {
+		s.cors = c
+	}
+}
+
+type cors struct {
+	any     bool // all origins are allowed
+	origins map[string]bool
+	expose  string // the Access-Control-Expose-Headers header
+}
+
+// addCORSHeaders adds the CORS headers, if any, for the response to r.
+func (s *Server) addCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	c := s.cors
+	if c == nil {
+		return
+	}
+	h := w.Header()
+	if !c.any {
+		h.Add("Vary", "Origin")
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.any && !c.origins[origin] {
+		return
+	}
+	if c.any {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.expose != "" {
+		h.Set("Access-Control-Expose-Headers", c.expose)
+	}
+	if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+		h.Set("Access-Control-Allow-Methods", s.allowHeader())
+		if rh := r.Header.Get("Access-Control-Request-Headers"); rh != "" {
+			h.Set("Access-Control-Allow-Headers", rh)
+		}
+	}
+}
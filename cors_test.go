@@ -0,0 +1,90 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCORS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a")},
+	}
+	site := CORSOptions{AllowedOrigins: []string{"https://www.example.com"}}
+	for _, tt := range []struct {
+		opts        CORSOptions
+		method      string
+		origin      string
+		allowOrigin string
+		expose      string
+		vary        string
+	}{
+		{site, "GET", "", "", "", "Origin"},
+		{site, "GET", "https://www.example.com", "https://www.example.com", "ETag, Content-Length", "Origin"},
+		{site, "HEAD", "https://www.example.com", "https://www.example.com", "ETag, Content-Length", "Origin"},
+		{site, "GET", "https://evil.example.com", "", "", "Origin"},
+		{CORSOptions{AllowedOrigins: []string{"*"}}, "GET", "https://a.example.com", "*", "ETag, Content-Length", ""},
+		{CORSOptions{AllowedOrigins: []string{"*"}}, "GET", "", "", "", ""},
+		{
+			CORSOptions{AllowedOrigins: []string{"*"}, ExposeHeaders: []string{"ETag", "X-Asset-Release"}},
+			"GET", "https://a.example.com", "*", "ETag, X-Asset-Release", "",
+		},
+		{
+			CORSOptions{AllowedOrigins: []string{"*"}, ExposeHeaders: []string{}},
+			"GET", "https://a.example.com", "*", "", "",
+		},
+	} {
+		s := New(fsys, WithCORS(tt.opts))
+		r := httptest.NewRequest(tt.method, "/a.css", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Errorf("%+v: %s from %q: got status %d", tt.opts, tt.method, tt.origin, w.Code)
+		}
+		h := w.Header()
+		if got := h.Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+			t.Errorf("%+v: %s from %q: got Access-Control-Allow-Origin %q; want %q",
+				tt.opts, tt.method, tt.origin, got, tt.allowOrigin)
+		}
+		if got := h.Get("Access-Control-Expose-Headers"); got != tt.expose {
+			t.Errorf("%+v: %s from %q: got Access-Control-Expose-Headers %q; want %q",
+				tt.opts, tt.method, tt.origin, got, tt.expose)
+		}
+		if got := h.Get("Vary"); got != tt.vary {
+			t.Errorf("%+v: %s from %q: got Vary %q; want %q", tt.opts, tt.method, tt.origin, got, tt.vary)
+		}
+		s.Close()
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a")},
+	}
+	s := New(fsys,
+		WithMethods("GET", "HEAD", "OPTIONS"),
+		WithCORS(CORSOptions{AllowedOrigins: []string{"https://www.example.com"}}),
+	)
+	defer s.Close()
+	r := httptest.NewRequest("OPTIONS", "/a.css", nil)
+	r.Header.Set("Origin", "https://www.example.com")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	r.Header.Set("Access-Control-Request-Headers", "x-requested-with")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != 204 {
+		t.Fatalf("got status %d; want 204", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://www.example.com",
+		"Access-Control-Allow-Methods": "GET,HEAD,OPTIONS",
+		"Access-Control-Allow-Headers": "x-requested-with",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("got %s %q; want %q", header, got, want)
+		}
+	}
+}
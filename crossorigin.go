@@ -0,0 +1,67 @@
+package assetserver
+
+import "net/http"
+
+// A CrossOriginPolicy gives the cross-origin headers that a Server adds to
+// its responses. See [WithCrossOriginPolicy]. Empty fields add no header.
+type CrossOriginPolicy struct {
+	// ResourcePolicy is the Cross-Origin-Resource-Policy header:
+	// "same-origin", "same-site", or "cross-origin". It says which pages
+	// may load the Server's assets without CORS; pages with a
+	// Cross-Origin-Embedder-Policy of require-corp can only load assets
+	// from other origins if it is "cross-origin" (or if they are loaded
+	// with CORS).
+	ResourcePolicy string
+	// EmbedderPolicy is the Cross-Origin-Embedder-Policy header, such as
+	// "require-corp" or "credentialless".
+	EmbedderPolicy string
+	// OpenerPolicy is the Cross-Origin-Opener-Policy header, such as
+	// "same-origin".
+	OpenerPolicy string
+}
+
+// CrossOriginIsolation returns a CrossOriginPolicy for sites which are
+// cross-origin isolated (so that their pages can use SharedArrayBuffer and
+// high-resolution timers). Its assets may be loaded by pages on any origin,
+// and its HTML pages and workers get the Cross-Origin-Embedder-Policy and
+// Cross-Origin-Opener-Policy headers needed for isolation:
+//
+//	Cross-Origin-Resource-Policy: cross-origin
+//	Cross-Origin-Embedder-Policy: require-corp
+//	Cross-Origin-Opener-Policy: same-origin
+func CrossOriginIsolation() CrossOriginPolicy {
+	return CrossOriginPolicy{
+		ResourcePolicy: "cross-origin",
+		EmbedderPolicy: "require-corp",
+		OpenerPolicy:   "same-origin",
+	}
+}
+
+// WithCrossOriginPolicy makes the Server add the headers given by p to all
+// of its responses. Browsers only apply the Cross-Origin-Embedder-Policy and
+// Cross-Origin-Opener-Policy headers to documents (and, for the former,
+// workers) and ignore them for other assets, so they are sent regardless of
+// the type of file. For example, a CDN hostname serving assets to a
+// cross-origin isolated site might use
+//
+//	WithCrossOriginPolicy(CrossOriginPolicy{ResourcePolicy: "cross-origin"})
+//
+// while a Server for the site itself might use [CrossOriginIsolation].
+func WithCrossOriginPolicy(p CrossOriginPolicy) Option {
+	return func(s *Server) {
+		s.crossOrigin = p
+	}
+}
+
+// addCrossOriginHeaders adds the headers for the CrossOriginPolicy to h.
+func (s *Server) addCrossOriginHeaders(h http.Header) {
+	for _, hv := range []struct{ header, value string }{
+		{"Cross-Origin-Resource-Policy", s.crossOrigin.ResourcePolicy},
+		{"Cross-Origin-Embedder-Policy", s.crossOrigin.EmbedderPolicy},
+		{"Cross-Origin-Opener-Policy", s.crossOrigin.OpenerPolicy},
+	} {
+		if hv.value != "" {
+			h.Set(hv.header, hv.value)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCrossOriginPolicy(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("<html></html>")},
+		"a.css":      &fstest.MapFile{Data: []byte("a")},
+	}
+	for _, tt := range []struct {
+		p    CrossOriginPolicy
+		want map[string]string
+	}{
+		{
+			CrossOriginPolicy{},
+			map[string]string{
+				"Cross-Origin-Resource-Policy": "",
+				"Cross-Origin-Embedder-Policy": "",
+				"Cross-Origin-Opener-Policy":   "",
+			},
+		},
+		{
+			CrossOriginPolicy{ResourcePolicy: "same-site"},
+			map[string]string{
+				"Cross-Origin-Resource-Policy": "same-site",
+				"Cross-Origin-Embedder-Policy": "",
+				"Cross-Origin-Opener-Policy":   "",
+			},
+		},
+		{
+			CrossOriginIsolation(),
+			map[string]string{
+				"Cross-Origin-Resource-Policy": "cross-origin",
+				"Cross-Origin-Embedder-Policy": "require-corp",
+				"Cross-Origin-Opener-Policy":   "same-origin",
+			},
+		},
+	} {
+		s := New(fsys, WithCrossOriginPolicy(tt.p))
+		for _, path := range []string{"/index.html", "/a.css", "/b.css"} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			for header, want := range tt.want {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%+v: GET %s: got %s %q; want %q", tt.p, path, header, got, want)
+				}
+			}
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,172 @@
+package assetserver
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// WithCSSRewriting makes the Server rewrite the url(...) references in CSS
+// files to refer to the tagged names of the referenced files, so that fonts,
+// images, and so on used by stylesheets are cached as well as the stylesheets
+// themselves. The rewriting is a transformation (see [WithTransform]) that
+// applies to the files matching any of the given patterns, or to all .css
+// files if there are no patterns.
+//
+// Only relative references to files in the Server's file system are
+// rewritten; references to absolute URLs, root-relative paths, data: URLs,
+// nonexistent files, and files which are themselves transformed are left
+// unchanged. When a referenced file changes, the stylesheet is rewritten
+// (and so gets a new tag) as well.
+//
+// A no-cache Server does not use tagged names, so it leaves the references
+// unchanged.
+func WithCSSRewriting(patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.css"}
+	}
+	return WithTransform(cssRewriter{}, patterns...)
+}
+
+type cssRewriter struct{}
+
+// Transform returns in unchanged: the rewriting depends on the Server.
+func (cssRewriter) Transform(name string, in []byte) ([]byte, error) {
+	return in, nil
+}
+
+func (cssRewriter) transformRefs(name string, in []byte, refs *refResolver) ([]byte, error) {
+	var out []byte
+	rest := in
+	for {
+		i := indexFold(rest, "url(")
+		if i < 0 {
+			break
+		}
+		i += len("url(")
+		start, end, ok := parseCSSURL(rest[i:])
+		if !ok {
+			out = append(out, rest[:i]...)
+			rest = rest[i:]
+			continue
+		}
+		start += i
+		end += i
+		out = append(out, rest[:start]...)
+		ref := string(rest[start:end])
+		if tagged, ok := tagRef(name, ref, "", refs.tag); ok {
+			out = append(out, tagged...)
+		} else {
+			out = append(out, ref...)
+		}
+		rest = rest[end:]
+	}
+	if out == nil {
+		return in, nil
+	}
+	return append(out, rest...), nil
+}
+
+// parseCSSURL parses the contents of a CSS url() function, starting just
+// after the opening parenthesis. It returns the start and end offsets of the
+// URL (excluding any quotes).
+func parseCSSURL(b []byte) (start, end int, ok bool) {
+	i := 0
+	for i < len(b) && isCSSSpace(b[i]) {
+		i++
+	}
+	if i == len(b) {
+		return 0, 0, false
+	}
+	start = i
+	var j int // index of the closing parenthesis
+	if q := b[i]; q == '"' || q == '\'' {
+		start++
+		n := bytes.IndexByte(b[start:], q)
+		if n < 0 {
+			return 0, 0, false
+		}
+		end = start + n
+		j = end + 1
+		for j < len(b) && isCSSSpace(b[j]) {
+			j++
+		}
+	} else {
+		n := bytes.IndexByte(b[start:], ')')
+		if n < 0 {
+			return 0, 0, false
+		}
+		j = start + n
+		end = j
+		for end > start && isCSSSpace(b[end-1]) {
+			end--
+		}
+	}
+	if j == len(b) || b[j] != ')' || end == start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func isCSSSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// tagRef returns the tagged version of ref, a URL found in the file with the
+// given name. It reports false if ref does not refer to a file that can be
+// tagged.
+//
+// Relative URLs are resolved against the file's name. Root-relative URLs are
+// only resolved if prefix (the URL path at which the file system is served,
+// ending with a slash) is non-empty and they start with prefix.
+func tagRef(name, ref, prefix string, tag func(string) (string, bool)) (string, bool) {
+	p, suffix := ref, ""
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		p, suffix = ref[:i], ref[i:]
+	}
+	if p == "" || strings.HasPrefix(p, "//") {
+		return "", false
+	}
+	if strings.HasPrefix(p, "/") {
+		if prefix == "" || !strings.HasPrefix(p, prefix) {
+			return "", false
+		}
+		unescaped, err := url.PathUnescape(strings.TrimPrefix(p, prefix))
+		if err != nil {
+			return "", false
+		}
+		t, ok := tag(path.Clean(unescaped))
+		if !ok {
+			return "", false
+		}
+		return insertTag(p, t) + suffix, true
+	}
+	if i := strings.IndexAny(p, ":/"); i >= 0 && p[i] == ':' {
+		return "", false // absolute URL
+	}
+	unescaped, err := url.PathUnescape(p)
+	if err != nil {
+		return "", false
+	}
+	target := path.Join(path.Dir(name), unescaped)
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return "", false
+	}
+	t, ok := tag(target)
+	if !ok {
+		return "", false
+	}
+	return insertTag(p, t) + suffix, true
+}
+
+// indexFold returns the index of the first ASCII case-insensitive match of
+// the lowercase string sub in b, or -1 if there is none.
+func indexFold(b []byte, sub string) int {
+	for i := 0; i+len(sub) <= len(b); i++ {
+		if bytes.EqualFold(b[i:i+len(sub)], []byte(sub)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,104 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCSSRewriting(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/app.css": &fstest.MapFile{Data: []byte(`@font-face { src: url("../fonts/a.woff2") format("woff2"), url( '../fonts/a.woff' ); }
+body { background: URL(img/bg.png?v=1#x) }
+.a { background: url(data:image/png;base64,AAAA) }
+.b { background: url(https://example.com/x.png) }
+.c { background: url(/img/root.png) }
+.d { background: url(missing.png) }
+.e { background: url(../../outside.png) }
+.f { background: url(other.css) }
+.g { background: url(img/with%20space.png) }
+.h { background: url(`)},
+		"css/other.css":          &fstest.MapFile{Data: []byte("x")},
+		"css/img/bg.png":         &fstest.MapFile{Data: []byte("bg")},
+		"css/img/with space.png": &fstest.MapFile{Data: []byte("space")},
+		"fonts/a.woff2":          &fstest.MapFile{Data: []byte("woff2")},
+		"fonts/a.woff":           &fstest.MapFile{Data: []byte("woff")},
+		"img/root.png":           &fstest.MapFile{Data: []byte("root")},
+	}
+	s := New(fsys, WithCSSRewriting())
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/css/app.css", nil))
+	want := `@font-face { src: url("../fonts/a.` + hashTag("woff2") + `.woff2") format("woff2"), url( '../fonts/a.` + hashTag("woff") + `.woff' ); }
+body { background: URL(img/bg.` + hashTag("bg") + `.png?v=1#x) }
+.a { background: url(data:image/png;base64,AAAA) }
+.b { background: url(https://example.com/x.png) }
+.c { background: url(/img/root.png) }
+.d { background: url(missing.png) }
+.e { background: url(../../outside.png) }
+.f { background: url(other.css) }
+.g { background: url(img/with%20space.` + hashTag("space") + `.png) }
+.h { background: url(`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCSSRewritingNoCache(t *testing.T) {
+	css := "body { background: url(bg.png) }"
+	s := NewNoCache(fstest.MapFS{
+		"app.css": &fstest.MapFile{Data: []byte(css)},
+		"bg.png":  &fstest.MapFile{Data: []byte("bg")},
+	}, WithCSSRewriting())
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/app.css", nil))
+	if got := w.Body.String(); got != css {
+		t.Errorf("got %q; want %q", got, css)
+	}
+}
+
+func TestCSSRewritingDependencyChange(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string, mtime time.Time) {
+		t.Helper()
+		name = filepath.Join(dir, name)
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t0 := time.Now().Add(-time.Hour)
+	write("app.css", "body { background: url(bg.png) }", t0)
+	write("bg.png", "bg1", t0)
+
+	s := New(os.DirFS(dir), WithCSSRewriting())
+	get := func() (body, etag string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/app.css", nil))
+		return w.Body.String(), w.Header().Get("ETag")
+	}
+	body1, etag1 := get()
+	if want := "body { background: url(bg." + hashTag("bg1") + ".png) }"; body1 != want {
+		t.Fatalf("got %q; want %q", body1, want)
+	}
+	write("bg.png", "bg22", t0.Add(time.Minute))
+	body2, etag2 := get()
+	if want := "body { background: url(bg." + hashTag("bg22") + ".png) }"; body2 != want {
+		t.Fatalf("after changing bg.png, got %q; want %q", body2, want)
+	}
+	if etag1 == etag2 {
+		t.Error("ETag didn't change when a referenced file changed")
+	}
+	tagged, err := s.Tag("app.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "app." + hashTag(body2) + ".css"; tagged != want {
+		t.Errorf("Tag(app.css) = %q; want %q", tagged, want)
+	}
+}
@@ -0,0 +1,118 @@
+package assetserver
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+	"unsafe"
+)
+
+// DebugHandler returns an HTTP handler which renders an HTML page describing
+// the Server's cache: for each file with cached information, its tag, size,
+// content type, the last time the information was computed or checked
+// against the file, and the approximate memory used by the cache entry.
+//
+// The page is intended for debugging problems such as stale tags. It reveals
+// the names of all cached files, so applications should mount it somewhere
+// that is not publicly accessible.
+func (s *Server) DebugHandler() http.Handler {
+	return http.HandlerFunc(s.serveDebug)
+}
+
+type debugEntry struct {
+	Name        string
+	Tag         string
+	Size        int64
+	ContentType string
+	Validated   time.Time // zero if never
+	Mem         int
+}
+
+type debugPage struct {
+	TrustCache bool
+	NoCache    bool
+	Entries    []debugEntry
+	TotalSize  int64
+	TotalMem   int
+}
+
+func (s *Server) serveDebug(w http.ResponseWriter, r *http.Request) {
+	page := debugPage{
+		TrustCache: s.trustCache(),
+		NoCache:    s.noCache,
+	}
+	s.cache.each(func(name string, e *cacheEntry) {
+		info := e.Load()
+		if info == nil {
+			return
+		}
+		ent := debugEntry{
+			Name:        name,
+			Tag:         info.tag,
+			Size:        info.size,
+			ContentType: info.contentType,
+			Mem:         entryMemSize(name, info),
+		}
+		if v := e.validated.Load(); v > 0 {
+			ent.Validated = time.Unix(0, v)
+		}
+		page.Entries = append(page.Entries, ent)
+		page.TotalSize += ent.Size
+		page.TotalMem += ent.Mem
+	})
+	sort.Slice(page.Entries, func(i, j int) bool {
+		return page.Entries[i].Name < page.Entries[j].Name
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := debugTemplate.Execute(w, page); err != nil {
+		s.log(r.Context(), slog.LevelError, "error rendering debug page", "err", err)
+	}
+}
+
+// entryMemSize estimates the memory used by the cache entry for the named
+// file: the entry and its info, the strings they refer to, and the map slot.
+// It doesn't count the LRU list element of a bounded cache or content type
+// strings shared between entries.
+func entryMemSize(name string, info *fileInfo) int {
+	return int(unsafe.Sizeof(cacheEntry{})+unsafe.Sizeof(fileInfo{})) +
+		len(name) + len(info.tag) +
+		int(unsafe.Sizeof(name)+unsafe.Sizeof(&cacheEntry{}))
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>assetserver cache</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 8px; text-align: left; }
+td.num { text-align: right; }
+tr:nth-child(even) { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<h1>assetserver cache</h1>
+<p>
+{{len .Entries}} files ({{.TotalSize}} bytes); approximately {{.TotalMem}} bytes of memory used.
+{{if .NoCache}}The server is in no-cache mode.{{end}}
+{{if .TrustCache}}Cached information is trusted without checking the files.{{end}}
+</p>
+<table>
+<tr><th>Name</th><th>Tag</th><th>Size</th><th>Content type</th><th>Last validated</th><th>Memory</th></tr>
+{{range .Entries}}<tr>
+<td>{{.Name}}</td>
+<td><code>{{.Tag}}</code></td>
+<td class="num">{{.Size}}</td>
+<td>{{.ContentType}}</td>
+<td>{{if .Validated.IsZero}}never{{else}}{{.Validated.Format "2006-01-02 15:04:05.000 MST"}}{{end}}</td>
+<td class="num">{{.Mem}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
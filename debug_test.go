@@ -0,0 +1,48 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDebugHandler(t *testing.T) {
+	s := New(fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: []byte("hello")},
+		"b<x>.html": &fstest.MapFile{Data: []byte("<p>hi</p>")},
+		"c.txt":     &fstest.MapFile{Data: []byte("not requested")},
+	})
+	for _, name := range []string{"a.txt", "b<x>.html"} {
+		if _, err := s.Tag(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	s.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/assets", nil))
+	if w.Code != 200 {
+		t.Fatalf("got status %d", w.Code)
+	}
+	if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("got Content-Type %q; want %q", got, want)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"2 files (14 bytes)",
+		"<td>a.txt</td>",
+		"<code>" + hashTag("hello") + "</code>",
+		"<td>text/plain; charset=utf-8</td>",
+		"<td>b&lt;x&gt;.html</td>",
+		"<td>text/html; charset=utf-8</td>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("debug page does not contain %q; got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "c.txt") {
+		t.Error("debug page lists a file which is not cached")
+	}
+	if strings.Contains(body, "never") {
+		t.Error("debug page shows a file which was never validated")
+	}
+}
@@ -0,0 +1,92 @@
+package assetserver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+)
+
+// devPollInterval is the interval at which a Server created by NewDev checks
+// for changed files.
+const devPollInterval = 500 * time.Millisecond
+
+// NewDev creates a no-cache Server for development which serves files from
+// the local directory dir in preference to those in fsys. This is the usual
+// arrangement for an application that embeds its assets (using an
+// [embed.FS] for fsys): during development, the assets are served from the
+// source directory so that changes appear without rebuilding.
+//
+// The Server is created with [WithLiveReload] (checking for changes twice a
+// second) and the given options, which may override that. The application
+// should mount [Server.LiveReloadHandler] or use
+// [WithLiveReloadInjection] to have browsers reload automatically.
+func NewDev(fsys fs.FS, dir string, opts ...Option) *Server {
+	ofs := &overlayFS{upper: os.DirFS(dir), lower: fsys}
+	opts = append([]Option{WithLiveReload(devPollInterval)}, opts...)
+	return newServer(ofs, true, opts)
+}
+
+// An overlayFS combines two file systems. Files in upper hide those with the
+// same name in lower.
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (ofs *overlayFS) Open(name string) (fs.File, error) {
+	f, err := ofs.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ofs.lower.Open(name)
+	}
+	return f, err
+}
+
+func (ofs *overlayFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := fs.Stat(ofs.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.Stat(ofs.lower, name)
+	}
+	return fi, err
+}
+
+func (ofs *overlayFS) ReadFile(name string) ([]byte, error) {
+	b, err := fs.ReadFile(ofs.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.ReadFile(ofs.lower, name)
+	}
+	return b, err
+}
+
+// ReadDir merges the entries of the named directory in both file systems.
+func (ofs *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(ofs.upper, name)
+	if upperErr != nil && !errors.Is(upperErr, fs.ErrNotExist) {
+		return nil, upperErr
+	}
+	lower, lowerErr := fs.ReadDir(ofs.lower, name)
+	if lowerErr != nil {
+		if errors.Is(lowerErr, fs.ErrNotExist) && upperErr == nil {
+			return upper, nil
+		}
+		return nil, lowerErr
+	}
+	if upperErr != nil {
+		return lower, nil
+	}
+	seen := make(map[string]bool)
+	for _, d := range upper {
+		seen[d.Name()] = true
+	}
+	entries := upper
+	for _, d := range lower {
+		if !seen[d.Name()] {
+			entries = append(entries, d)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
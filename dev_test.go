@@ -0,0 +1,78 @@
+package assetserver
+
+import (
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewDev(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range map[string]string{
+		"a.txt":       "local a",
+		"css/app.css": "local css",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	embedded := fstest.MapFS{
+		"a.txt":         &fstest.MapFile{Data: []byte("embedded a")},
+		"b.txt":         &fstest.MapFile{Data: []byte("embedded b")},
+		"css/app.css":   &fstest.MapFile{Data: []byte("embedded css")},
+		"css/print.css": &fstest.MapFile{Data: []byte("embedded print")},
+	}
+	s := NewDev(embedded, dir)
+	defer s.Close()
+
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{"/a.txt", "local a"},
+		{"/b.txt", "embedded b"},
+		{"/css/app.css", "local css"},
+		{"/css/print.css", "embedded print"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s: got %q; want %q", tt.path, got, tt.want)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("GET %s: got Cache-Control %q; want no-cache", tt.path, got)
+		}
+	}
+	if tagged, err := s.Tag("a.txt"); err != nil || tagged != "a.txt" {
+		t.Errorf("Tag(a.txt) = %q, %v; want untagged name", tagged, err)
+	}
+	if s.liveReload == nil {
+		t.Error("NewDev did not enable live reload")
+	}
+
+	var names []string
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.txt", "b.txt", "css/app.css", "css/print.css"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("files (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,122 @@
+package assetserver
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+// WithCanonicalDuplicates makes [Server.Tag] return the same tagged name for
+// all files with identical contents and the same extension (as happens when
+// a vendored library is copied into several directories). The tagged name is
+// that of the first of the files in lexical order (or, if that file is
+// pre-hashed, its name; see [WithPreHashed]). Pages which refer to the
+// copies then share one cached response, in browsers and CDNs alike.
+//
+// The duplicates are found by walking the file system when Tag is first
+// called and again whenever the file that Tag would return has changed, so
+// duplicates created later may not be noticed until then. See
+// [Server.Duplicates] for a report of the duplicates.
+func WithCanonicalDuplicates() Option {
+	return func(s *Server) {
+		s.duplicates = new(duplicateIndex)
+	}
+}
+
+type duplicateIndex struct {
+	mu        sync.Mutex
+	canonical map[dupKey]string // for each set of duplicates, the first name
+}
+
+// A dupKey identifies a set of duplicate files.
+type dupKey struct {
+	tag string
+	ext string
+}
+
+// Duplicates walks the Server's file system and returns the sets of files
+// which have identical contents (as served, after any transformations) and
+// the same extension. Each set is sorted, and the sets are sorted by their
+// first names.
+//
+// If ctx is canceled, Duplicates stops early and returns the context's
+// error.
+func (s *Server) Duplicates(ctx context.Context) ([][]string, error) {
+	groups, err := s.findDuplicates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var dups [][]string
+	for _, names := range groups {
+		dups = append(dups, names)
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i][0] < dups[j][0] })
+	return dups, nil
+}
+
+// findDuplicates returns the sets of duplicate files, sorted by name.
+func (s *Server) findDuplicates(ctx context.Context) (map[dupKey][]string, error) {
+	groups := make(map[dupKey][]string)
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := s.currentInfo(ctx, name)
+		if err != nil {
+			return err
+		}
+		key := dupKey{tag: info.tag, ext: path.Ext(name)}
+		groups[key] = append(groups[key], name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key, names := range groups {
+		if len(names) < 2 {
+			delete(groups, key)
+			continue
+		}
+		sort.Strings(names)
+	}
+	return groups, nil
+}
+
+// canonicalName returns the name of the first file which duplicates the
+// named file, whose info is given, or name itself if there is none.
+func (s *Server) canonicalName(ctx context.Context, name string, info *fileInfo) (string, error) {
+	di := s.duplicates
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	key := dupKey{tag: info.tag, ext: path.Ext(name)}
+	for tries := 0; tries < 2; tries++ {
+		if di.canonical == nil {
+			groups, err := s.findDuplicates(ctx)
+			if err != nil {
+				return "", err
+			}
+			di.canonical = make(map[dupKey]string)
+			for key, names := range groups {
+				di.canonical[key] = names[0]
+			}
+		}
+		canonical, ok := di.canonical[key]
+		if !ok || canonical == name {
+			return name, nil
+		}
+		// Check that the canonical file hasn't changed since the walk.
+		if ci, err := s.currentInfo(ctx, canonical); err == nil && ci.tag == info.tag {
+			return canonical, nil
+		}
+		di.canonical = nil
+	}
+	return name, nil
+}
@@ -0,0 +1,64 @@
+package assetserver
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDuplicates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/jquery.js":     &fstest.MapFile{Data: []byte("jquery")},
+		"b/jquery.js":     &fstest.MapFile{Data: []byte("jquery")},
+		"c/lib/jquery.js": &fstest.MapFile{Data: []byte("jquery")},
+		"jquery.txt":      &fstest.MapFile{Data: []byte("jquery")},
+		"x.css":           &fstest.MapFile{Data: []byte("")},
+		"y.css":           &fstest.MapFile{Data: []byte("")},
+		"z.css":           &fstest.MapFile{Data: []byte("z")},
+	}
+	s := New(fsys, WithCanonicalDuplicates())
+	got, err := s.Duplicates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"a/jquery.js", "b/jquery.js", "c/lib/jquery.js"},
+		{"x.css", "y.css"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Duplicates (-want +got):\n%s", diff)
+	}
+
+	jquery := "a/jquery." + hashTag("jquery") + ".js"
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"a/jquery.js", jquery},
+		{"b/jquery.js", jquery},
+		{"/c/lib/jquery.js", "/" + jquery},
+		{"jquery.txt", "jquery." + hashTag("jquery") + ".txt"},
+		{"y.css", "x." + hashTag("") + ".css"},
+		{"z.css", "z." + hashTag("z") + ".css"},
+	} {
+		got, err := s.Tag(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Tag(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// When the canonical file changes, the next one takes its place.
+	fsys["a/jquery.js"] = &fstest.MapFile{Data: []byte("jquery 2")}
+	got1, err := s.Tag("c/lib/jquery.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b/jquery." + hashTag("jquery") + ".js"; got1 != want {
+		t.Errorf("after change, Tag(c/lib/jquery.js) = %q; want %q", got1, want)
+	}
+}
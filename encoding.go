@@ -0,0 +1,21 @@
+package assetserver
+
+import (
+	"path"
+	"strings"
+)
+
+// inherentEncodings maps the extensions of file types which are always
+// compressed, such as gzipped SVG images, to their Content-Encoding. Such
+// files are served with that encoding and the content type of their
+// decompressed contents (from builtinTypes), which is how browsers expect
+// them; served as-is, they would be shown as downloads or broken images.
+var inherentEncodings = map[string]string{
+	".svgz": "gzip",
+}
+
+// inherentEncoding returns the Content-Encoding of the named file if its
+// type is always compressed, or "" otherwise.
+func inherentEncoding(name string) string {
+	return inherentEncodings[strings.ToLower(path.Ext(name))]
+}
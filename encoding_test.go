@@ -0,0 +1,57 @@
+package assetserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInherentEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("<svg></svg>"))
+	zw.Close()
+	svgz := buf.Bytes()
+	fsys := fstest.MapFS{
+		"logo.svgz":    &fstest.MapFile{Data: svgz},
+		"LOGO.SVGZ":    &fstest.MapFile{Data: svgz},
+		"logo.svgz.br": &fstest.MapFile{Data: []byte("brotli")},
+		"logo.svg":     &fstest.MapFile{Data: []byte("<svg></svg>")},
+	}
+	for _, opts := range [][]Option{
+		nil,
+		{WithPrecompressed()},
+		{WithStreamingHash()},
+	} {
+		s := New(fsys, opts...)
+		for _, tt := range []struct {
+			name     string
+			encoding string
+		}{
+			{"logo.svgz", "gzip"},
+			{"LOGO.SVGZ", "gzip"},
+			{"logo.svg", ""},
+		} {
+			r := httptest.NewRequest("GET", "/"+tt.name, nil)
+			r.Header.Set("Accept-Encoding", "br, gzip")
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != 200 {
+				t.Errorf("%d options: GET %s: got status %d", len(opts), tt.name, w.Code)
+				continue
+			}
+			if got, want := w.Header().Get("Content-Type"), "image/svg+xml"; got != want {
+				t.Errorf("%d options: GET %s: got Content-Type %q; want %q", len(opts), tt.name, got, want)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("%d options: GET %s: got Content-Encoding %q; want %q", len(opts), tt.name, got, tt.encoding)
+			}
+			if tt.encoding != "" && !bytes.Equal(w.Body.Bytes(), svgz) {
+				t.Errorf("%d options: GET %s: body isn't the file's contents", len(opts), tt.name)
+			}
+		}
+		s.Close()
+	}
+}
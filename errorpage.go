@@ -0,0 +1,74 @@
+package assetserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// maxErrorPageFiles is the maximum number of nearby files listed on a detailed
+// error page.
+const maxErrorPageFiles = 50
+
+// errTagMismatch is reported on a detailed error page when a request's tag
+// does not match the file.
+var errTagMismatch = errors.New("the tag does not match the file's current tag")
+
+// serveErrorPage responds with an error (such as 404 or 500) for the named
+// file. A production Server sends the generic error body. A no-cache Server,
+// which is meant for development, sends a page with details to help debug
+// the problem: the request path, the file name that was tried, the files in
+// the same directory, and the underlying error (if any).
+func (s *Server) serveErrorPage(w http.ResponseWriter, r *http.Request, code int, name, tag string, err error) {
+	if !s.noCache {
+		if code == http.StatusNotFound {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, fmt.Sprintf("%d %s", code, http.StatusText(code)), code)
+		}
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %s\n\n", code, http.StatusText(code))
+	fmt.Fprintf(&b, "Path:  %s\n", path.Clean("/"+r.URL.Path))
+	fmt.Fprintf(&b, "Name:  %s\n", name)
+	if tag != "" {
+		fmt.Fprintf(&b, "Tag:   %s\n", tag)
+		if info := s.cachedInfo(name); info != nil && info.tag != tag {
+			fmt.Fprintf(&b, "       (the file's current tag is %s)\n", info.tag)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "Error: %s\n", err)
+	}
+	dir := path.Dir(name)
+	if entries, err := fs.ReadDir(s.fsys, dir); err == nil {
+		if dir == "." {
+			fmt.Fprintf(&b, "\nFiles in the root directory:\n")
+		} else {
+			fmt.Fprintf(&b, "\nFiles in %s/:\n", dir)
+		}
+		for i, e := range entries {
+			if i == maxErrorPageFiles {
+				fmt.Fprintf(&b, "  (%d more)\n", len(entries)-i)
+				break
+			}
+			suffix := ""
+			if e.IsDir() {
+				suffix = "/"
+			}
+			fmt.Fprintf(&b, "  %s%s\n", e.Name(), suffix)
+		}
+	} else if dir != "." {
+		fmt.Fprintf(&b, "\nDirectory %s/ cannot be read: %s\n", dir, err)
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	fmt.Fprint(w, b.String())
+}
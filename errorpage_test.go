@@ -0,0 +1,98 @@
+package assetserver
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetailedErrorPages(t *testing.T) {
+	fsys := errorFS{
+		FS: fstest.MapFS{
+			"css/app.css":   &fstest.MapFile{Data: []byte("app")},
+			"css/print.css": &fstest.MapFile{Data: []byte("print")},
+			"css/img/x.png": &fstest.MapFile{Data: []byte("x")},
+			"secret.txt":    &fstest.MapFile{Data: []byte("s")},
+		},
+		errs: map[string]error{"secret.txt": errors.New("permission denied")},
+	}
+	appTag := hashTag("app")
+	for _, tt := range []struct {
+		path string
+		code int
+		want []string
+	}{
+		{
+			"/css/../css/ap.css", 404,
+			[]string{
+				"404 Not Found\n",
+				"Path:  /css/ap.css\n",
+				"Name:  css/ap.css\n",
+				"Error: open css/ap.css: file does not exist\n",
+				"Files in css/:\n  app.css\n  img/\n  print.css\n",
+			},
+		},
+		{
+			"/css/app.0123456789.css", 404,
+			[]string{
+				"Name:  css/app.css\n",
+				"Tag:   0123456789\n       (the file's current tag is " + appTag + ")\n",
+				"Error: " + errTagMismatch.Error() + "\n",
+			},
+		},
+		{
+			"/secret.txt", 500,
+			[]string{
+				"500 Internal Server Error\n",
+				"Error: open secret.txt: permission denied\n",
+				"Files in the root directory:\n  css/\n  secret.txt\n",
+			},
+		},
+		{
+			"/nope/x.js", 404,
+			[]string{"Directory nope/ cannot be read"},
+		},
+	} {
+		// Load the tag for css/app.css.
+		s := NewNoCache(fsys)
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/css/app.css", nil))
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.code)
+		}
+		if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+			t.Errorf("GET %s: got Content-Type %q", tt.path, got)
+		}
+		body := w.Body.String()
+		for _, want := range tt.want {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET %s: body does not contain %q; got:\n%s", tt.path, want, body)
+			}
+		}
+	}
+}
+
+func TestGenericErrorPages(t *testing.T) {
+	fsys := errorFS{
+		FS:   fstest.MapFS{"secret.txt": &fstest.MapFile{Data: []byte("s")}},
+		errs: map[string]error{"secret.txt": errors.New("permission denied")},
+	}
+	s := New(fsys)
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{"/missing.txt", "404 page not found\n"},
+		{"/secret.txt", "500 Internal Server Error\n"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s: got body %q; want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package assetserver
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithExpvar makes the Server publish counters using the expvar package,
+// for applications that don't use a metrics system such as Prometheus (see
+// the assetservermetrics package for that). The variables are named with the
+// given prefix followed by:
+//
+//   - requests: HTTP requests served
+//   - errors: HTTP requests which resulted in a 5xx response
+//   - cache_hits: lookups of file information found in the cache
+//   - cache_misses: lookups of file information which required hashing the
+//     file
+//   - hashes: files hashed successfully
+//   - hash_errors: failed attempts to hash a file
+//
+// If multiple Servers use the same prefix, they share the variables.
+func WithExpvar(prefix string) Option {
+	requests := expvarInt(prefix + "requests")
+	errors := expvarInt(prefix + "errors")
+	hits := expvarInt(prefix + "cache_hits")
+	misses := expvarInt(prefix + "cache_misses")
+	hashes := expvarInt(prefix + "hashes")
+	hashErrors := expvarInt(prefix + "hash_errors")
+	return WithMetricsHooks(MetricsHooks{
+		Request: func(status int, _ int64) {
+			requests.Add(1)
+			if status >= 500 {
+				errors.Add(1)
+			}
+		},
+		CacheLookup: func(hit bool) {
+			if hit {
+				hits.Add(1)
+			} else {
+				misses.Add(1)
+			}
+		},
+		HashDone: func(_ time.Duration, err error) {
+			if err != nil {
+				hashErrors.Add(1)
+			} else {
+				hashes.Add(1)
+			}
+		},
+	})
+}
+
+// expvarInt returns the published *expvar.Int with the given name, creating
+// it if necessary.
+func expvarInt(name string) *expvar.Int {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	switch v := expvar.Get(name).(type) {
+	case nil:
+		return expvar.NewInt(name)
+	case *expvar.Int:
+		return v
+	default:
+		panic(fmt.Sprintf("assetserver: expvar %q is already published with type %T", name, v))
+	}
+}
+
+// expvarMu serializes creating expvars so that concurrent calls to
+// WithExpvar don't race to publish the same name.
+var expvarMu sync.Mutex
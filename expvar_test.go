@@ -0,0 +1,86 @@
+package assetserver
+
+import (
+	"errors"
+	"expvar"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExpvar(t *testing.T) {
+	fsys := errorFS{
+		FS: readErrorFS{
+			FS: hashErrorFS{
+				FS: fstest.MapFS{
+					"a.txt": &fstest.MapFile{Data: []byte("a")},
+					"b.txt": &fstest.MapFile{Data: []byte("b")},
+					"c.txt": &fstest.MapFile{Data: []byte("c")},
+				},
+				errs: map[string]error{"c.txt": errors.New("bad sector")},
+			},
+			errs: map[string]error{"b.txt": errors.New("bad sector")},
+		},
+	}
+	s := New(fsys, WithExpvar("test_expvar_"))
+	for _, target := range []string{"/a.txt", "/a.txt", "/b.txt", "/c.txt", "/d.txt"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	// A second Server with the same prefix shares the variables.
+	s2 := New(fsys, WithExpvar("test_expvar_"))
+	s2.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a.txt", nil))
+
+	for name, want := range map[string]int64{
+		"requests":     6,
+		"errors":       2,
+		"cache_hits":   1,
+		"cache_misses": 3,
+		"hashes":       2,
+		"hash_errors":  1,
+	} {
+		v, ok := expvar.Get("test_expvar_" + name).(*expvar.Int)
+		if !ok {
+			t.Errorf("expvar %s not published", name)
+			continue
+		}
+		if got := v.Value(); got != want {
+			t.Errorf("%s = %d; want %d", name, got, want)
+		}
+	}
+}
+
+func TestExpvarConflict(t *testing.T) {
+	expvar.NewString("test_expvar_conflict_requests")
+	defer func() {
+		if recover() == nil {
+			t.Error("WithExpvar did not panic with a conflicting variable")
+		}
+	}()
+	WithExpvar("test_expvar_conflict_")
+}
+
+// hashErrorFS wraps an FS of seekable files so that reading certain files
+// fails while they are being hashed.
+type hashErrorFS struct {
+	fs.FS
+	errs map[string]error
+}
+
+func (fsys hashErrorFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := fsys.errs[name]; ok {
+		return hashErrorFile{f.(seekerFile), err}, nil
+	}
+	return f, nil
+}
+
+type hashErrorFile struct {
+	seekerFile
+	err error
+}
+
+func (f hashErrorFile) Read([]byte) (int, error) { return 0, f.err }
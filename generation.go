@@ -0,0 +1,155 @@
+package assetserver
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultGenerationGrace is how long old generations are kept by default.
+const defaultGenerationGrace = 5 * time.Minute
+
+// WithGenerationGrace sets how long the Server keeps serving an old
+// generation of its assets after [Server.BeginGeneration] replaces it. The
+// default is 5 minutes. WithGenerationGrace panics if d is negative.
+func WithGenerationGrace(d time.Duration) Option {
+	return func(s *Server) {
+		if d < 0 {
+			panic("assetserver: WithGenerationGrace called with negative duration")
+		}
+		s.generationGrace = d
+	}
+}
+
+type generations struct {
+	current atomic.Pointer[Server] // nil until BeginGeneration is called
+
+	mu     sync.Mutex
+	old    []*Server // retired generations, newest first
+	closed bool
+}
+
+// BeginGeneration atomically replaces the assets that s serves with a new
+// snapshot: the files of fsys, served with the same options as s. This
+// allows a long-running Server to switch between consistent deployments of
+// its assets, such as directories containing successive releases, without
+// observing a release that is only partly written.
+//
+// Requests that are in flight when BeginGeneration is called finish against
+// the old snapshot. Afterwards, requests are served from fsys, except that a
+// request for a tagged name whose tag doesn't match the new file but does
+// match the file in an older generation is served from that generation.
+// This way, pages rendered just before a (rolling) deploy can still load
+// their assets. Old generations are kept for a grace period (see
+// [WithGenerationGrace]) and then discarded.
+//
+// BeginGeneration returns the Server for the new generation, which can be
+// used with methods such as Preload; [Server.Current] returns it as well. Tag
+// and ReadFile on s use the current generation, but other methods use the
+// file system given to New or NewNoCache, so call them on the result of
+// Current instead. The tenants of a Server created with [WithFSSelector]
+// are unaffected by BeginGeneration.
+func (s *Server) BeginGeneration(fsys fs.FS) *Server {
+	g := s.newChild(fsys)
+	gens := &s.generations
+	gens.mu.Lock()
+	defer gens.mu.Unlock()
+	if gens.closed {
+		g.Close()
+		return g
+	}
+	prev := s.Current()
+	gens.current.Store(g)
+	gens.old = append([]*Server{prev}, gens.old...)
+	time.AfterFunc(s.generationGrace, func() { s.retire(prev) })
+	return g
+}
+
+// Current returns the Server for the current generation of s's assets: the
+// result of the latest call to [Server.BeginGeneration], or s itself if
+// there hasn't been one.
+func (s *Server) Current() *Server {
+	if g := s.generations.current.Load(); g != nil {
+		return g
+	}
+	return s
+}
+
+// generationFor returns the generation that should serve r: the current
+// one, unless r is for a tagged name that only matches an old generation.
+func (s *Server) generationFor(r *http.Request) *Server {
+	cur := s.Current()
+	if cur == s {
+		return s
+	}
+	tag, p := removeTag(path.Clean("/" + r.URL.Path))
+	if tag == "" {
+		return cur
+	}
+	name := p[1:]
+	gens := &s.generations
+	gens.mu.Lock()
+	candidates := append([]*Server{cur}, gens.old...)
+	gens.mu.Unlock()
+	for _, g := range candidates {
+		if info, err := g.currentInfo(r.Context(), name); err == nil && info.tag == tag {
+			return g
+		}
+	}
+	return cur
+}
+
+// retire discards the old generation g once its grace period is over.
+func (s *Server) retire(g *Server) {
+	gens := &s.generations
+	gens.mu.Lock()
+	found := false
+	for i, o := range gens.old {
+		if o == g {
+			gens.old = append(gens.old[:i:i], gens.old[i+1:]...)
+			found = true
+			break
+		}
+	}
+	gens.mu.Unlock()
+	if !found {
+		return
+	}
+	if g == s {
+		// s stays usable (and keeps its options and background
+		// goroutines), but its cached info is no longer needed.
+		for _, name := range s.cache.names() {
+			s.cache.evict(name)
+		}
+		return
+	}
+	g.Close()
+}
+
+// otherGenerations returns the current and old generations other than s.
+func (s *Server) otherGenerations() []*Server {
+	gens := &s.generations
+	gens.mu.Lock()
+	defer gens.mu.Unlock()
+	var others []*Server
+	for _, g := range append([]*Server{gens.current.Load()}, gens.old...) {
+		if g != nil && g != s {
+			others = append(others, g)
+		}
+	}
+	return others
+}
+
+// closeGenerations closes the generations created by BeginGeneration.
+func (s *Server) closeGenerations() {
+	gens := &s.generations
+	gens.mu.Lock()
+	gens.closed = true
+	gens.mu.Unlock()
+	for _, g := range s.otherGenerations() {
+		g.Close()
+	}
+}
@@ -0,0 +1,99 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestBeginGeneration(t *testing.T) {
+	gen1 := fstest.MapFS{
+		"style.css": &fstest.MapFile{Data: []byte("v1")},
+		"old.js":    &fstest.MapFile{Data: []byte("old")},
+	}
+	gen2 := fstest.MapFS{
+		"style.css": &fstest.MapFile{Data: []byte("v2")},
+	}
+	gen3 := fstest.MapFS{
+		"style.css": &fstest.MapFile{Data: []byte("v3")},
+	}
+	s := New(gen1, WithGenerationGrace(time.Hour))
+	defer s.Close()
+	if s.Current() != s {
+		t.Fatal("Current didn't return the Server before BeginGeneration")
+	}
+	tagged1, err := s.Tag("style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2 := s.BeginGeneration(gen2)
+	if s.Current() != g2 {
+		t.Fatal("Current didn't return the new generation")
+	}
+	tagged2, err := s.Tag("style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tagged2 == tagged1 {
+		t.Fatalf("Tag returned %s for both generations", tagged1)
+	}
+	s.BeginGeneration(gen3)
+	tagged3, err := s.Tag("style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/style.css", 200, "v3"},
+		{"/" + tagged3, 200, "v3"},
+		{"/" + tagged2, 200, "v2"},
+		{"/" + tagged1, 200, "v1"},
+		{"/style.1234567890.css", 404, ""},
+		{"/old.js", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.status)
+			continue
+		}
+		if tt.status == 200 && w.Body.String() != tt.body {
+			t.Errorf("GET %s: got body %q; want %q", tt.path, w.Body, tt.body)
+		}
+	}
+
+	// Once retired, old generations are no longer consulted.
+	s.retire(g2)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tagged2, nil))
+	if w.Code != 404 {
+		t.Errorf("GET %s after retiring its generation: got status %d; want 404", tagged2, w.Code)
+	}
+}
+
+func TestGenerationGrace(t *testing.T) {
+	s := New(fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("1")}}, WithGenerationGrace(0))
+	defer s.Close()
+	tagged, err := s.Tag("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.BeginGeneration(fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("2")}})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tagged, nil))
+		if w.Code == 404 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET %s: old generation still served after its grace period", tagged)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
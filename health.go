@@ -0,0 +1,82 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// WithHealthCheckFile names a file which [Server.Healthy] reads to verify
+// that the file system is working. The file should always exist; a small file
+// created for the purpose (a sentinel) is ideal.
+func WithHealthCheckFile(name string) Option {
+	return func(s *Server) {
+		s.healthFile = name
+	}
+}
+
+// healthSample is the maximum number of cached files that Healthy checks.
+const healthSample = 10
+
+// Healthy reports whether the Server's file system appears to be readable,
+// returning an error describing the problem if not. It is intended to be
+// used by readiness or liveness probes, so that problems such as a bad
+// volume mount are detected before they cause failed requests.
+//
+// Healthy checks that the root directory of the file system can be read,
+// that the file named by [WithHealthCheckFile] (if any) can be read, and that
+// a sample of the files whose information is cached can be stat'ed. Files
+// which have been deleted are not considered a problem.
+//
+// If ctx is done before the checks complete (for instance, because the file
+// system is hung), Healthy returns an error wrapping the context's error.
+// File system operations can't be interrupted, so the checks continue in the
+// background; until they complete, further calls to Healthy wait for them
+// rather than starting checks of their own, so a file system which stays hung
+// doesn't accumulate a blocked goroutine for every probe.
+func (s *Server) Healthy(ctx context.Context) error {
+	done := s.healthChecks.DoChan("", func() (any, error) {
+		return nil, s.checkHealth()
+	})
+	select {
+	case res := <-done:
+		return res.Err
+	case <-ctx.Done():
+		return fmt.Errorf("assetserver: health check did not complete: %w", ctx.Err())
+	}
+}
+
+func (s *Server) checkHealth() error {
+	if _, err := fs.ReadDir(s.fsys, "."); err != nil {
+		return fmt.Errorf("assetserver: cannot read root directory: %w", err)
+	}
+	if s.healthFile != "" {
+		if err := s.checkHealthFile(); err != nil {
+			return fmt.Errorf("assetserver: cannot read health check file: %w", err)
+		}
+	}
+	var sample []string
+	s.cache.each(func(name string, _ *cacheEntry) {
+		if len(sample) < healthSample {
+			sample = append(sample, name)
+		}
+	})
+	for _, name := range sample {
+		if _, err := fs.Stat(s.fsys, name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("assetserver: cannot stat %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) checkHealthFile() error {
+	f, err := s.fsys.Open(s.healthFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(io.Discard, io.LimitReader(f, 512))
+	return err
+}
@@ -0,0 +1,107 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHealthy(t *testing.T) {
+	errDisk := errors.New("disk on fire")
+	files := fstest.MapFS{
+		"a.txt":   &fstest.MapFile{Data: []byte("a")},
+		"b.txt":   &fstest.MapFile{Data: []byte("b")},
+		"healthz": &fstest.MapFile{Data: []byte("ok")},
+	}
+	for _, tt := range []struct {
+		name    string
+		errs    map[string]error
+		opts    []Option
+		wantErr string // empty means healthy
+	}{
+		{"healthy", nil, []Option{WithHealthCheckFile("healthz")}, ""},
+		{"root unreadable", map[string]error{".": errDisk}, nil, "cannot read root directory"},
+		{"health file missing", nil, []Option{WithHealthCheckFile("missing")}, "cannot read health check file"},
+		{"health file unreadable", map[string]error{"healthz": errDisk}, []Option{WithHealthCheckFile("healthz")}, "cannot read health check file"},
+		{"cached file unreadable", map[string]error{"b.txt": errDisk}, nil, "cannot stat b.txt"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &errorFS{FS: files, errs: map[string]error{}}
+			s := New(fsys, tt.opts...)
+			for _, name := range []string{"a.txt", "b.txt"} {
+				if _, err := s.Tag(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, err := range tt.errs {
+				fsys.errs[name] = err
+			}
+			err := s.Healthy(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Healthy: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Healthy returned %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealthyDeletedFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+	}
+	s := New(fsys)
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	delete(fsys, "a.txt")
+	if err := s.Healthy(context.Background()); err != nil {
+		t.Fatalf("Healthy: %s", err)
+	}
+}
+
+func TestHealthyHung(t *testing.T) {
+	fsys := &hungFS{release: make(chan struct{})}
+	defer close(fsys.release)
+	s := New(fsys)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.Healthy(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Healthy returned %v; want deadline exceeded", err)
+	}
+
+	// Later probes wait for the hung check rather than starting more.
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		err := s.Healthy(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Healthy returned %v; want deadline exceeded", err)
+		}
+	}
+	if n := fsys.opens.Load(); n > 1 {
+		t.Errorf("got %d blocked calls to Open; want at most 1", n)
+	}
+}
+
+// hungFS is a file system whose operations block until release is closed.
+type hungFS struct {
+	release chan struct{}
+	opens   atomic.Int64
+}
+
+func (fsys *hungFS) Open(name string) (fs.File, error) {
+	fsys.opens.Add(1)
+	<-fsys.release
+	return nil, fs.ErrNotExist
+}
@@ -0,0 +1,255 @@
+package assetserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WithVersionHistory makes the Server keep the contents of up to n previous
+// versions of each file, so that a request for a tagged name whose tag
+// belongs to an old version of the file (or to a file which has since been
+// deleted) is served that version, with the same long-lived Cache-Control
+// header as the current version, rather than 404 Not Found. This prevents
+// breakage during deploys, when pages rendered before the files changed
+// (or cached by browsers and CDNs) still refer to the old tagged names.
+//
+// The Server copies each version of a file when it first computes its tag,
+// since by the time it notices that the file has changed, the old contents
+// are gone. If dir is empty, the copies are kept in memory; otherwise, they
+// are written to files in a new directory created in dir, which must exist,
+// and the files are removed once they are discarded or the Server is closed.
+// (Tenants and generations of the Server each get a directory of their
+// own.) Either way, the copies take up as much space as the files themselves
+// (for each version, including the current one), so WithVersionHistory is
+// best suited to modest assets.
+//
+// The history only covers tags computed by the Server, so it has no effect
+// on no-cache Servers or files whose names include hashes (see
+// [WithPreHashed]). WithVersionHistory panics if n <= 0.
+func WithVersionHistory(n int, dir string) Option {
+	if n <= 0 {
+		panic("assetserver: WithVersionHistory called with n <= 0")
+	}
+	return func(s *Server) {
+		s.history = &versionHistory{
+			n:        n,
+			dir:      dir,
+			versions: make(map[string][]*fileVersion),
+		}
+	}
+}
+
+type versionHistory struct {
+	n   int    // the number of previous versions to keep
+	dir string // if empty, keep versions in memory
+
+	mu       sync.Mutex
+	versions map[string][]*fileVersion // by name, newest first
+	// subdir is the directory in dir holding this history's files,
+	// created on first use. Servers which share options (such as
+	// generations) write the same versions, so they need separate
+	// directories to avoid removing each other's files.
+	subdir string
+}
+
+type fileVersion struct {
+	tag         string
+	contentType string
+	mtime       int64 // as unix nano
+	size        int64
+
+	body []byte // nil if the contents are in path
+	path string
+}
+
+// recordVersion adds the version of the named file described by info to the
+// history, unless it's already the newest version there.
+func (s *Server) recordVersion(ctx context.Context, name string, info *fileInfo) {
+	vh := s.history
+	if vh == nil || s.noCache || s.isPreHashed(name) {
+		return
+	}
+	vh.mu.Lock()
+	vs := vh.versions[name]
+	recorded := len(vs) > 0 && vs[0].tag == info.tag
+	vh.mu.Unlock()
+	if recorded {
+		return
+	}
+	b := info.body
+	if b == nil {
+		var err error
+		b, err = fs.ReadFile(s.fsys, name)
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "cannot record file version", "name", name, "tag", info.tag, "error", err)
+			return
+		}
+		if !s.versionMatches(name, b, info) {
+			// The file changed after it was hashed. The new
+			// version will be recorded once its tag is computed.
+			return
+		}
+	}
+	v := &fileVersion{
+		tag:         info.tag,
+		contentType: info.contentType,
+		mtime:       info.mtime,
+		size:        int64(len(b)),
+	}
+	if vh.dir == "" {
+		v.body = bytes.Clone(b)
+	} else {
+		subdir, err := vh.versionDir()
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "cannot record file version", "name", name, "tag", info.tag, "error", err)
+			return
+		}
+		sum := sha256.Sum256([]byte(name))
+		v.path = filepath.Join(subdir, info.tag+"-"+makeTag(sum[:]))
+		if err := os.WriteFile(v.path, b, 0o644); err != nil {
+			s.log(ctx, slog.LevelWarn, "cannot record file version", "name", name, "tag", info.tag, "error", err)
+			return
+		}
+	}
+
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	vs = vh.versions[name]
+	for i, old := range vs {
+		if old.tag == v.tag {
+			// The file was changed back to an earlier version (or
+			// another goroutine recorded this one first).
+			vs = append(vs[:i:i], vs[i+1:]...)
+			if old.path != "" && old.path != v.path {
+				os.Remove(old.path)
+			}
+			break
+		}
+	}
+	vs = append([]*fileVersion{v}, vs...)
+	if len(vs) > vh.n+1 {
+		for _, old := range vs[vh.n+1:] {
+			if old.path != "" {
+				os.Remove(old.path)
+			}
+		}
+		vs = vs[:vh.n+1]
+	}
+	vh.versions[name] = vs
+}
+
+// versionMatches reports whether b, the contents of the named file, are the
+// contents described by info.
+func (s *Server) versionMatches(name string, b []byte, info *fileInfo) bool {
+	if int64(len(b)) != info.size {
+		return false
+	}
+	if s.partialHash != nil && s.partialHash.applies(name, info.size) {
+		// The tag doesn't cover the whole file; trust the size.
+		return true
+	}
+	sum := sha256.Sum256(b)
+	return s.typedTag(makeTag(sum[:]), info.contentType) == info.tag
+}
+
+// lookup returns the version of the named file with the given tag, or nil.
+func (vh *versionHistory) lookup(name, tag string) *fileVersion {
+	if vh == nil {
+		return nil
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	for _, v := range vh.versions[name] {
+		if v.tag == tag {
+			return v
+		}
+	}
+	return nil
+}
+
+// versionDir returns the directory holding the history's files, creating it
+// if necessary.
+func (vh *versionHistory) versionDir() (string, error) {
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	if vh.subdir == "" {
+		d, err := os.MkdirTemp(vh.dir, "versions-")
+		if err != nil {
+			return "", err
+		}
+		vh.subdir = d
+	}
+	return vh.subdir, nil
+}
+
+// close removes the versions that were written to files.
+func (vh *versionHistory) close() {
+	if vh == nil || vh.dir == "" {
+		return
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	for name := range vh.versions {
+		delete(vh.versions, name)
+	}
+	if vh.subdir != "" {
+		os.RemoveAll(vh.subdir)
+		vh.subdir = ""
+	}
+}
+
+// serveVersion serves the version of the named file with the given tag from
+// the history, if there is one, and reports whether it did.
+func (s *Server) serveVersion(w http.ResponseWriter, r *http.Request, name, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	v := s.history.lookup(name, tag)
+	if v == nil {
+		return false
+	}
+	var content io.ReadSeeker
+	if v.body != nil {
+		content = bytes.NewReader(v.body)
+	} else {
+		f, err := os.Open(v.path)
+		if err != nil {
+			// The version was discarded after the lookup.
+			return false
+		}
+		defer f.Close()
+		content = f
+	}
+	s.log(r.Context(), slog.LevelInfo, "serving previous file version", "name", name, "tag", tag)
+	setAssetInfo(r.Context(), &AssetInfo{
+		Name:        name,
+		Tag:         tag,
+		Size:        v.size,
+		ContentType: v.contentType,
+	})
+	h := w.Header()
+	h.Set("Cache-Control", s.cacheControl(name, tag))
+	h.Set("ETag", `"`+tag+`"`)
+	s.addCanonicalLink(r.Context(), h, name, tag, tag)
+	if _, ok := h["Content-Type"]; !ok {
+		if v.contentType != "" {
+			h.Set("Content-Type", v.contentType)
+		} else {
+			h["Content-Type"] = nil
+		}
+	}
+	if encoding := inherentEncoding(name); encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	http.ServeContent(w, r, name, time.Unix(0, v.mtime), content)
+	return true
+}
@@ -0,0 +1,118 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestVersionHistory(t *testing.T) {
+	for _, spill := range []bool{false, true} {
+		name := "memory"
+		if spill {
+			name = "dir"
+		}
+		t.Run(name, func(t *testing.T) {
+			var dir string
+			if spill {
+				dir = t.TempDir()
+			}
+			fsys := fstest.MapFS{}
+			s := New(fsys, WithVersionHistory(2, dir))
+			var tags []string
+			for i, body := range []string{"v0", "v1", "v2", "v3"} {
+				fsys["style.css"] = &fstest.MapFile{Data: []byte(body), ModTime: time.Unix(int64(i), 0)}
+				tagged, err := s.Tag("style.css")
+				if err != nil {
+					t.Fatal(err)
+				}
+				tags = append(tags, tagged)
+			}
+			fsys["gone.txt"] = &fstest.MapFile{Data: []byte("gone")}
+			gone, err := s.Tag("gone.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			delete(fsys, "gone.txt")
+
+			for _, tt := range []struct {
+				path   string
+				status int
+				body   string
+			}{
+				{"/" + tags[3], 200, "v3"},
+				{"/" + tags[2], 200, "v2"},
+				{"/" + tags[1], 200, "v1"},
+				{"/" + tags[0], 404, ""}, // more than 2 versions ago
+				{"/style.css", 200, "v3"},
+				{"/" + gone, 200, "gone"},
+				{"/gone.txt", 404, ""},
+			} {
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+				if w.Code != tt.status {
+					t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.status)
+					continue
+				}
+				if tt.status != 200 {
+					continue
+				}
+				if w.Body.String() != tt.body {
+					t.Errorf("GET %s: got body %q; want %q", tt.path, w.Body, tt.body)
+				}
+				if tt.path == "/style.css" {
+					continue
+				}
+				if got, want := w.Header().Get("Cache-Control"), "public, max-age=31536000, immutable"; got != want {
+					t.Errorf("GET %s: got Cache-Control %q; want %q", tt.path, got, want)
+				}
+			}
+
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if dir != "" {
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) > 0 {
+					t.Errorf("after Close, %s contains %d files", dir, len(entries))
+				}
+			}
+		})
+	}
+}
+
+func TestVersionHistoryGenerations(t *testing.T) {
+	dir := t.TempDir()
+	s := New(fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("1")}},
+		WithVersionHistory(2, dir), WithGenerationGrace(time.Hour))
+	defer s.Close()
+	g2 := s.BeginGeneration(fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("2")}})
+	if _, err := g2.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// The next generation records the same version as g2 before the
+	// file changes.
+	gen3 := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("2")}}
+	g3 := s.BeginGeneration(gen3)
+	old, err := g3.Tag("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen3["a.txt"] = &fstest.MapFile{Data: []byte("3"), ModTime: time.Unix(1, 0)}
+	if _, err := g3.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Retiring g2 must not remove g3's copy of the old version.
+	s.retire(g2)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+old, nil))
+	if w.Code != 200 || w.Body.String() != "2" {
+		t.Errorf("GET /%s after retiring g2: got (%d, %q); want (200, \"2\")", old, w.Code, w.Body)
+	}
+}
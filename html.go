@@ -0,0 +1,224 @@
+package assetserver
+
+import (
+	"bytes"
+	"strings"
+)
+
+// HTMLRewriteOptions configures [WithHTMLRewriting].
+type HTMLRewriteOptions struct {
+	// Patterns selects the files to rewrite. If empty, all .html files
+	// are rewritten. See the Patterns section of the package
+	// documentation for the syntax.
+	Patterns []string
+	// Prefix is the URL path at which the Server's files are served, such
+	// as "/static/". If set, root-relative references which start with
+	// the prefix are rewritten as well as relative ones.
+	Prefix string
+	// Integrity adds an integrity attribute (for subresource integrity)
+	// to each <script> and <link> element whose reference is rewritten,
+	// unless it already has one.
+	Integrity bool
+}
+
+// WithHTMLRewriting makes the Server rewrite the src, href, and srcset
+// attributes in HTML files to refer to the tagged names of the referenced
+// files. This gives plain static HTML pages the benefits of tagged names
+// without using templates and [Server.Tag]. The rewriting is a
+// transformation (see [WithTransform]).
+//
+// As with [WithCSSRewriting], references to absolute URLs, nonexistent
+// files, and files which are themselves transformed (such as other HTML
+// pages) are left unchanged, and a no-cache Server leaves all references
+// unchanged. The contents of <script> and <style> elements and comments are
+// not modified.
+func WithHTMLRewriting(opts HTMLRewriteOptions) Option {
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.html"}
+	}
+	prefix := opts.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return WithTransform(htmlRewriter{prefix: prefix, integrity: opts.Integrity}, patterns...)
+}
+
+type htmlRewriter struct {
+	prefix    string
+	integrity bool
+}
+
+// Transform returns in unchanged: the rewriting depends on the Server.
+func (htmlRewriter) Transform(name string, in []byte) ([]byte, error) {
+	return in, nil
+}
+
+// An htmlAttr is an attribute of an HTML start tag. The value is
+// in[valStart:valEnd] and the attribute ends at in[end].
+type htmlAttr struct {
+	name             string // lowercase
+	valStart, valEnd int
+	end              int
+}
+
+func (hr htmlRewriter) transformRefs(name string, in []byte, refs *refResolver) ([]byte, error) {
+	var out []byte
+	copied := 0 // in[:copied] has been written to out
+	replace := func(start, end int, s string) {
+		out = append(out, in[copied:start]...)
+		out = append(out, s...)
+		copied = end
+	}
+	for i := 0; i < len(in); {
+		if bytes.HasPrefix(in[i:], []byte("<!--")) {
+			end := bytes.Index(in[i+4:], []byte("-->"))
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+		if in[i] != '<' || i+1 == len(in) || !isASCIILetter(in[i+1]) {
+			i++
+			continue
+		}
+		tag, attrs, end := parseHTMLTag(in, i+1)
+		i = end
+		var integrityAttr string // the attribute whose target is checked
+		switch tag {
+		case "script":
+			integrityAttr = "src"
+		case "link":
+			integrityAttr = "href"
+		}
+		hasIntegrity := false
+		for _, a := range attrs {
+			if a.name == "integrity" {
+				hasIntegrity = true
+			}
+		}
+		for _, a := range attrs {
+			val := string(in[a.valStart:a.valEnd])
+			switch a.name {
+			case "src", "href":
+				var target string
+				tagged, ok := tagRef(name, strings.TrimSpace(val), hr.prefix, func(t string) (string, bool) {
+					target = t
+					return refs.tag(t)
+				})
+				if !ok {
+					continue
+				}
+				replace(a.valStart, a.valEnd, tagged)
+				if hr.integrity && !hasIntegrity && a.name == integrityAttr {
+					if sri, ok := refs.integrity(target); ok {
+						replace(a.end, a.end, ` integrity="`+sri+`"`)
+					}
+				}
+			case "srcset":
+				if rewritten, ok := hr.rewriteSrcset(name, val, refs); ok {
+					replace(a.valStart, a.valEnd, rewritten)
+				}
+			}
+		}
+		if tag == "script" || tag == "style" {
+			// Skip the raw text contents.
+			j := indexFold(in[i:], "</"+tag)
+			if j < 0 {
+				break
+			}
+			i += j
+		}
+	}
+	if out == nil {
+		return in, nil
+	}
+	return append(out, in[copied:]...), nil
+}
+
+// rewriteSrcset rewrites the URLs in the value of a srcset attribute.
+func (hr htmlRewriter) rewriteSrcset(name, val string, refs *refResolver) (string, bool) {
+	candidates := strings.Split(val, ",")
+	changed := false
+	for i, c := range candidates {
+		trimmed := strings.TrimLeft(c, " \t\n\r\f")
+		lead := c[:len(c)-len(trimmed)]
+		ref := trimmed
+		if j := strings.IndexAny(trimmed, " \t\n\r\f"); j >= 0 {
+			ref = trimmed[:j]
+		}
+		tagged, ok := tagRef(name, ref, hr.prefix, refs.tag)
+		if !ok {
+			continue
+		}
+		candidates[i] = lead + tagged + trimmed[len(ref):]
+		changed = true
+	}
+	return strings.Join(candidates, ","), changed
+}
+
+// parseHTMLTag parses an HTML start tag beginning with the tag name at
+// in[i]. It returns the lowercase tag name, the attributes, and the index
+// just after the end of the tag.
+func parseHTMLTag(in []byte, i int) (tag string, attrs []htmlAttr, end int) {
+	start := i
+	for i < len(in) && !isCSSSpace(in[i]) && in[i] != '>' && in[i] != '/' {
+		i++
+	}
+	tag = strings.ToLower(string(in[start:i]))
+	for i < len(in) {
+		for i < len(in) && (isCSSSpace(in[i]) || in[i] == '/') {
+			i++
+		}
+		if i == len(in) {
+			break
+		}
+		if in[i] == '>' {
+			return tag, attrs, i + 1
+		}
+		nameStart := i
+		for i < len(in) && !isCSSSpace(in[i]) && in[i] != '>' && in[i] != '=' && in[i] != '/' {
+			i++
+		}
+		a := htmlAttr{name: strings.ToLower(string(in[nameStart:i]))}
+		j := i
+		for j < len(in) && isCSSSpace(in[j]) {
+			j++
+		}
+		if j == len(in) || in[j] != '=' {
+			// An attribute without a value.
+			a.valStart, a.valEnd, a.end = i, i, i
+			attrs = append(attrs, a)
+			continue
+		}
+		j++
+		for j < len(in) && isCSSSpace(in[j]) {
+			j++
+		}
+		if j < len(in) && (in[j] == '"' || in[j] == '\'') {
+			q := in[j]
+			a.valStart = j + 1
+			n := bytes.IndexByte(in[a.valStart:], q)
+			if n < 0 {
+				return tag, attrs, len(in)
+			}
+			a.valEnd = a.valStart + n
+			a.end = a.valEnd + 1
+		} else {
+			a.valStart = j
+			for j < len(in) && !isCSSSpace(in[j]) && in[j] != '>' {
+				j++
+			}
+			a.valEnd = j
+			a.end = j
+		}
+		attrs = append(attrs, a)
+		i = a.end
+	}
+	return tag, attrs, len(in)
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
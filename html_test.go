@@ -0,0 +1,81 @@
+package assetserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHTMLRewriting(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+<link rel="stylesheet" href="css/app.css">
+<link rel=icon href=img/icon.png>
+<script src='js/app.js' defer></script>
+<script>var x = "<img src=img/a.png>";</script>
+<style>.a { background: url(img/a.png) }</style>
+</head>
+<body>
+<!-- <img src="img/a.png"> -->
+<IMG SRC="img/a.png?x=1" alt="a">
+<img srcset="img/a.png 1x, img/b.png 2x,https://example.com/c.png 3x">
+<img src="/static/img/a.png">
+<img src="/other/img/a.png">
+<a href="about.html">About</a>
+<a href="https://example.com/">Elsewhere</a>
+<img src="missing.png">
+</body>
+</html>
+`
+	fsys := fstest.MapFS{
+		"index.html":   &fstest.MapFile{Data: []byte(page)},
+		"about.html":   &fstest.MapFile{Data: []byte("about")},
+		"css/app.css":  &fstest.MapFile{Data: []byte("css")},
+		"js/app.js":    &fstest.MapFile{Data: []byte("js")},
+		"img/a.png":    &fstest.MapFile{Data: []byte("a")},
+		"img/b.png":    &fstest.MapFile{Data: []byte("b")},
+		"img/icon.png": &fstest.MapFile{Data: []byte("icon")},
+	}
+	s := New(fsys, WithHTMLRewriting(HTMLRewriteOptions{Prefix: "/static"}))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/index.html", nil))
+	a, b := hashTag("a"), hashTag("b")
+	want := strings.NewReplacer(
+		`href="css/app.css"`, `href="css/app.`+hashTag("css")+`.css"`,
+		`href=img/icon.png`, `href=img/icon.`+hashTag("icon")+`.png`,
+		`src='js/app.js'`, `src='js/app.`+hashTag("js")+`.js'`,
+		`SRC="img/a.png?x=1"`, `SRC="img/a.`+a+`.png?x=1"`,
+		`srcset="img/a.png 1x, img/b.png 2x,`, `srcset="img/a.`+a+`.png 1x, img/b.`+b+`.png 2x,`,
+		`src="/static/img/a.png"`, `src="/static/img/a.`+a+`.png"`,
+	).Replace(page)
+	if got := w.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHTMLIntegrity(t *testing.T) {
+	page := `<link rel="stylesheet" href="app.css"><script src="app.js" integrity="sha256-custom"></script><script src=x.js></script>`
+	fsys := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(page)},
+		"app.css":    &fstest.MapFile{Data: []byte("css")},
+		"app.js":     &fstest.MapFile{Data: []byte("js")},
+		"x.js":       &fstest.MapFile{Data: []byte("x")},
+	}
+	s := New(fsys, WithHTMLRewriting(HTMLRewriteOptions{Integrity: true}))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/index.html", nil))
+	sri := func(s string) string {
+		b := sha256.Sum256([]byte(s))
+		return "sha256-" + base64.StdEncoding.EncodeToString(b[:])
+	}
+	want := `<link rel="stylesheet" href="app.` + hashTag("css") + `.css" integrity="` + sri("css") + `">` +
+		`<script src="app.` + hashTag("js") + `.js" integrity="sha256-custom"></script>` +
+		`<script src=x.` + hashTag("x") + `.js integrity="` + sri("x") + `"></script>`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,395 @@
+package assetserver
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// ImageResizeOptions configures [WithImageResizing].
+type ImageResizeOptions struct {
+	// Patterns selects the images which may be resized. If empty, all
+	// .jpg, .jpeg, .png, and .gif files may be resized. See the Patterns
+	// section of the package documentation for the syntax.
+	Patterns []string
+	// Widths and Heights list the values allowed for the w and h query
+	// parameters. A request for any other size is rejected. If Heights is
+	// empty, the h parameter is not allowed.
+	Widths  []int
+	Heights []int
+	// MaxSourcePixels limits the size (width times height) of the images
+	// which are decoded for resizing. If zero, it is 50 million.
+	MaxSourcePixels int
+	// CacheEntries is the number of resized images to keep in memory. If
+	// zero, it is 100.
+	CacheEntries int
+	// CacheDir, if non-empty, is a directory in which to store resized
+	// images so that they survive restarts. The directory must exist.
+	CacheDir string
+}
+
+// WithImageResizing makes the Server serve resized versions of images when
+// they are requested with w (width) and h (height) query parameters, such as
+// "/img/hero.jpg?w=640" or, with the tagged name, "/img/hero.Tag.jpg?w=640".
+//
+// If only one of w and h is given, the image is scaled to that width or
+// height, keeping its aspect ratio. If both are given, the image is scaled
+// to cover the requested size and then cropped to it, keeping the center.
+// Images are never scaled up. The result has the same format as the source.
+//
+// To keep clients from making the Server do arbitrary work, only the sizes
+// listed in opts are allowed; requests with other sizes or with other query
+// parameters receive 400 Bad Request. WithImageResizing panics if
+// opts.Widths is empty.
+//
+// Resized images are cached in memory (and, if opts.CacheDir is set, on disk)
+// keyed by the source image's tag and the requested size, so an image is
+// resized again only when it changes.
+func WithImageResizing(opts ImageResizeOptions) Option {
+	if len(opts.Widths) == 0 {
+		panic("assetserver: WithImageResizing called without any allowed widths")
+	}
+	if len(opts.Patterns) == 0 {
+		opts.Patterns = []string{"**/*.jpg", "**/*.jpeg", "**/*.png", "**/*.gif"}
+	}
+	opts.Patterns = compilePatterns(opts.Patterns)
+	if opts.MaxSourcePixels == 0 {
+		opts.MaxSourcePixels = 50e6
+	}
+	if opts.CacheEntries == 0 {
+		opts.CacheEntries = 100
+	}
+	ir := &imageResizer{
+		opts:    opts,
+		sem:     make(chan struct{}, runtime.GOMAXPROCS(0)),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+	return func(s *Server) {
+		s.imageResizer = ir
+	}
+}
+
+type imageResizer struct {
+	opts ImageResizeOptions
+	sem  chan struct{} // limits concurrent resizing
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of *resizedImage, by key
+	lru     *list.List               // most recently used first
+}
+
+type resizedImage struct {
+	key  string
+	body []byte
+}
+
+// requested reports whether r asks for a resized version of the named file.
+func (ir *imageResizer) requested(r *http.Request, name string) bool {
+	if ir == nil || r.URL.RawQuery == "" || !matchAny(ir.opts.Patterns, name) {
+		return false
+	}
+	q := r.URL.Query()
+	return q.Has("w") || q.Has("h")
+}
+
+// parseSize parses and validates the resizing query parameters. If
+// allowVersion is set, the global version parameter is allowed as well.
+func (ir *imageResizer) parseSize(r *http.Request, allowVersion bool) (width, height int, err error) {
+	for k, vs := range r.URL.Query() {
+		var allowed []int
+		switch k {
+		case versionParam:
+			if allowVersion {
+				continue
+			}
+			return 0, 0, fmt.Errorf("unsupported parameter %q", k)
+		case "w":
+			allowed = ir.opts.Widths
+		case "h":
+			allowed = ir.opts.Heights
+		default:
+			return 0, 0, fmt.Errorf("unsupported parameter %q", k)
+		}
+		if len(vs) != 1 {
+			return 0, 0, fmt.Errorf("parameter %q given more than once", k)
+		}
+		n, err := strconv.Atoi(vs[0])
+		if err != nil || !containsInt(allowed, n) {
+			return 0, 0, fmt.Errorf("unsupported %s=%s", k, vs[0])
+		}
+		if k == "w" {
+			width = n
+		} else {
+			height = n
+		}
+	}
+	return width, height, nil
+}
+
+func containsInt(s []int, n int) bool {
+	for _, m := range s {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+func (ir *imageResizer) lookup(key string) []byte {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	e, ok := ir.entries[key]
+	if !ok {
+		return nil
+	}
+	ir.lru.MoveToFront(e)
+	return e.Value.(*resizedImage).body
+}
+
+func (ir *imageResizer) store(key string, body []byte) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	if _, ok := ir.entries[key]; ok {
+		return
+	}
+	ir.entries[key] = ir.lru.PushFront(&resizedImage{key: key, body: body})
+	for ir.lru.Len() > ir.opts.CacheEntries {
+		oldest := ir.lru.Remove(ir.lru.Back()).(*resizedImage)
+		delete(ir.entries, oldest.key)
+	}
+}
+
+// serveResizedImage responds to a request for a resized version of the named
+// image. The tag is the one given in the request, if any.
+func (s *Server) serveResizedImage(w http.ResponseWriter, r *http.Request, name, tag string) {
+	ir := s.imageResizer
+	width, height, err := ir.parseSize(r, s.globalVersion != "")
+	if err != nil {
+		http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	info, err := s.currentInfo(ctx, name)
+	if err != nil {
+		s.writeFSError(w, r, name, err)
+		return
+	}
+	if tag != "" && tag != info.tag {
+		s.serveErrorPage(w, r, http.StatusNotFound, name, tag, errTagMismatch)
+		return
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%d", name, info.tag, width, height)))
+	key := hex.EncodeToString(sum[:])
+	body, err := s.resizedImage(ctx, name, key, width, height)
+	if err != nil {
+		s.writeFSError(w, r, name, err)
+		return
+	}
+	h := w.Header()
+	h.Set("Cache-Control", s.responseCacheControl(r, name, tag))
+	h.Set("ETag", `"`+makeTag(sum[:])+`"`)
+	if _, ok := h["Content-Type"]; !ok {
+		h.Set("Content-Type", info.contentType)
+	}
+	http.ServeContent(w, r, name, time.Unix(0, info.mtime), bytes.NewReader(body))
+}
+
+// resizedImage returns the resized image identified by key, from the cache
+// if possible.
+func (s *Server) resizedImage(ctx context.Context, name, key string, width, height int) ([]byte, error) {
+	ir := s.imageResizer
+	if body := ir.lookup(key); body != nil {
+		return body, nil
+	}
+	var diskName string
+	if ir.opts.CacheDir != "" {
+		diskName = filepath.Join(ir.opts.CacheDir, key+path.Ext(name))
+		if body, err := os.ReadFile(diskName); err == nil {
+			ir.store(key, body)
+			return body, nil
+		}
+	}
+
+	ir.sem <- struct{}{}
+	body, err := s.resizeImage(name, width, height)
+	<-ir.sem
+	if err != nil {
+		return nil, err
+	}
+	ir.store(key, body)
+	if diskName != "" {
+		if err := writeFileAtomic(diskName, body); err != nil {
+			s.log(ctx, slog.LevelWarn, "cannot write resized image to cache directory", "name", name, "err", err)
+		}
+	}
+	return body, nil
+}
+
+var errImageTooLarge = errors.New("image is too large to resize")
+
+// resizeImage reads the named image and returns it resized and encoded.
+func (s *Server) resizeImage(name string, width, height int) ([]byte, error) {
+	b, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("assetserver: cannot decode %s: %w", name, err)
+	}
+	if cfg.Width*cfg.Height > s.imageResizer.opts.MaxSourcePixels {
+		return nil, fmt.Errorf("assetserver: %s: %w", name, errImageTooLarge)
+	}
+	src, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("assetserver: cannot decode %s: %w", name, err)
+	}
+	dst := resize(src, width, height)
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	case "png":
+		err = png.Encode(&buf, dst)
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("assetserver: cannot encode %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resize scales src to the given width or height (if the other is zero) or
+// scales and crops it to cover both. It never scales an image up.
+func resize(src image.Image, width, height int) image.Image {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	if sw == 0 || sh == 0 {
+		return src
+	}
+	crop := sb
+	switch {
+	case height == 0:
+		height = sh * width / sw
+	case width == 0:
+		width = sw * height / sh
+	default:
+		// Crop the source to the target aspect ratio.
+		if sw*height > sh*width {
+			cw := sh * width / height
+			crop.Min.X += (sw - cw) / 2
+			crop.Max.X = crop.Min.X + cw
+		} else {
+			ch := sw * height / width
+			crop.Min.Y += (sh - ch) / 2
+			crop.Max.Y = crop.Min.Y + ch
+		}
+	}
+	if width > crop.Dx() || height > crop.Dy() {
+		width, height = crop.Dx(), crop.Dy()
+	}
+	width, height = max(width, 1), max(height, 1)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst
+}
+
+// writeFileAtomic writes b to the named file by way of a temporary file.
+func writeFileAtomic(name string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		removeTemp(tmp)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Srcset returns the value of a srcset attribute for an <img> element which
+// lets browsers choose among resized versions (see [WithImageResizing]) of
+// the named image: one candidate for each allowed width that is no larger
+// than the image, plus the original image at its own width.
+// The candidates' URLs are formed by joining prefix (the URL path at which
+// the Server's files are served, such as "/static/") with the image's tagged
+// name, so a responsive image takes a single template function call:
+//
+//	<img src="/static/{{tag "img/hero.jpg"}}" srcset="{{srcset "/static/" "img/hero.jpg"}}" sizes="100vw">
+//
+// Srcset returns an error if the Server wasn't created with
+// WithImageResizing or the named file is not an image which may be resized.
+func (s *Server) Srcset(prefix, name string) (string, error) {
+	ir := s.imageResizer
+	if ir == nil {
+		return "", errors.New("assetserver: Srcset called on a Server without WithImageResizing")
+	}
+	name = strings.TrimPrefix(name, "/")
+	if !matchAny(ir.opts.Patterns, name) {
+		return "", fmt.Errorf("assetserver: Srcset called for %s, which is not resized", name)
+	}
+	tagged, err := s.Tag(name)
+	if err != nil {
+		return "", err
+	}
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", fmt.Errorf("assetserver: cannot decode %s: %w", name, err)
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	tagged = escapePath(tagged)
+	widths := append([]int(nil), ir.opts.Widths...)
+	sort.Ints(widths)
+	var candidates []string
+	for _, w := range widths {
+		if w > cfg.Width {
+			break
+		}
+		candidates = append(candidates, fmt.Sprintf("%s%s?w=%d %dw", prefix, tagged, w, w))
+	}
+	if !containsInt(widths, cfg.Width) {
+		candidates = append(candidates, fmt.Sprintf("%s%s %dw", prefix, tagged, cfg.Width))
+	}
+	return strings.Join(candidates, ", "), nil
+}
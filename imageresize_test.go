@@ -0,0 +1,162 @@
+package assetserver
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageResizing(t *testing.T) {
+	src := testPNG(t, 200, 100)
+	fsys := fstest.MapFS{
+		"img/hero.png": &fstest.MapFile{Data: src},
+		"notes.txt":    &fstest.MapFile{Data: []byte("notes")},
+	}
+	dir := t.TempDir()
+	s := New(fsys, WithImageResizing(ImageResizeOptions{
+		Widths:   []int{50, 400},
+		Heights:  []int{50},
+		CacheDir: dir,
+	}))
+	for _, tt := range []struct {
+		path       string
+		code       int
+		wantWidth  int
+		wantHeight int
+	}{
+		{"/img/hero.png?w=50", 200, 50, 25},
+		{"/img/hero.png?h=50", 200, 100, 50},
+		{"/img/hero.png?w=50&h=50", 200, 50, 50},
+		{"/img/hero.png?w=400", 200, 200, 100},
+		{"/img/hero." + hashTag(string(src)) + ".png?w=50", 200, 50, 25},
+		{"/img/hero.png", 200, 200, 100},
+		{"/img/hero.png?w=51", 400, 0, 0},
+		{"/img/hero.png?h=100", 400, 0, 0},
+		{"/img/hero.png?w=50&q=90", 400, 0, 0},
+		{"/img/hero.png?w=50&w=400", 400, 0, 0},
+		{"/img/hero." + hashTag("old") + ".png?w=50", 404, 0, 0},
+		{"/img/missing.png?w=50", 404, 0, 0},
+		{"/notes.txt?w=50", 200, 0, 0},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		if tt.code != 200 || tt.wantWidth == 0 {
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); got != "image/png" {
+			t.Errorf("GET %s: got Content-Type %q", tt.path, got)
+		}
+		cfg, err := png.DecodeConfig(w.Body)
+		if err != nil {
+			t.Errorf("GET %s: cannot decode response: %s", tt.path, err)
+			continue
+		}
+		if cfg.Width != tt.wantWidth || cfg.Height != tt.wantHeight {
+			t.Errorf("GET %s: got %dx%d image; want %dx%d", tt.path, cfg.Width, cfg.Height, tt.wantWidth, tt.wantHeight)
+		}
+	}
+
+	// The resized images were written to the cache directory, and a new
+	// Server uses them.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("got %d files in cache directory; want 4", len(entries))
+	}
+	for _, e := range entries {
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), []byte("cached"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s = New(fsys, WithImageResizing(ImageResizeOptions{
+		Widths:   []int{50},
+		CacheDir: dir,
+	}))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/img/hero.png?w=50", nil))
+	if got, want := w.Body.String(), "cached"; got != want {
+		t.Errorf("with cache directory: got body %q; want %q", got, want)
+	}
+}
+
+func TestImageResizingLimits(t *testing.T) {
+	fsys := fstest.MapFS{
+		"big.png": &fstest.MapFile{Data: testPNG(t, 100, 100)},
+		"bad.png": &fstest.MapFile{Data: []byte("not a png")},
+	}
+	s := New(fsys, WithImageResizing(ImageResizeOptions{
+		Widths:          []int{10},
+		MaxSourcePixels: 5000,
+	}))
+	for _, path := range []string{"/big.png?w=10", "/bad.png?w=10"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 500 {
+			t.Errorf("GET %s: got status %d; want 500", path, w.Code)
+		}
+	}
+}
+
+func TestSrcset(t *testing.T) {
+	src := testPNG(t, 200, 100)
+	fsys := fstest.MapFS{
+		"img/hero.png": &fstest.MapFile{Data: src},
+		"img/bad.png":  &fstest.MapFile{Data: []byte("not a png")},
+		"a.txt":        &fstest.MapFile{Data: []byte("a")},
+	}
+	tagged := "/static/img/hero." + hashTag(string(src)) + ".png"
+	for _, tt := range []struct {
+		widths []int
+		want   string
+	}{
+		{[]int{100, 50}, tagged + "?w=50 50w, " + tagged + "?w=100 100w, " + tagged + " 200w"},
+		{[]int{100, 200, 400}, tagged + "?w=100 100w, " + tagged + "?w=200 200w"},
+		{[]int{400}, tagged + " 200w"},
+	} {
+		s := New(fsys, WithImageResizing(ImageResizeOptions{Widths: tt.widths}))
+		got, err := s.Srcset("/static", "img/hero.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("with widths %v: got %q; want %q", tt.widths, got, tt.want)
+		}
+	}
+
+	s := New(fsys, WithImageResizing(ImageResizeOptions{Widths: []int{50}}))
+	for _, name := range []string{"a.txt", "img/bad.png", "img/missing.png"} {
+		if _, err := s.Srcset("/", name); err == nil {
+			t.Errorf("Srcset(%q): got nil error", name)
+		}
+	}
+	if _, err := New(fsys).Srcset("/", "img/hero.png"); err == nil {
+		t.Error("Srcset without WithImageResizing: got nil error")
+	}
+}
@@ -0,0 +1,123 @@
+package assetserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// WithImageNegotiation makes the Server serve an alternative format of a
+// JPEG, PNG, or GIF image to browsers which accept it, if the Server's file
+// system has a variant of the image in that format alongside it: a file with
+// the same name but a different extension, such as "hero.avif" or
+// "hero.webp" for "hero.jpg". Pages keep referring to the original image (and
+// to its tagged name, as returned by [Server.Tag]); the Server picks the
+// variant according to the request's Accept header and adds a Vary: Accept
+// header to the response so that caches store each format separately.
+//
+// The formats are given in order of preference by their extensions without
+// the dot; the supported formats are "avif", "webp", and "jxl". If no
+// formats are given, the Server prefers AVIF and then WebP. A format is only
+// used if the Accept header lists its media type explicitly, since browsers
+// send wildcards such as image/* even for formats they don't support.
+//
+// The tag in an image's tagged name is that of the original file, so when a
+// variant changes without the original changing, clients that have cached
+// the variant keep using it until the original changes too.
+func WithImageNegotiation(formats ...string) Option {
+	if len(formats) == 0 {
+		formats = []string{"avif", "webp"}
+	}
+	var variants []imageVariant
+	for _, format := range formats {
+		mediaType, ok := imageVariantTypes[format]
+		if !ok {
+			panic(fmt.Sprintf("assetserver: WithImageNegotiation: unsupported format %q", format))
+		}
+		variants = append(variants, imageVariant{ext: "." + format, mediaType: mediaType})
+	}
+	return func(s *Server) {
+		s.imageVariants = variants
+	}
+}
+
+var imageVariantTypes = map[string]string{
+	"avif": "image/avif",
+	"webp": "image/webp",
+	"jxl":  "image/jxl",
+}
+
+type imageVariant struct {
+	ext       string // such as ".webp"
+	mediaType string
+}
+
+// negotiateImage chooses the variant of the named image to serve in response
+// to r, and adds a Vary header to h for images which can be negotiated. If a
+// variant should be served, it returns the variant's name and current tag;
+// otherwise it returns "", "". If the request included a tag, a variant is
+// only chosen if the tag matches the original image. For an untagged request,
+// the variant's tag is "" too, so that the response is cached like any other
+// for an untagged name.
+func (s *Server) negotiateImage(ctx context.Context, r *http.Request, h http.Header, name, tag string) (variant, variantTag string) {
+	if len(s.imageVariants) == 0 {
+		return "", ""
+	}
+	ext := path.Ext(name)
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+	default:
+		return "", ""
+	}
+	h.Add("Vary", "Accept")
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return "", ""
+	}
+	checkedTag := tag == ""
+	for _, v := range s.imageVariants {
+		if !headerAccepts(accept, v.mediaType) {
+			continue
+		}
+		info, err := s.currentInfo(ctx, strings.TrimSuffix(name, ext)+v.ext)
+		if err != nil {
+			continue
+		}
+		if !checkedTag {
+			orig, err := s.currentInfo(ctx, name)
+			if err != nil || orig.tag != tag {
+				return "", ""
+			}
+			checkedTag = true
+		}
+		if tag == "" {
+			return strings.TrimSuffix(name, ext) + v.ext, ""
+		}
+		return strings.TrimSuffix(name, ext) + v.ext, info.tag
+	}
+	return "", ""
+}
+
+// headerAccepts reports whether the value of an Accept or Accept-Encoding
+// header explicitly lists value (a media type or content coding) with a
+// non-zero quality.
+func headerAccepts(header, value string) bool {
+	for _, elem := range strings.Split(header, ",") {
+		v, params, _ := strings.Cut(elem, ";")
+		if !strings.EqualFold(strings.TrimSpace(v), value) {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(k) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
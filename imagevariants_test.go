@@ -0,0 +1,108 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestImageNegotiation(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hero.jpg":  &fstest.MapFile{Data: []byte("jpeg")},
+		"hero.webp": &fstest.MapFile{Data: []byte("webp")},
+		"hero.avif": &fstest.MapFile{Data: []byte("avif")},
+		"logo.png":  &fstest.MapFile{Data: []byte("png")},
+		"logo.webp": &fstest.MapFile{Data: []byte("logo webp")},
+		"a.css":     &fstest.MapFile{Data: []byte("css")},
+	}
+	s := New(fsys, WithImageNegotiation())
+	const browser = "image/avif,image/webp,image/apng,image/*,*/*;q=0.8"
+	for _, tt := range []struct {
+		path     string
+		accept   string
+		code     int
+		want     string
+		wantVary string
+	}{
+		{"/hero.jpg", browser, 200, "avif", "Accept"},
+		{"/hero.jpg", "image/webp,image/*", 200, "webp", "Accept"},
+		{"/hero.jpg", "image/avif;q=0, image/webp;q=0.5", 200, "webp", "Accept"},
+		{"/hero.jpg", "image/*,*/*", 200, "jpeg", "Accept"},
+		{"/hero.jpg", "", 200, "jpeg", "Accept"},
+		{"/logo.png", browser, 200, "logo webp", "Accept"},
+		{"/hero." + hashTag("jpeg") + ".jpg", browser, 200, "avif", "Accept"},
+		{"/hero." + hashTag("jpeg") + ".jpg", "", 200, "jpeg", "Accept"},
+		{"/hero." + hashTag("old") + ".jpg", browser, 404, "", "Accept"},
+		{"/hero.webp", browser, 200, "webp", ""},
+		{"/a.css", browser, 200, "css", ""},
+	} {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("GET %s (Accept: %s): got status %d; want %d", tt.path, tt.accept, w.Code, tt.code)
+			continue
+		}
+		if got := w.Header().Get("Vary"); got != tt.wantVary {
+			t.Errorf("GET %s (Accept: %s): got Vary %q; want %q", tt.path, tt.accept, got, tt.wantVary)
+		}
+		if tt.code != 200 {
+			continue
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s (Accept: %s): got body %q; want %q", tt.path, tt.accept, got, tt.want)
+		}
+		if got, want := w.Header().Get("ETag"), `"`+hashTag(tt.want)+`"`; got != want {
+			t.Errorf("GET %s (Accept: %s): got ETag %s; want %s", tt.path, tt.accept, got, want)
+		}
+	}
+}
+
+func TestImageNegotiationPreference(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hero.jpg":  &fstest.MapFile{Data: []byte("jpeg")},
+		"hero.webp": &fstest.MapFile{Data: []byte("webp")},
+		"hero.avif": &fstest.MapFile{Data: []byte("avif")},
+	}
+	s := New(fsys, WithImageNegotiation("webp"))
+	r := httptest.NewRequest("GET", "/hero.jpg", nil)
+	r.Header.Set("Accept", "image/avif,image/webp")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if got, want := w.Body.String(), "webp"; got != want {
+		t.Errorf("got body %q; want %q", got, want)
+	}
+	if got, want := w.Header().Get("Content-Type"), "image/webp"; got != want {
+		t.Errorf("got Content-Type %q; want %q", got, want)
+	}
+}
+
+func TestImageNegotiationCacheControl(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hero.jpg":  &fstest.MapFile{Data: []byte("jpeg")},
+		"hero.webp": &fstest.MapFile{Data: []byte("webp")},
+	}
+	s := New(fsys, WithImageNegotiation())
+	cacheControl := func(path, accept string) string {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Fatalf("GET %s (Accept: %s): got status %d", path, accept, w.Code)
+		}
+		return w.Header().Get("Cache-Control")
+	}
+	// A variant served for an untagged name may change, so it is cached
+	// like the original rather than indefinitely.
+	if got, want := cacheControl("/hero.jpg", "image/webp"), cacheControl("/hero.jpg", "image/jpeg"); got != want {
+		t.Errorf("untagged negotiated response: got Cache-Control %q; want %q", got, want)
+	}
+	tagged := "/hero." + hashTag("jpeg") + ".jpg"
+	if got, want := cacheControl(tagged, "image/webp"), cacheControl(tagged, "image/jpeg"); got != want {
+		t.Errorf("tagged negotiated response: got Cache-Control %q; want %q", got, want)
+	}
+}
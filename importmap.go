@@ -0,0 +1,59 @@
+package assetserver
+
+import (
+	"encoding/json"
+	"io/fs"
+	"strings"
+)
+
+// ImportMap returns an import map, in JSON format, which maps the URL of each
+// JavaScript module in the Server's file system to its tagged URL. The URLs
+// are formed by joining prefix (the URL path at which the Server's files are
+// served, such as "/static/") with the file names.
+//
+// With the import map in place, browsers fetch modules by their tagged names
+// when resolving imports (including relative imports between modules), so
+// that an entire module graph can be cached indefinitely without rewriting
+// the import statements. Include the map in a page's <head> before any
+// module scripts:
+//
+//	<script type="importmap">{{.ImportMap}}</script>
+//
+// The modules are the files matching any of the given patterns, or all .js
+// and .mjs files if there are no patterns. (See the Patterns section of the
+// package documentation for the syntax.) The JSON is safe to include in an
+// HTML <script> element.
+//
+// ImportMap hashes every module, so applications should call it when their
+// pages change (typically once, at startup) rather than for each request. A
+// no-cache Server does not use tagged names, so its import map is empty.
+func (s *Server) ImportMap(prefix string, patterns ...string) ([]byte, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.js", "**/*.mjs"}
+	}
+	patterns = compilePatterns(patterns)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	imports := make(map[string]string)
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !matchAny(patterns, name) || s.noCache {
+			return nil
+		}
+		tagged, err := s.Tag(name)
+		if err != nil {
+			return err
+		}
+		imports[prefix+escapePath(name)] = prefix + escapePath(tagged)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Imports map[string]string `json:"imports"`
+	}{imports})
+}
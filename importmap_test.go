@@ -0,0 +1,51 @@
+package assetserver
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestImportMap(t *testing.T) {
+	fsys := fstest.MapFS{
+		"js/app.js":      &fstest.MapFile{Data: []byte("import './util.js'")},
+		"js/util.js":     &fstest.MapFile{Data: []byte("util")},
+		"js/lib/x.mjs":   &fstest.MapFile{Data: []byte("x")},
+		"css/app.css":    &fstest.MapFile{Data: []byte("css")},
+		"js/<script>.js": &fstest.MapFile{Data: []byte("s")},
+	}
+	s := New(fsys)
+	got, err := s.ImportMap("/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"imports":{` +
+		`"/static/js/%3Cscript%3E.js":"/static/js/%3Cscript%3E.` + hashTag("s") + `.js",` +
+		`"/static/js/app.js":"/static/js/app.` + hashTag("import './util.js'") + `.js",` +
+		`"/static/js/lib/x.mjs":"/static/js/lib/x.` + hashTag("x") + `.mjs",` +
+		`"/static/js/util.js":"/static/js/util.` + hashTag("util") + `.js"}}`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = s.ImportMap("/", "js/lib/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"imports":{"/js/lib/x.mjs":"/js/lib/x.` + hashTag("x") + `.mjs"}}`
+	if string(got) != want {
+		t.Errorf("with pattern, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestImportMapNoCache(t *testing.T) {
+	s := NewNoCache(fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte("app")},
+	})
+	got, err := s.ImportMap("/static/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"imports":{}}`; string(got) != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package assetserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+)
+
+// An IntegrityEntry describes a file in a Server's file system. See
+// [Server.Integrity].
+type IntegrityEntry struct {
+	// Path is the file's name, as given to the underlying fs.FS.
+	Path string `json:"path"`
+	// Tag is the file's current tag, as used in its tagged name.
+	Tag string `json:"tag"`
+	// SHA256 is the hex-encoded SHA-256 hash of the file's contents (as
+	// stored in the file system, before any transformations).
+	SHA256 string `json:"sha256"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+}
+
+// Integrity walks the file system of the current generation of the Server
+// (see [Server.Current]) and returns a description of each file, in order by
+// name. Deploy tooling can compare the result with the output of the build
+// to verify that the Server is serving what was built. Integrity reads every
+// file in full to compute its SHA-256 hash (and, like [Server.Preload],
+// hashes every file whose information isn't cached), so it may be slow for
+// large file systems.
+//
+// If ctx is canceled, Integrity stops early and returns the context's error.
+//
+// The entries are also available as a JSON array from [Server.AdminHandler].
+func (s *Server) Integrity(ctx context.Context) ([]IntegrityEntry, error) {
+	if g := s.Current(); g != s {
+		return g.Integrity(ctx)
+	}
+	var entries []IntegrityEntry
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := s.currentInfo(ctx, name)
+		if err != nil {
+			return err
+		}
+		sum, size, err := hashFile(ctx, s.fsys, name)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, IntegrityEntry{
+			Path:   name,
+			Tag:    info.tag,
+			SHA256: sum,
+			Size:   size,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// hashFile returns the hex-encoded SHA-256 hash and the size of the named
+// file's contents.
+func hashFile(ctx context.Context, fsys fs.FS, name string) (sum string, size int64, err error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err = io.Copy(h, ctxReader{ctx, f})
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+func (s *Server) serveIntegrity(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.Integrity(r.Context())
+	if err != nil {
+		s.log(r.Context(), slog.LevelError, "error computing integrity manifest", "err", err)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []IntegrityEntry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,80 @@
+package assetserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIntegrity(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+		"js/app.js":     &fstest.MapFile{Data: []byte("app()")},
+	}
+	s := New(fsys)
+	defer s.Close()
+	sha := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	want := []IntegrityEntry{
+		{Path: "css/style.css", Tag: hashTag("body{}"), SHA256: sha("body{}"), Size: 6},
+		{Path: "js/app.js", Tag: hashTag("app()"), SHA256: sha("app()"), Size: 5},
+	}
+	got, err := s.Integrity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Integrity: got %+v; want %+v", got, want)
+	}
+
+	// The admin handler serves the same entries.
+	h := s.AdminHandler(func(h http.Handler) http.Handler { return h })
+	for _, tt := range []struct {
+		method string
+		code   int
+	}{
+		{"GET", 200},
+		{"POST", 405},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, "/integrity", nil))
+		if w.Code != tt.code {
+			t.Errorf("%s /integrity: got status %d; want %d", tt.method, w.Code, tt.code)
+			continue
+		}
+		if tt.code != 200 {
+			continue
+		}
+		var entries []IntegrityEntry
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(entries, want) {
+			t.Errorf("GET /integrity: got %+v; want %+v", entries, want)
+		}
+	}
+
+	// The entries describe the current generation.
+	next := fstest.MapFS{
+		"css/style.css": &fstest.MapFile{Data: []byte("p{}")},
+	}
+	s.BeginGeneration(next)
+	got, err = s.Integrity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []IntegrityEntry{
+		{Path: "css/style.css", Tag: hashTag("p{}"), SHA256: sha("p{}"), Size: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Integrity after BeginGeneration: got %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package assetserver
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithLanguageNegotiation makes the Server serve localized variants of the
+// files matching any of the given patterns (or, if there are no patterns, all
+// .html files) according to the request's Accept-Language header. The
+// variants of a file are named by inserting a lowercase language tag before
+// the extension: a request for "errors.html" is answered with "errors.de.html"
+// for a client that prefers German, "errors.en.html" for one that prefers
+// English, and so on.
+//
+// For each language the client accepts, in order of preference, the Server
+// looks for a variant with the full language tag (such as "pt-br") and then
+// with the primary language alone ("pt"). If there is no such variant, it
+// serves the variant for defaultLang. Negotiation only applies to files
+// whose defaultLang variant exists; requests for other names, and requests
+// for tagged names, are served as usual.
+//
+// Negotiated responses have a Content-Language header naming the variant's
+// language and a Vary: Accept-Language header so that caches store each
+// variant separately.
+func WithLanguageNegotiation(defaultLang string, patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.html"}
+	}
+	ln := &languageNegotiation{
+		defaultLang: strings.ToLower(defaultLang),
+		patterns:    compilePatterns(patterns),
+	}
+	return func(s *Server) {
+		s.languages = ln
+	}
+}
+
+type languageNegotiation struct {
+	defaultLang string
+	patterns    []string
+}
+
+// languageVariant returns the name of the variant of the named file for lang.
+func languageVariant(name, lang string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + lang + ext
+}
+
+// negotiateLanguage returns the name of the localized variant of the named
+// file to serve in response to r, or "" if the file isn't localized. If it
+// chooses a variant, it sets the Vary and Content-Language headers in h.
+func (s *Server) negotiateLanguage(ctx context.Context, r *http.Request, h http.Header, name string) string {
+	ln := s.languages
+	if ln == nil || !matchAny(ln.patterns, name) {
+		return ""
+	}
+	defaultName := languageVariant(name, ln.defaultLang)
+	if _, err := s.currentInfo(ctx, defaultName); err != nil {
+		return ""
+	}
+	h.Add("Vary", "Accept-Language")
+	variant, lang := defaultName, ln.defaultLang
+	for _, l := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+		candidates := []string{l}
+		if primary, _, ok := strings.Cut(l, "-"); ok {
+			candidates = append(candidates, primary)
+		}
+		found := false
+		for _, c := range candidates {
+			if c == ln.defaultLang {
+				found = true
+				break
+			}
+			v := languageVariant(name, c)
+			if _, err := s.currentInfo(ctx, v); err == nil {
+				variant, lang = v, c
+				found = true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+	h.Set("Content-Language", lang)
+	return variant
+}
+
+// acceptedLanguages returns the lowercase language tags listed in an
+// Accept-Language header value, most preferred first. Wildcards, tags with a
+// quality of zero, and malformed tags are omitted.
+func acceptedLanguages(header string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, elem := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(elem, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !isLanguageTag(tag) {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(params, "="); ok && strings.TrimSpace(k) == "q" {
+			var err error
+			if q, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, lang{tag, q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// isLanguageTag reports whether s looks like a language tag. This keeps
+// arbitrary header contents out of file names.
+func isLanguageTag(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLanguageNegotiation(t *testing.T) {
+	fsys := fstest.MapFS{
+		"errors.en.html":  &fstest.MapFile{Data: []byte("error")},
+		"errors.de.html":  &fstest.MapFile{Data: []byte("Fehler")},
+		"errors.pt.html":  &fstest.MapFile{Data: []byte("erro")},
+		"about.html":      &fstest.MapFile{Data: []byte("about")},
+		"about.fr.html":   &fstest.MapFile{Data: []byte("à propos")},
+		"app.js":          &fstest.MapFile{Data: []byte("js")},
+		"docs/a.en.html":  &fstest.MapFile{Data: []byte("a")},
+		"docs/a.es.html":  &fstest.MapFile{Data: []byte("una")},
+		"docs/a.en.html~": &fstest.MapFile{Data: []byte("backup")},
+	}
+	s := New(fsys, WithLanguageNegotiation("en"))
+	for _, tt := range []struct {
+		path     string
+		accept   string
+		code     int
+		want     string
+		wantLang string
+	}{
+		{"/errors.html", "de-DE,de;q=0.9,en;q=0.8", 200, "Fehler", "de"},
+		{"/errors.html", "fr, de;q=0.5", 200, "Fehler", "de"},
+		{"/errors.html", "pt-BR", 200, "erro", "pt"},
+		{"/errors.html", "en-GB, de", 200, "error", "en"},
+		{"/errors.html", "de;q=0.1, en;q=0.9", 200, "error", "en"},
+		{"/errors.html", "de;q=0, fr", 200, "error", "en"},
+		{"/errors.html", "", 200, "error", "en"},
+		{"/errors.html", "../../x", 200, "error", "en"},
+		{"/docs/a.html", "es", 200, "una", "es"},
+		{"/errors.de.html", "en", 200, "Fehler", ""},
+		{"/about.html", "fr", 200, "about", ""},
+		{"/app.js", "de", 200, "js", ""},
+		{"/missing.html", "de", 404, "", ""},
+	} {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept-Language", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("GET %s (Accept-Language: %s): got status %d; want %d", tt.path, tt.accept, w.Code, tt.code)
+			continue
+		}
+		if tt.code != 200 {
+			continue
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s (Accept-Language: %s): got body %q; want %q", tt.path, tt.accept, got, tt.want)
+		}
+		if got := w.Header().Get("Content-Language"); got != tt.wantLang {
+			t.Errorf("GET %s (Accept-Language: %s): got Content-Language %q; want %q", tt.path, tt.accept, got, tt.wantLang)
+		}
+		wantVary := ""
+		if tt.wantLang != "" {
+			wantVary = "Accept-Language"
+		}
+		if got := w.Header().Get("Vary"); got != wantVary {
+			t.Errorf("GET %s (Accept-Language: %s): got Vary %q; want %q", tt.path, tt.accept, got, wantVary)
+		}
+	}
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"en", []string{"en"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []string{"fr-ch", "fr", "en", "de"}},
+		{"de;q=0.5, en", []string{"en", "de"}},
+		{"de;q=0, en;q=bad, ../x, es", []string{"es"}},
+	} {
+		if got := acceptedLanguages(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("acceptedLanguages(%q) = %q; want %q", tt.header, got, tt.want)
+		}
+	}
+}
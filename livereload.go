@@ -0,0 +1,304 @@
+package assetserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithLiveReload makes the Server watch its file system for changes so that
+// browsers can reload automatically during development. The Server checks
+// the files for changes (by comparing their sizes and modification times)
+// every interval d in the background until [Server.Close] is called.
+//
+// Connect browsers to the changes using the handler returned by
+// [Server.LiveReloadHandler].
+//
+// WithLiveReload may only be used with a no-cache Server (see [NewNoCache]);
+// New panics if it is given this option.
+func WithLiveReload(d time.Duration) Option {
+	return func(s *Server) {
+		if d <= 0 {
+			panic("assetserver: WithLiveReload called with non-positive interval")
+		}
+		s.liveReload = &liveReload{
+			interval: d,
+			subs:     make(map[chan []string]struct{}),
+		}
+	}
+}
+
+type liveReload struct {
+	interval time.Duration
+
+	mu    sync.Mutex
+	files map[string]fileStamp // as of the last check
+	subs  map[chan []string]struct{}
+}
+
+// A fileStamp identifies a version of a file.
+type fileStamp struct {
+	size  int64
+	mtime int64
+}
+
+// snapshotFiles walks the file system and records the version of each file.
+func (s *Server) snapshotFiles() map[string]fileStamp {
+	files := make(map[string]fileStamp)
+	fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[name] = fileStamp{size: fi.Size(), mtime: fi.ModTime().UnixNano()}
+		return nil
+	})
+	return files
+}
+
+func (s *Server) watchLoop() {
+	lr := s.liveReload
+	ticker := time.NewTicker(lr.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkChanges()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// checkChanges compares the files to the last snapshot and notifies the
+// subscribers of any changes. It is only called by watchLoop, so lr.files is
+// not modified concurrently; lr.mu is held only briefly, so that connecting
+// clients aren't blocked while changed files are hashed.
+func (s *Server) checkChanges() {
+	lr := s.liveReload
+	files := s.snapshotFiles()
+	lr.mu.Lock()
+	var changed []string
+	for name, stamp := range files {
+		if old, ok := lr.files[name]; !ok || old != stamp {
+			changed = append(changed, name)
+		}
+	}
+	for name := range lr.files {
+		if _, ok := files[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	lr.files = files
+	lr.mu.Unlock()
+	if len(changed) == 0 {
+		return
+	}
+	sort.Strings(changed)
+	if s.changeHook != nil {
+		// Bring the cached info for the changed files up to date now,
+		// rather than on their next request, so that the change hook is
+		// called promptly.
+		for _, name := range changed {
+			s.revalidateFile(name)
+		}
+	}
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	for ch := range lr.subs {
+		select {
+		case ch <- changed:
+		default:
+			// The subscriber hasn't received the previous change
+			// yet; it will reload anyway.
+		}
+	}
+}
+
+func (lr *liveReload) subscribe() chan []string {
+	ch := make(chan []string, 1)
+	lr.mu.Lock()
+	lr.subs[ch] = struct{}{}
+	lr.mu.Unlock()
+	return ch
+}
+
+func (lr *liveReload) unsubscribe(ch chan []string) {
+	lr.mu.Lock()
+	delete(lr.subs, ch)
+	lr.mu.Unlock()
+}
+
+// WithLiveReloadInjection makes the Server add a script tag loading the live
+// reload client from url (where the application has mounted
+// [Server.LiveReloadHandler]) to the HTML files that it serves, just before
+// the closing </body> tag (or at the end, if there isn't one). This means
+// that the application's templates don't need to include the script
+// themselves during development.
+//
+// WithLiveReloadInjection requires [WithLiveReload].
+func WithLiveReloadInjection(url string) Option {
+	return func(s *Server) {
+		s.injectURL = url
+	}
+}
+
+// injects reports whether the Server modifies the file with the given info
+// as it serves it.
+func (s *Server) injects(info *fileInfo) bool {
+	if s.injectURL == "" {
+		return false
+	}
+	mediaType, _, _ := strings.Cut(info.contentType, ";")
+	return strings.TrimSpace(mediaType) == "text/html"
+}
+
+// serveInjected serves an HTML file with the live reload script tag added.
+func (s *Server) serveInjected(w http.ResponseWriter, r *http.Request, name string, info *fileInfo, f io.Reader) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		s.writeFSError(w, r, name, err)
+		return
+	}
+	tag := `<script src="` + html.EscapeString(s.injectURL) + `"></script>`
+	i := lastIndexFold(b, "</body>")
+	if i < 0 {
+		i = len(b)
+	}
+	out := make([]byte, 0, len(b)+len(tag))
+	out = append(out, b[:i]...)
+	out = append(out, tag...)
+	out = append(out, b[i:]...)
+	http.ServeContent(w, r, name, time.Unix(0, info.mtime), bytes.NewReader(out))
+}
+
+// lastIndexFold returns the index of the last ASCII case-insensitive match of
+// the lowercase string sub in b, or -1 if there is none.
+func lastIndexFold(b []byte, sub string) int {
+	for i := len(b) - len(sub); i >= 0; i-- {
+		if bytes.EqualFold(b[i:i+len(sub)], []byte(sub)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// liveReloadKeepalive is how often the live reload handler sends a comment
+// to keep idle connections open through proxies.
+const liveReloadKeepalive = 30 * time.Second
+
+// LiveReloadHandler returns an HTTP handler which notifies browsers of
+// changes to the Server's files using server-sent events. Mount it at some
+// path, such as "/_livereload", and include a script tag for the same URL in
+// the application's pages during development:
+//
+//	<script src="/_livereload"></script>
+//
+// The handler responds to requests for the script (those that don't accept
+// text/event-stream) with the client code, which connects to the event
+// stream. When files change, the client reloads the stylesheets if only CSS
+// files changed and otherwise reloads the page. The client code is also
+// available as [LiveReloadScript].
+//
+// Each event is named "change" and its data is a JSON array of the names of
+// the files that changed.
+//
+// LiveReloadHandler panics if the Server wasn't created with
+// [WithLiveReload].
+func (s *Server) LiveReloadHandler() http.Handler {
+	if s.liveReload == nil {
+		panic("assetserver: LiveReloadHandler called on a Server without WithLiveReload")
+	}
+	return http.HandlerFunc(s.serveLiveReload)
+}
+
+func (s *Server) serveLiveReload(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte(LiveReloadScript("")))
+		return
+	}
+	lr := s.liveReload
+	ch := lr.subscribe()
+	defer lr.unsubscribe(ch)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+	if _, err := w.Write([]byte(": connected\n\n")); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	keepalive := time.NewTicker(liveReloadKeepalive)
+	defer keepalive.Stop()
+	for {
+		var msg []byte
+		select {
+		case changed := <-ch:
+			data, err := json.Marshal(changed)
+			if err != nil {
+				panic(err)
+			}
+			msg = append(append([]byte("event: change\ndata: "), data...), "\n\n"...)
+		case <-keepalive.C:
+			msg = []byte(": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+		if _, err := w.Write(msg); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// LiveReloadScript returns JavaScript code which connects to the event stream
+// served by [Server.LiveReloadHandler] at the given URL and reloads the page
+// (or just its stylesheets) when files change. It is useful for including
+// the client inline, rather than loading it from the handler. If url is
+// empty, the client connects to the URL from which the script was loaded.
+func LiveReloadScript(url string) string {
+	src := "document.currentScript.src"
+	if url != "" {
+		b, err := json.Marshal(url)
+		if err != nil {
+			panic(err)
+		}
+		src = string(b)
+	}
+	return `(function() {
+	var es = new EventSource(` + src + `);
+	es.addEventListener("change", function(e) {
+		var files = JSON.parse(e.data);
+		var cssOnly = files.every(function(f) { return /\.css$/.test(f); });
+		if (!cssOnly) {
+			location.reload();
+			return;
+		}
+		document.querySelectorAll('link[rel="stylesheet"]').forEach(function(link) {
+			var u = new URL(link.href);
+			u.searchParams.set("livereload", Date.now());
+			link.href = u.href;
+		});
+	});
+})();
+`
+}
@@ -0,0 +1,188 @@
+package assetserver
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLiveReload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a.css", "a")
+	writeFile("b.js", "b")
+
+	s := NewNoCache(os.DirFS(dir), WithLiveReload(10*time.Millisecond))
+	defer s.Close()
+	ts := httptest.NewServer(s.LiveReloadHandler())
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	checkResponseHeader(t, resp, "Content-Type", "text/event-stream")
+	events := bufio.NewScanner(resp.Body)
+	nextLine := func() string {
+		t.Helper()
+		if !events.Scan() {
+			t.Fatalf("error reading event stream: %v", events.Err())
+		}
+		return events.Text()
+	}
+	if got, want := nextLine(), ": connected"; got != want {
+		t.Fatalf("got first line %q; want %q", got, want)
+	}
+	nextLine()
+
+	// Make sure the modification time changes even on file systems with
+	// coarse timestamps.
+	mtime := time.Now().Add(time.Hour)
+	writeFile("a.css", "aa")
+	if err := os.Chtimes(filepath.Join(dir, "a.css"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "b.js")); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for {
+		line := nextLine()
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == 2 {
+			break
+		}
+	}
+	want := []string{"event: change", `data: ["a.css","b.js"]`}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got event %q; want %q", lines, want)
+	}
+}
+
+func TestLiveReloadScript(t *testing.T) {
+	s := NewNoCache(os.DirFS(t.TempDir()), WithLiveReload(time.Hour))
+	defer s.Close()
+	ts := httptest.NewServer(s.LiveReloadHandler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	checkResponseCode(t, resp, 200)
+	checkResponseHeader(t, resp, "Content-Type", "text/javascript; charset=utf-8")
+	checkResponseBody(t, resp, []byte(LiveReloadScript("")))
+
+	if got := LiveReloadScript("/x"); !strings.Contains(got, `new EventSource("/x")`) {
+		t.Errorf("LiveReloadScript(%q) does not connect to /x:\n%s", "/x", got)
+	}
+}
+
+func TestLiveReloadRequiresNoCache(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New with WithLiveReload did not panic")
+		}
+	}()
+	New(os.DirFS(t.TempDir()), WithLiveReload(time.Second))
+}
+
+func TestLiveReloadInjection(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("<html><body><p>hi</p></BODY></html>")},
+		"frag.html":  &fstest.MapFile{Data: []byte("<p>hi</p>")},
+		"app.js":     &fstest.MapFile{Data: []byte("</body>")},
+	}
+	s := NewNoCache(fsys, WithLiveReload(time.Hour), WithLiveReloadInjection("/_livereload?a&b"))
+	defer s.Close()
+	const script = `<script src="/_livereload?a&amp;b"></script>`
+	for _, tt := range []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/index.html", "<html><body><p>hi</p>" + script + "</BODY></html>"},
+		{"GET", "/frag.html", "<p>hi</p>" + script},
+		{"GET", "/app.js", "</body>"},
+		{"HEAD", "/index.html", ""},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != 200 {
+			t.Errorf("%s %s: got status %d", tt.method, tt.path, w.Code)
+			continue
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s %s: got body %q; want %q", tt.method, tt.path, got, tt.want)
+		}
+		if tt.method == "HEAD" {
+			wantLen := strconv.Itoa(len("<html><body><p>hi</p>" + script + "</BODY></html>"))
+			if got := w.Header().Get("Content-Length"); got != wantLen {
+				t.Errorf("HEAD %s: got Content-Length %s; want %s", tt.path, got, wantLen)
+			}
+		}
+	}
+}
+
+func TestLiveReloadHashOutsideLock(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+	hashing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var block bool
+	slow := TransformFunc(func(name string, in []byte) ([]byte, error) {
+		if block {
+			hashing <- struct{}{}
+			<-release
+		}
+		return in, nil
+	})
+	s := NewNoCache(fsys, WithLiveReload(time.Hour), WithTransform(slow),
+		WithChangeHook(func(name, oldTag, newTag string) {}))
+	defer s.Close()
+	if _, err := s.currentInfo(context.Background(), "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	block = true
+	fsys["a.txt"] = &fstest.MapFile{Data: []byte("aa"), ModTime: time.Unix(1, 0)}
+	checked := make(chan struct{})
+	go func() {
+		s.checkChanges()
+		close(checked)
+	}()
+	<-hashing
+	// A client can connect while the changed file is hashed.
+	subscribed := make(chan chan []string)
+	go func() { subscribed <- s.liveReload.subscribe() }()
+	select {
+	case ch := <-subscribed:
+		s.liveReload.unsubscribe(ch)
+	case <-time.After(5 * time.Second):
+		t.Error("subscribe blocked while a changed file was hashed")
+	}
+	close(release)
+	<-checked
+}
@@ -0,0 +1,48 @@
+package assetserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithLogger makes the Server log events which may indicate problems:
+//
+//   - errors which cause a 500 response (Error level)
+//   - errors hashing files and files which take a long time to hash (Warn
+//     level), as well as every hash (Debug level)
+//   - requests whose tags don't match the current file contents (Info level)
+//   - files which have changed or been deleted, invalidating the cached
+//     information about them (Info level)
+//   - problems with the cache file given by [WithCacheFile]
+//
+// By default, a Server doesn't log anything.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// slowHash is the time after which a hash operation is logged as slow.
+const slowHash = time.Second
+
+func (s *Server) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.Log(ctx, level, msg, args...)
+}
+
+func (s *Server) logHash(ctx context.Context, name string, d time.Duration, err error) {
+	if s.logger == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		s.log(ctx, slog.LevelWarn, "error hashing file", "name", name, "duration", d, "err", err)
+	case d >= slowHash:
+		s.log(ctx, slog.LevelWarn, "slow hash", "name", name, "duration", d)
+	default:
+		s.log(ctx, slog.LevelDebug, "hashed file", "name", name, "duration", d)
+	}
+}
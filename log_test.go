@@ -0,0 +1,70 @@
+package assetserver
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fsys := errorFS{
+		FS: fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+			"b.txt": &fstest.MapFile{Data: []byte("b")},
+		},
+		errs: map[string]error{"b.txt": errors.New("disk on fire")},
+	}
+	s := New(fsys, WithLogger(logger))
+	for _, target := range []string{
+		"/a.txt",
+		"/a.0123456789.txt",
+		"/b.txt",
+	} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	delete(fsys.FS.(fstest.MapFS), "a.txt")
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a.txt", nil))
+
+	got := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="hashed file" name=a.txt`,
+		`level=INFO msg="request tag does not match file" name=a.txt tag=0123456789`,
+		`level=ERROR msg="error serving file" name=b.txt err="open b.txt: disk on fire"`,
+		`level=INFO msg="forgot cached info for deleted file" name=a.txt`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output does not contain %q; got:\n%s", want, got)
+		}
+	}
+}
+
+func TestLoggerFileChanged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	dir := t.TempDir()
+	name := dir + "/a.txt"
+	if err := os.WriteFile(name, []byte("a0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithLogger(logger))
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte("a11"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	want := `level=INFO msg="file changed; cached info is outdated" name=a.txt tag=` + hashTag("a0")
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("log output does not contain %q; got:\n%s", want, got)
+	}
+}
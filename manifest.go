@@ -0,0 +1,206 @@
+package assetserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// WithBuildManifest makes the Server read the manifest written by a front-end
+// build tool, such as Vite (with build.manifest enabled) or webpack (with
+// webpack-manifest-plugin), from the named file in its file system so that
+// [Server.Entry] can resolve the tool's entry points to their output files.
+// The manifest is read when Entry is first called and again whenever it
+// changes.
+//
+// The file names in the manifest are interpreted as names in the Server's
+// file system (with any leading slash removed), so the tool's output
+// directory should be the root of the file system and its public path
+// should be empty or "/".
+func WithBuildManifest(name string) Option {
+	name = strings.TrimPrefix(name, "/")
+	return func(s *Server) {
+		s.buildManifest = &buildManifest{name: name}
+	}
+}
+
+// An Entry describes the output files for an entry point of a front-end
+// build. See [Server.Entry].
+type Entry struct {
+	// File is the name of the entry's main output file, such as
+	// "assets/main.4f2a1c.js".
+	File string
+	// CSS lists the stylesheets that the entry (including the chunks it
+	// imports) requires, in order. Pages should link to them, since the
+	// JavaScript doesn't load them itself.
+	CSS []string
+	// Imports lists the JavaScript chunks that the entry imports, directly
+	// or indirectly. Pages may preload them to avoid a request waterfall.
+	Imports []string
+}
+
+type buildManifest struct {
+	name string
+
+	mu     sync.Mutex
+	tag    string // of the parsed manifest file
+	chunks map[string]*manifestChunk
+	byName map[string]string // chunk name -> key, for entry chunks
+	flat   bool              // the manifest is a flat map of names (webpack)
+}
+
+// A manifestChunk is an entry in a Vite manifest. A webpack manifest, which
+// maps names to files, is represented by chunks with only File set.
+type manifestChunk struct {
+	File    string   `json:"file"`
+	Name    string   `json:"name"`
+	IsEntry bool     `json:"isEntry"`
+	CSS     []string `json:"css"`
+	Imports []string `json:"imports"`
+}
+
+// Entry returns the output files for an entry point of the front-end build
+// whose manifest was given by [WithBuildManifest]. The name is the entry's
+// source file as it appears in the manifest (such as "src/main.ts" for Vite
+// or "main.js" for webpack) or the name of the entry chunk (such as "main").
+// Templates can use Entry to refer to the hashed names that the build tool
+// produced:
+//
+//	{{with entry "main"}}
+//	{{range .CSS}}<link rel="stylesheet" href="/static/{{.}}">{{end}}
+//	<script type="module" src="/static/{{.File}}"></script>
+//	{{end}}
+//
+// Entry returns an error if the Server wasn't created with WithBuildManifest,
+// the manifest can't be read, or it has no such entry.
+func (s *Server) Entry(name string) (*Entry, error) {
+	bm := s.buildManifest
+	if bm == nil {
+		return nil, errors.New("assetserver: Entry called on a Server without WithBuildManifest")
+	}
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if err := s.loadBuildManifest(context.Background()); err != nil {
+		return nil, err
+	}
+	key, ok := bm.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("assetserver: no entry %q in build manifest %s", name, bm.name)
+	}
+	entry := &Entry{File: cleanManifestName(bm.chunks[key].File)}
+	if bm.flat {
+		// webpack lists an entry's stylesheet as a sibling of its
+		// script.
+		if base, ok := strings.CutSuffix(key, ".js"); ok {
+			if css, ok := bm.chunks[base+".css"]; ok {
+				entry.CSS = append(entry.CSS, cleanManifestName(css.File))
+			}
+		}
+		return entry, nil
+	}
+	seen := map[string]bool{key: true}
+	var visit func(key string, root bool)
+	visit = func(key string, root bool) {
+		c := bm.chunks[key]
+		if c == nil {
+			return
+		}
+		// Visit imports first so that shared stylesheets come before
+		// the ones that depend on them.
+		for _, imp := range c.Imports {
+			if seen[imp] {
+				continue
+			}
+			seen[imp] = true
+			visit(imp, false)
+		}
+		if !root {
+			entry.Imports = append(entry.Imports, cleanManifestName(c.File))
+		}
+		for _, css := range c.CSS {
+			css = cleanManifestName(css)
+			if !containsString(entry.CSS, css) {
+				entry.CSS = append(entry.CSS, css)
+			}
+		}
+	}
+	visit(key, true)
+	return entry, nil
+}
+
+// lookup returns the manifest key for the entry with the given name.
+func (bm *buildManifest) lookup(name string) (string, bool) {
+	if _, ok := bm.chunks[name]; ok {
+		return name, true
+	}
+	if bm.flat {
+		if _, ok := bm.chunks[name+".js"]; ok {
+			return name + ".js", true
+		}
+		return "", false
+	}
+	key, ok := bm.byName[name]
+	return key, ok
+}
+
+// loadBuildManifest reads and parses the build manifest if it has changed
+// since it was last read. The caller must hold bm.mu.
+func (s *Server) loadBuildManifest(ctx context.Context) error {
+	bm := s.buildManifest
+	info, err := s.currentInfo(ctx, bm.name)
+	if err != nil {
+		return fmt.Errorf("assetserver: cannot read build manifest: %w", err)
+	}
+	if info.tag == bm.tag && bm.chunks != nil {
+		return nil
+	}
+	b, err := fs.ReadFile(s.fsys, bm.name)
+	if err != nil {
+		return fmt.Errorf("assetserver: cannot read build manifest: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("assetserver: cannot parse build manifest %s: %w", bm.name, err)
+	}
+	chunks := make(map[string]*manifestChunk)
+	byName := make(map[string]string)
+	flat := false
+	for key, v := range raw {
+		var file string
+		if err := json.Unmarshal(v, &file); err == nil {
+			flat = true
+			chunks[key] = &manifestChunk{File: file}
+			continue
+		}
+		c := new(manifestChunk)
+		if err := json.Unmarshal(v, c); err != nil {
+			return fmt.Errorf("assetserver: cannot parse build manifest %s: entry %q: %w", bm.name, key, err)
+		}
+		chunks[key] = c
+		if c.IsEntry && c.Name != "" {
+			byName[c.Name] = key
+		}
+	}
+	bm.tag = info.tag
+	bm.chunks = chunks
+	bm.byName = byName
+	bm.flat = flat
+	return nil
+}
+
+func cleanManifestName(name string) string {
+	return strings.TrimPrefix(name, "/")
+}
+
+func containsString(s []string, v string) bool {
+	for _, w := range s {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
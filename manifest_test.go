@@ -0,0 +1,128 @@
+package assetserver
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+const viteManifest = `{
+  "_shared.B7PI925R.js": {
+    "file": "assets/shared.B7PI925R.js",
+    "name": "shared",
+    "css": ["assets/shared.ChJ_j-JJ.css"]
+  },
+  "src/main.ts": {
+    "file": "assets/main.4f2a1c.js",
+    "name": "main",
+    "src": "src/main.ts",
+    "isEntry": true,
+    "imports": ["_shared.B7PI925R.js", "src/util.ts"],
+    "dynamicImports": ["src/lazy.ts"],
+    "css": ["assets/main.5UjPuW-k.css"]
+  },
+  "src/util.ts": {
+    "file": "assets/util.9c8d7e.js",
+    "imports": ["_shared.B7PI925R.js"]
+  },
+  "src/lazy.ts": {
+    "file": "assets/lazy.0a1b2c.js",
+    "isDynamicEntry": true
+  },
+  "src/admin.ts": {
+    "file": "assets/admin.77aa88.js",
+    "name": "admin",
+    "isEntry": true
+  }
+}`
+
+func TestEntryVite(t *testing.T) {
+	fsys := fstest.MapFS{
+		".vite/manifest.json": &fstest.MapFile{Data: []byte(viteManifest)},
+	}
+	s := New(fsys, WithBuildManifest("/.vite/manifest.json"))
+	main := &Entry{
+		File:    "assets/main.4f2a1c.js",
+		CSS:     []string{"assets/shared.ChJ_j-JJ.css", "assets/main.5UjPuW-k.css"},
+		Imports: []string{"assets/shared.B7PI925R.js", "assets/util.9c8d7e.js"},
+	}
+	for _, tt := range []struct {
+		name string
+		want *Entry
+	}{
+		{"main", main},
+		{"src/main.ts", main},
+		{"admin", &Entry{File: "assets/admin.77aa88.js"}},
+		{"src/lazy.ts", &Entry{File: "assets/lazy.0a1b2c.js"}},
+		{"shared", nil},
+		{"nope", nil},
+	} {
+		got, err := s.Entry(tt.name)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Entry(%q): got nil error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Entry(%q): %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Entry(%q) = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// The manifest is reread when it changes.
+	fsys[".vite/manifest.json"] = &fstest.MapFile{Data: []byte(`{"src/main.ts": {"file": "assets/main.new.js", "name": "main", "isEntry": true}}`)}
+	got, err := s.Entry("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Entry{File: "assets/main.new.js"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("after change, Entry = %+v; want %+v", got, want)
+	}
+}
+
+func TestEntryWebpack(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json": &fstest.MapFile{Data: []byte(`{
+			"main.js": "/main.3b4c5d.js",
+			"main.css": "/main.6e7f80.css",
+			"vendor.js": "vendor.112233.js"
+		}`)},
+	}
+	s := New(fsys, WithBuildManifest("manifest.json"))
+	for _, tt := range []struct {
+		name string
+		want *Entry
+	}{
+		{"main", &Entry{File: "main.3b4c5d.js", CSS: []string{"main.6e7f80.css"}}},
+		{"main.js", &Entry{File: "main.3b4c5d.js", CSS: []string{"main.6e7f80.css"}}},
+		{"vendor", &Entry{File: "vendor.112233.js"}},
+	} {
+		got, err := s.Entry(tt.name)
+		if err != nil {
+			t.Errorf("Entry(%q): %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Entry(%q) = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEntryErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"bad.json": &fstest.MapFile{Data: []byte(`[1, 2]`)},
+	}
+	for _, s := range []*Server{
+		New(fsys),
+		New(fsys, WithBuildManifest("missing.json")),
+		New(fsys, WithBuildManifest("bad.json")),
+	} {
+		if _, err := s.Entry("main"); err == nil {
+			t.Error("Entry: got nil error")
+		}
+	}
+}
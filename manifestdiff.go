@@ -0,0 +1,87 @@
+package assetserver
+
+import "sort"
+
+// A ChangeKind says how an asset differs between two manifests. See
+// [DiffManifests].
+type ChangeKind int
+
+const (
+	// AssetAdded means that the asset is only in the new manifest.
+	AssetAdded ChangeKind = iota + 1
+	// AssetRemoved means that the asset is only in the old manifest.
+	AssetRemoved
+	// AssetChanged means that the asset's contents differ.
+	AssetChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case AssetAdded:
+		return "added"
+	case AssetRemoved:
+		return "removed"
+	case AssetChanged:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// An AssetChange describes an asset which differs between two manifests.
+type AssetChange struct {
+	Path string
+	Kind ChangeKind
+	// OldSize and NewSize are the asset's sizes in the old and new
+	// manifests. OldSize is 0 for an added asset and NewSize is 0 for a
+	// removed one.
+	OldSize int64
+	NewSize int64
+}
+
+// SizeDelta returns the change in the asset's size.
+func (c AssetChange) SizeDelta() int64 {
+	return c.NewSize - c.OldSize
+}
+
+// DiffManifests compares two manifests of a Server's files, such as those
+// returned by [Server.Integrity] for two builds, and returns the assets which
+// were added, removed, or changed, in order by path. An asset has changed if
+// its SHA-256 hash differs or, if either manifest lacks hashes, if its tag or
+// size does. This is useful for release notes and for CI checks which catch
+// unexpected growth in the size of the assets.
+func DiffManifests(old, new []IntegrityEntry) []AssetChange {
+	oldByPath := make(map[string]IntegrityEntry, len(old))
+	for _, e := range old {
+		oldByPath[e.Path] = e
+	}
+	var changes []AssetChange
+	seen := make(map[string]bool, len(new))
+	for _, e := range new {
+		seen[e.Path] = true
+		o, ok := oldByPath[e.Path]
+		switch {
+		case !ok:
+			changes = append(changes, AssetChange{Path: e.Path, Kind: AssetAdded, NewSize: e.Size})
+		case entryChanged(o, e):
+			changes = append(changes, AssetChange{Path: e.Path, Kind: AssetChanged, OldSize: o.Size, NewSize: e.Size})
+		}
+	}
+	for _, o := range old {
+		if !seen[o.Path] {
+			changes = append(changes, AssetChange{Path: o.Path, Kind: AssetRemoved, OldSize: o.Size})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// entryChanged reports whether the file described by o changed to the one
+// described by e.
+func entryChanged(o, e IntegrityEntry) bool {
+	if o.SHA256 != "" && e.SHA256 != "" {
+		return o.SHA256 != e.SHA256
+	}
+	return o.Tag != e.Tag || o.Size != e.Size
+}
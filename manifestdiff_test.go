@@ -0,0 +1,42 @@
+package assetserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffManifests(t *testing.T) {
+	old := []IntegrityEntry{
+		{Path: "css/style.css", Tag: "aaaaaaaaaa", SHA256: "a1", Size: 100},
+		{Path: "js/app.js", Tag: "bbbbbbbbbb", SHA256: "b1", Size: 1000},
+		{Path: "js/old.js", Tag: "cccccccccc", SHA256: "c1", Size: 50},
+		{Path: "img/logo.png", Tag: "dddddddddd", SHA256: "d1", Size: 500},
+	}
+	new := []IntegrityEntry{
+		{Path: "css/style.css", Tag: "aaaaaaaaaa", SHA256: "a1", Size: 100},
+		{Path: "js/app.js", Tag: "eeeeeeeeee", SHA256: "b2", Size: 1200},
+		{Path: "js/new.js", Tag: "ffffffffff", SHA256: "f1", Size: 70},
+		// Without hashes, the tags and sizes are compared.
+		{Path: "img/logo.png", Tag: "gggggggggg", Size: 500},
+	}
+	want := []AssetChange{
+		{Path: "img/logo.png", Kind: AssetChanged, OldSize: 500, NewSize: 500},
+		{Path: "js/app.js", Kind: AssetChanged, OldSize: 1000, NewSize: 1200},
+		{Path: "js/new.js", Kind: AssetAdded, NewSize: 70},
+		{Path: "js/old.js", Kind: AssetRemoved, OldSize: 50},
+	}
+	got := DiffManifests(old, new)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v; want %+v", got, want)
+	}
+	var delta int64
+	for _, c := range got {
+		delta += c.SizeDelta()
+	}
+	if delta != 220 {
+		t.Errorf("got total size delta %d; want 220", delta)
+	}
+	if got := DiffManifests(old, old); len(got) != 0 {
+		t.Errorf("diff of identical manifests: got %+v", got)
+	}
+}
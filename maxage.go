@@ -0,0 +1,50 @@
+package assetserver
+
+import (
+	"strconv"
+	"time"
+)
+
+// WithImmutableMaxAge sets the max-age of the Cache-Control header sent for
+// tagged names (and other immutable files; see [WithPreHashed] and
+// [WithImmutableDirs]) which match any of the given patterns, or for all
+// files if there are no patterns. The default is one year. For example,
+//
+//	WithImmutableMaxAge(7*24*time.Hour, "experiments/**")
+//
+// lets CDNs and browsers drop experiment bundles after a week while other
+// assets are kept for a year. If WithImmutableMaxAge is given more than once,
+// the first one whose patterns match a file applies to it. The duration is
+// rounded down to a whole number of seconds, and it must be at least one
+// second.
+//
+// See the Patterns section of the package documentation for the pattern
+// syntax.
+func WithImmutableMaxAge(d time.Duration, patterns ...string) Option {
+	if d < time.Second {
+		panic("assetserver: WithImmutableMaxAge called with a duration under one second")
+	}
+	ma := immutableMaxAge{
+		patterns:     compilePatterns(patterns),
+		cacheControl: "public, max-age=" + strconv.FormatInt(int64(d/time.Second), 10) + ", immutable",
+	}
+	return func(s *Server) {
+		s.immutableMaxAges = append(s.immutableMaxAges, ma)
+	}
+}
+
+type immutableMaxAge struct {
+	patterns     []string // if empty, match all files
+	cacheControl string
+}
+
+// immutableCacheControl returns the Cache-Control header for an immutable
+// response for the named file.
+func (s *Server) immutableCacheControl(name string) string {
+	for _, ma := range s.immutableMaxAges {
+		if len(ma.patterns) == 0 || matchAny(ma.patterns, name) {
+			return ma.cacheControl
+		}
+	}
+	return "public, max-age=31536000, immutable"
+}
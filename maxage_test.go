@@ -0,0 +1,57 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestImmutableMaxAge(t *testing.T) {
+	fsys := fstest.MapFS{
+		"experiments/a.js": &fstest.MapFile{Data: []byte("a")},
+		"vendor/lib.js":    &fstest.MapFile{Data: []byte("lib")},
+		"app.js":           &fstest.MapFile{Data: []byte("app")},
+		"assets/x.1234.js": &fstest.MapFile{Data: []byte("x")},
+	}
+	s := New(fsys,
+		WithImmutableMaxAge(7*24*time.Hour, "experiments/**"),
+		WithImmutableMaxAge(90*time.Second+time.Millisecond, "assets/**", "experiments/**"),
+		WithPreHashed("assets/**"),
+	)
+	for _, tt := range []struct {
+		name   string
+		tagged bool
+		want   string
+	}{
+		{"experiments/a.js", true, "public, max-age=604800, immutable"},
+		{"experiments/a.js", false, "public, max-age=60"},
+		{"vendor/lib.js", true, "public, max-age=31536000, immutable"},
+		{"app.js", true, "public, max-age=31536000, immutable"},
+		{"assets/x.1234.js", false, "public, max-age=90, immutable"},
+	} {
+		path := tt.name
+		if tt.tagged {
+			var err error
+			if path, err = s.Tag(tt.name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+path, nil))
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("GET %s: got Cache-Control %q; want %q", path, got, tt.want)
+		}
+	}
+
+	all := New(fsys, WithImmutableMaxAge(time.Hour))
+	tagged, err := all.Tag("app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	all.ServeHTTP(w, httptest.NewRequest("GET", "/"+tagged, nil))
+	if got, want := w.Header().Get("Cache-Control"), "public, max-age=3600, immutable"; got != want {
+		t.Errorf("GET %s: got Cache-Control %q; want %q", tagged, got, want)
+	}
+}
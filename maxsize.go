@@ -0,0 +1,66 @@
+package assetserver
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+)
+
+// WithMaxFileSize makes the Server refuse to serve files larger than n bytes,
+// rather than hashing and sending them. This guards against a huge file (such
+// as a build artifact or database dump) accidentally placed in the asset
+// directory tying up the disk and network.
+//
+// Requests for a file which is too large receive the given status code,
+// which must be 404 Not Found (the file is treated as if it didn't exist) or
+// 500 Internal Server Error (the file is treated as unreadable, so the error
+// is passed to the [WithErrorHook] function and [Server.Preload] fails). In
+// either case, the Server logs a warning.
+func WithMaxFileSize(n int64, code int) Option {
+	if n <= 0 {
+		panic("assetserver: WithMaxFileSize called with n <= 0")
+	}
+	if code != http.StatusNotFound && code != http.StatusInternalServerError {
+		panic(fmt.Sprintf("assetserver: WithMaxFileSize called with unsupported status code %d", code))
+	}
+	return func(s *Server) {
+		s.maxFileSize = n
+		s.maxFileSizeNotFound = code == http.StatusNotFound
+	}
+}
+
+// A fileTooLargeError reports a file which is larger than the limit set by
+// WithMaxFileSize.
+type fileTooLargeError struct {
+	name      string
+	size, max int64
+	notFound  bool
+}
+
+func (e *fileTooLargeError) Error() string {
+	return fmt.Sprintf("assetserver: %s is too large to serve (%d bytes; the maximum is %d)", e.name, e.size, e.max)
+}
+
+func (e *fileTooLargeError) Unwrap() error {
+	if e.notFound {
+		return fs.ErrNotExist
+	}
+	return nil
+}
+
+// checkSize returns an error if a file of the given size is too large to
+// serve.
+func (s *Server) checkSize(ctx context.Context, name string, size int64) error {
+	if !s.tooLarge(size) {
+		return nil
+	}
+	s.log(ctx, slog.LevelWarn, "file is too large to serve", "name", name, "size", size, "max", s.maxFileSize)
+	return &fileTooLargeError{name: name, size: size, max: s.maxFileSize, notFound: s.maxFileSizeNotFound}
+}
+
+// tooLarge reports whether a file of the given size is too large to serve.
+func (s *Server) tooLarge(size int64) bool {
+	return s.maxFileSize > 0 && size > s.maxFileSize
+}
@@ -0,0 +1,55 @@
+package assetserver
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMaxFileSize(t *testing.T) {
+	fsys := fstest.MapFS{
+		"small.txt": &fstest.MapFile{Data: []byte("small")},
+		"big.bin":   &fstest.MapFile{Data: []byte(strings.Repeat("x", 100))},
+	}
+	for _, code := range []int{404, 500} {
+		var hookCalls int
+		for _, streaming := range []bool{false, true} {
+			opts := []Option{
+				WithMaxFileSize(10, code),
+				WithErrorHook(func(*http.Request, string, error) { hookCalls++ }),
+			}
+			if streaming {
+				opts = append(opts, WithStreamingHash())
+			}
+			s := New(fsys, opts...)
+			for _, tt := range []struct {
+				path string
+				code int
+			}{
+				{"/small.txt", 200},
+				{"/big.bin", code},
+			} {
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+				if w.Code != tt.code {
+					t.Errorf("code %d, streaming=%t: GET %s: got status %d; want %d", code, streaming, tt.path, w.Code, tt.code)
+				}
+			}
+			_, err := s.Tag("big.bin")
+			var tooLarge *fileTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Errorf("code %d: Tag(big.bin): got error %v; want fileTooLargeError", code, err)
+			}
+			if got, want := errors.Is(err, fs.ErrNotExist), code == 404; got != want {
+				t.Errorf("code %d: Tag(big.bin): errors.Is(err, fs.ErrNotExist) = %t; want %t", code, got, want)
+			}
+		}
+		if want := map[int]int{404: 0, 500: 2}[code]; hookCalls != want {
+			t.Errorf("code %d: got %d error hook calls; want %d", code, hookCalls, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package assetserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithMethods sets the request methods that the Server accepts. By default,
+// a Server accepts GET and HEAD. The methods may include GET, HEAD, and
+// OPTIONS, and must include GET. For example, a Server behind a proxy which
+// mishandles HEAD responses might use
+//
+//	WithMethods("GET")
+//
+// and a Server which should answer CORS preflight requests itself might use
+//
+//	WithMethods("GET", "HEAD", "OPTIONS")
+//
+// The Server responds to an OPTIONS request with 204 No Content and an Allow
+// header listing the methods. It responds to a request with any other
+// method with 405 Method Not Allowed (see [WithMethodNotAllowedHandler]).
+func WithMethods(methods ...string) Option {
+	var hasGet bool
+	for _, m := range methods {
+		switch m {
+		case "GET":
+			hasGet = true
+		case "HEAD", "OPTIONS":
+		default:
+			panic(fmt.Sprintf("assetserver: WithMethods called with unsupported method %q", m))
+		}
+	}
+	if !hasGet {
+		panic("assetserver: WithMethods called without GET")
+	}
+	allow := strings.Join(methods, ",")
+	return func(s *Server) {
+		s.methods = methods
+		s.allow = allow
+	}
+}
+
+// WithMethodNotAllowedHandler makes the Server use h to respond to requests
+// whose methods it doesn't accept, instead of sending a plain-text 405
+// Method Not Allowed error. The Allow header listing the accepted methods is
+// set before h is called, so h may keep or change it.
+func WithMethodNotAllowedHandler(h http.Handler) Option {
+	return func(s *Server) {
+		s.methodNotAllowed = h
+	}
+}
+
+// acceptsMethod reports whether the Server accepts the request method.
+func (s *Server) acceptsMethod(method string) bool {
+	if s.methods == nil {
+		return method == "GET" || method == "HEAD"
+	}
+	for _, m := range s.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allowHeader returns the value of the Allow header.
+func (s *Server) allowHeader() string {
+	if s.allow == "" {
+		return "GET,HEAD"
+	}
+	return s.allow
+}
+
+func (s *Server) serveMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", s.allowHeader())
+	if s.methodNotAllowed != nil {
+		s.methodNotAllowed.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+}
+
+func (s *Server) serveOptions(w http.ResponseWriter) {
+	w.Header().Set("Allow", s.allowHeader())
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,67 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMethods(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a")},
+	}
+	custom405 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error":"method not allowed"}`))
+	})
+	for _, tt := range []struct {
+		opts   []Option
+		method string
+		code   int
+		allow  string
+		body   string
+	}{
+		{nil, "GET", 200, "", "a"},
+		{nil, "HEAD", 200, "", ""},
+		{nil, "OPTIONS", 405, "GET,HEAD", "405 Method Not Allowed\n"},
+		{nil, "POST", 405, "GET,HEAD", "405 Method Not Allowed\n"},
+		{[]Option{WithMethods("GET")}, "GET", 200, "", "a"},
+		{[]Option{WithMethods("GET")}, "HEAD", 405, "GET", "405 Method Not Allowed\n"},
+		{[]Option{WithMethods("GET", "HEAD", "OPTIONS")}, "OPTIONS", 204, "GET,HEAD,OPTIONS", ""},
+		{[]Option{WithMethods("GET", "HEAD", "OPTIONS")}, "PUT", 405, "GET,HEAD,OPTIONS", "405 Method Not Allowed\n"},
+		{[]Option{WithMethodNotAllowedHandler(custom405)}, "POST", 405, "GET,HEAD", `{"error":"method not allowed"}`},
+	} {
+		s := New(fsys, tt.opts...)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, "/a.css", nil))
+		if w.Code != tt.code {
+			t.Errorf("%s (%d options): got status %d; want %d", tt.method, len(tt.opts), w.Code, tt.code)
+			continue
+		}
+		if got := w.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s (%d options): got Allow %q; want %q", tt.method, len(tt.opts), got, tt.allow)
+		}
+		if got := w.Body.String(); got != tt.body {
+			t.Errorf("%s (%d options): got body %q; want %q", tt.method, len(tt.opts), got, tt.body)
+		}
+	}
+}
+
+func TestWithMethodsPanics(t *testing.T) {
+	for _, methods := range [][]string{
+		{"HEAD"},
+		{"GET", "POST"},
+		{},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithMethods(%q) didn't panic", methods)
+				}
+			}()
+			WithMethods(methods...)
+		}()
+	}
+}
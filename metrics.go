@@ -0,0 +1,153 @@
+package assetserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MetricsHooks contains functions that a Server calls to report events for
+// the purpose of collecting metrics. Any of the functions may be nil. They are
+// called synchronously from the goroutines doing the work, so they must be
+// fast and safe for concurrent use.
+//
+// The assetservermetrics package uses MetricsHooks to provide Prometheus
+// metrics.
+type MetricsHooks struct {
+	// Request is called after the Server responds to an HTTP request with
+	// the response status code and the number of body bytes written.
+	Request func(status int, bytes int64)
+	// CacheLookup is called when the Server looks up the information
+	// about a file (such as its tag) to serve a request or to satisfy a
+	// call to Tag. The hit parameter reports whether up-to-date
+	// information was found in the cache; if not, the file is hashed.
+	CacheLookup func(hit bool)
+	// HashStart is called when the Server begins to hash a file.
+	HashStart func()
+	// HashDone is called when the Server finishes hashing a file (which
+	// it began after a call to HashStart). The duration is the time
+	// taken to hash the file and err is any error that occurred.
+	HashDone func(d time.Duration, err error)
+}
+
+// WithMetricsHooks registers functions to be called by the Server to report
+// events for collecting metrics. WithMetricsHooks may be given more than once.
+func WithMetricsHooks(hooks MetricsHooks) Option {
+	return func(s *Server) {
+		s.metrics = append(s.metrics, hooks)
+	}
+}
+
+func (s *Server) reportCacheLookup(ctx context.Context, hit bool) {
+	s.setTraceAttribute(ctx, "assetserver.cache_hit", hit)
+	s.timing(ctx).cacheLookup(hit)
+	for _, m := range s.metrics {
+		if m.CacheLookup != nil {
+			m.CacheLookup(hit)
+		}
+	}
+}
+
+// reportHash reports the start of a hash operation for the named file. The
+// caller must call the returned function with a pointer to the error result
+// of the operation when the hash is complete.
+func (s *Server) reportHash(ctx context.Context, name string) func(*error) {
+	if len(s.metrics) == 0 && s.logger == nil && len(s.traces) == 0 {
+		return func(*error) {}
+	}
+	endTrace := s.startHashTrace(ctx, name)
+	for _, m := range s.metrics {
+		if m.HashStart != nil {
+			m.HashStart()
+		}
+	}
+	start := time.Now()
+	return func(err *error) {
+		d := time.Since(start)
+		for _, m := range s.metrics {
+			if m.HashDone != nil {
+				m.HashDone(d, *err)
+			}
+		}
+		s.logHash(ctx, name, d, *err)
+		endTrace(*err)
+	}
+}
+
+func (s *Server) reportRequest(rec *responseRecorder) {
+	for _, m := range s.metrics {
+		if m.Request != nil {
+			m.Request(rec.code(), rec.bytes)
+		}
+	}
+}
+
+// A responseRecorder wraps a ResponseWriter to record the status code and the
+// number of bytes written, along with the requested file.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+
+	name string // the requested file name, once known
+	tag  string // the requested tag, if any
+
+	// onStatus, if non-nil, is called when the status code becomes known,
+	// before the header is written.
+	onStatus func(status int)
+}
+
+func (w *responseRecorder) code() int {
+	if w.status == 0 {
+		// Nothing was written; net/http will send a 200.
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// setStatus records the status code of the response if it isn't known yet.
+func (w *responseRecorder) setStatus(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if w.onStatus != nil {
+		w.onStatus(status)
+	}
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if status >= 200 {
+		w.setStatus(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.setStatus(http.StatusOK)
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom passes through to the underlying ResponseWriter's ReadFrom method,
+// if it has one, so that net/http can still use sendfile.
+func (w *responseRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.setStatus(http.StatusOK)
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter for the benefit of
+// http.ResponseController.
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,113 @@
+package assetserver
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testMetrics struct {
+	mu       sync.Mutex
+	statuses []int
+	bytes    int64
+	hits     int
+	misses   int
+	hashes   int
+	inFlight int
+}
+
+func (m *testMetrics) hooks() MetricsHooks {
+	return MetricsHooks{
+		Request: func(status int, bytes int64) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.statuses = append(m.statuses, status)
+			m.bytes += bytes
+		},
+		CacheLookup: func(hit bool) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if hit {
+				m.hits++
+			} else {
+				m.misses++
+			}
+		},
+		HashStart: func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.inFlight++
+		},
+		HashDone: func(d time.Duration, err error) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.inFlight--
+			m.hashes++
+		},
+	}
+}
+
+func TestMetricsHooks(t *testing.T) {
+	var m testMetrics
+	s := New(os.DirFS("testdata/assets"), WithMetricsHooks(m.hooks()))
+	for _, tt := range []struct {
+		target string
+		inm    string
+	}{
+		{"/a.js", ""},
+		{"/a.js", ""},
+		{"/a.js", `"sI22qapGJ0"`},
+		{"/d/style.css", ""},
+		{"/nope.js", ""},
+	} {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.inm != "" {
+			req.Header.Set("If-None-Match", tt.inm)
+		}
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if _, err := s.Tag("b.min.js"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Tag("b.min.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(m.statuses, []int{200, 200, 304, 200, 404}); diff != "" {
+		t.Errorf("wrong statuses (-got, +want):\n%s", diff)
+	}
+	wantBytes := int64(len("ajs\n")*2 + len("style\n") + len("404 page not found\n"))
+	if m.bytes != wantBytes {
+		t.Errorf("got %d bytes; want %d", m.bytes, wantBytes)
+	}
+	if m.hits != 3 || m.misses != 3 {
+		t.Errorf("got %d hits and %d misses; want 3 and 3", m.hits, m.misses)
+	}
+	if m.hashes != 3 || m.inFlight != 0 {
+		t.Errorf("got %d hashes (%d in flight); want 3 (0 in flight)", m.hashes, m.inFlight)
+	}
+}
+
+// Check that wrapping the ResponseWriter to collect metrics doesn't prevent
+// the use of sendfile.
+func TestMetricsHooksReadFrom(t *testing.T) {
+	var m testMetrics
+	s := New(os.DirFS("testdata/assets"), WithMetricsHooks(m.hooks()))
+	w := &readFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.js", nil))
+	lr, ok := w.src.(*io.LimitedReader)
+	if !ok {
+		t.Fatalf("ReadFrom called with %T; want *io.LimitedReader", w.src)
+	}
+	if _, ok := lr.R.(*os.File); !ok {
+		t.Fatalf("ReadFrom called with a LimitedReader wrapping %T; want *os.File", lr.R)
+	}
+	if m.bytes != int64(len("ajs\n")) {
+		t.Fatalf("got %d bytes; want %d", m.bytes, len("ajs\n"))
+	}
+}
@@ -0,0 +1,52 @@
+package assetserver
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+)
+
+// A Minifier minifies the contents of files of a given media type (such as
+// "text/css"). The *minify.M type from github.com/tdewolff/minify implements
+// Minifier, and the assetserverminify package provides a ready-made one.
+type Minifier interface {
+	Minify(mediaType string, w io.Writer, r io.Reader) error
+}
+
+// WithMinifier makes the Server minify the files matching any of the given
+// patterns (or, if there are no patterns, all .js, .mjs, .css, .html, .svg,
+// and .json files) using m. The media type given to m is derived from each
+// file's extension. Minification is a transformation (see [WithTransform]),
+// so each version of a file is only minified once and the tag reflects the
+// minified contents.
+func WithMinifier(m Minifier, patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = []string{
+			"**/*.js", "**/*.mjs", "**/*.css", "**/*.html", "**/*.svg", "**/*.json",
+		}
+	}
+	return WithTransform(minifyTransformer{m}, patterns...)
+}
+
+type minifyTransformer struct {
+	m Minifier
+}
+
+func (mt minifyTransformer) Transform(name string, in []byte) ([]byte, error) {
+	mediaType, _, _ := strings.Cut(typeByExtension(path.Ext(name)), ";")
+	switch path.Ext(name) {
+	case ".js", ".mjs":
+		// Not all systems agree on the type for JavaScript.
+		mediaType = "text/javascript"
+	}
+	if mediaType == "" {
+		return in, nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(in))
+	if err := mt.m.Minify(mediaType, &buf, bytes.NewReader(in)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
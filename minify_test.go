@@ -0,0 +1,66 @@
+package assetserver
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// spaceMinifier removes spaces and records the media types it was given.
+type spaceMinifier struct {
+	mediaTypes []string
+}
+
+func (m *spaceMinifier) Minify(mediaType string, w io.Writer, r io.Reader) error {
+	m.mediaTypes = append(m.mediaTypes, mediaType)
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte("invalid")) {
+		return errors.New("syntax error")
+	}
+	_, err = w.Write(bytes.ReplaceAll(b, []byte(" "), nil))
+	return err
+}
+
+func TestMinifier(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":    &fstest.MapFile{Data: []byte("var x = 1;")},
+		"app.css":   &fstest.MapFile{Data: []byte("a { b: c }")},
+		"page.html": &fstest.MapFile{Data: []byte("<p> hi </p>")},
+		"bad.css":   &fstest.MapFile{Data: []byte("invalid css")},
+		"a.txt":     &fstest.MapFile{Data: []byte("a b c")},
+	}
+	m := new(spaceMinifier)
+	s := New(fsys, WithMinifier(m))
+	for _, tt := range []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/app.js", 200, "varx=1;"},
+		{"/app.css", 200, "a{b:c}"},
+		{"/page.html", 200, "<p>hi</p>"},
+		{"/a.txt", 200, "a b c"},
+		{"/bad.css", 500, "500 Internal Server Error\n"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.want {
+			t.Errorf("GET %s: got %d %q; want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.want)
+		}
+	}
+	want := []string{"text/javascript", "text/css", "text/html", "text/css"}
+	if len(m.mediaTypes) != len(want) {
+		t.Fatalf("minifier got media types %q; want %q", m.mediaTypes, want)
+	}
+	for i := range want {
+		if m.mediaTypes[i] != want[i] {
+			t.Fatalf("minifier got media types %q; want %q", m.mediaTypes, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package objectfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPStore is a Store which reads objects over plain HTTP. This works with
+// any object store that serves objects at predictable URLs, such as a publicly
+// readable S3 or GCS bucket. (Stores which require authenticated requests can
+// be used by supplying a Client whose Transport signs requests.)
+//
+// The metadata of an object is taken from the Content-Length, Last-Modified,
+// and ETag headers of a HEAD response.
+type HTTPStore struct {
+	// BaseURL is the URL that object names are relative to, such as
+	// "https://storage.googleapis.com/my-bucket/assets/".
+	BaseURL string
+	// Client is used to make requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+func (s *HTTPStore) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *HTTPStore) do(ctx context.Context, method, name string) (*http.Response, error) {
+	elems := strings.Split(name, "/")
+	for i, elem := range elems {
+		elems[i] = url.PathEscape(elem)
+	}
+	u := strings.TrimSuffix(s.BaseURL, "/") + "/" + strings.Join(elems, "/")
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fs.ErrNotExist
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("objectfs: %s %s: unexpected status %s", method, u, resp.Status)
+	}
+}
+
+// Stat implements Store.
+func (s *HTTPStore) Stat(ctx context.Context, name string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, "HEAD", name)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	info := ObjectInfo{
+		Size:    resp.ContentLength,
+		Version: resp.Header.Get("ETag"),
+	}
+	if info.Size < 0 {
+		return ObjectInfo{}, fmt.Errorf("objectfs: HEAD %s: missing Content-Length", name)
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		t, err := http.ParseTime(lm)
+		if err != nil {
+			return ObjectInfo{}, fmt.Errorf("objectfs: HEAD %s: bad Last-Modified header: %s", name, err)
+		}
+		info.ModTime = t
+	}
+	return info, nil
+}
+
+// Get implements Store.
+func (s *HTTPStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, "GET", name)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
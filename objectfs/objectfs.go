@@ -0,0 +1,369 @@
+// Package objectfs provides a file system backed by a remote object store
+// (such as S3 or GCS), with local caching of object metadata and contents.
+//
+// It is intended for serving assets out of an object store using an
+// assetserver.Server:
+//
+//	store := &objectfs.HTTPStore{BaseURL: "https://storage.googleapis.com/my-bucket/assets/"}
+//	assets := assetserver.New(objectfs.New(store, objectfs.WithCacheDir(dir)))
+//
+// Object stores don't have real directories, so an FS doesn't either: opening
+// any name other than "." (which is always an empty directory) opens the
+// object with that name.
+package objectfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// A Store is a remote object store.
+type Store interface {
+	// Stat returns information about the named object. If the object
+	// does not exist, the error must wrap fs.ErrNotExist.
+	Stat(ctx context.Context, name string) (ObjectInfo, error)
+	// Get returns the contents of the named object. If the object does
+	// not exist, the error must wrap fs.ErrNotExist.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// ObjectInfo describes an object in a Store.
+type ObjectInfo struct {
+	Size    int64
+	ModTime time.Time
+	// Version identifies the contents of the object. It is typically an
+	// ETag (S3) or generation number (GCS). If Version is empty, the size
+	// and modification time are used to identify the contents instead.
+	Version string
+}
+
+func (info ObjectInfo) version() string {
+	if info.Version != "" {
+		return info.Version
+	}
+	return strconv.FormatInt(info.Size, 10) + "-" + strconv.FormatInt(info.ModTime.UnixNano(), 10)
+}
+
+// An FS is an fs.FS which reads objects from a Store.
+//
+// An FS caches the metadata of each object it opens for a limited time (see
+// [WithMetadataTTL]) and caches the contents of each version of an object
+// until a newer version is seen. This means that when used with an
+// assetserver.Server, whose tags are hashes of the contents, each distinct
+// asset tag corresponds to a single download from the store.
+type FS struct {
+	store    Store
+	ttl      time.Duration
+	timeout  time.Duration
+	cacheDir string // if empty, contents are cached in memory
+
+	mu      sync.Mutex
+	objects map[string]*object
+
+	downloads singleflight.Group
+}
+
+type object struct {
+	info    ObjectInfo
+	statErr error // if non-nil, the object is missing (or couldn't be read)
+	statted time.Time
+
+	version string // version of data/path, if cached
+	data    []byte // if cached in memory
+	path    string // if cached on disk
+}
+
+// An Option configures an FS.
+type Option func(*FS)
+
+// WithMetadataTTL sets how long an FS trusts the metadata it has fetched from
+// the store for an object (including the fact that an object does not exist)
+// before fetching it again. The default is one minute.
+func WithMetadataTTL(d time.Duration) Option {
+	return func(fsys *FS) {
+		fsys.ttl = d
+	}
+}
+
+// WithCacheDir makes the FS cache object contents in files inside dir rather
+// than in memory. The directory must exist.
+func WithCacheDir(dir string) Option {
+	return func(fsys *FS) {
+		fsys.cacheDir = dir
+	}
+}
+
+// WithTimeout sets a time limit for each operation on the store. By default,
+// there is no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(fsys *FS) {
+		fsys.timeout = d
+	}
+}
+
+// New creates an FS which reads from store.
+func New(store Store, opts ...Option) *FS {
+	fsys := &FS{
+		store:   store,
+		ttl:     time.Minute,
+		objects: make(map[string]*object),
+	}
+	for _, opt := range opts {
+		opt(fsys)
+	}
+	return fsys
+}
+
+func (fsys *FS) context() (context.Context, context.CancelFunc) {
+	if fsys.timeout > 0 {
+		return context.WithTimeout(context.Background(), fsys.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Stat implements fs.StatFS.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return rootInfo{}, nil
+	}
+	info, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fileInfo{name: name, info: info}, nil
+}
+
+// stat returns the (possibly cached) metadata for the named object.
+func (fsys *FS) stat(name string) (ObjectInfo, error) {
+	fsys.mu.Lock()
+	obj, ok := fsys.objects[name]
+	if ok && time.Since(obj.statted) < fsys.ttl {
+		fsys.mu.Unlock()
+		return obj.info, obj.statErr
+	}
+	fsys.mu.Unlock()
+
+	ctx, cancel := fsys.context()
+	defer cancel()
+	info, err := fsys.store.Stat(ctx, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// Don't cache transient errors.
+		return ObjectInfo{}, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	obj, ok = fsys.objects[name]
+	if !ok {
+		obj = new(object)
+		fsys.objects[name] = obj
+	}
+	obj.info = info
+	obj.statErr = err
+	obj.statted = time.Now()
+	if err != nil {
+		fsys.dropContents(obj)
+	}
+	return info, err
+}
+
+// Open implements fs.FS.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return rootDir{}, nil
+	}
+	info, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	f, err := fsys.openCached(name, info)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f, nil
+}
+
+// openCached opens the contents of the given version of the named object,
+// downloading them if they are not already cached.
+func (fsys *FS) openCached(name string, info ObjectInfo) (fs.File, error) {
+	version := info.version()
+	for attempt := 0; attempt < 2; attempt++ {
+		fsys.mu.Lock()
+		obj := fsys.objects[name]
+		var data []byte
+		var diskPath string
+		if obj != nil && obj.version == version {
+			data, diskPath = obj.data, obj.path
+		}
+		fsys.mu.Unlock()
+
+		fi := fileInfo{name: name, info: info}
+		if data != nil {
+			return &memFile{Reader: bytes.NewReader(data), fi: fi}, nil
+		}
+		if diskPath != "" {
+			f, err := os.Open(diskPath)
+			if err == nil {
+				return &diskFile{File: f, fi: fi}, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			// Someone removed the file from the cache directory.
+			// Download it again.
+		}
+		_, err, _ := fsys.downloads.Do(name+"\x00"+version, func() (any, error) {
+			return nil, fsys.download(name, version)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("object contents changed while downloading")
+}
+
+func (fsys *FS) download(name, version string) error {
+	ctx, cancel := fsys.context()
+	defer cancel()
+	rc, err := fsys.store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var data []byte
+	var diskPath string
+	if fsys.cacheDir == "" {
+		data, err = io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			data = []byte{}
+		}
+	} else {
+		sum := sha256.Sum256([]byte(name + "\x00" + version))
+		diskPath = filepath.Join(fsys.cacheDir, hex.EncodeToString(sum[:]))
+		if err := writeFileAtomic(diskPath, rc); err != nil {
+			return err
+		}
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	obj, ok := fsys.objects[name]
+	if !ok {
+		obj = new(object)
+		fsys.objects[name] = obj
+	}
+	fsys.dropContents(obj)
+	obj.version = version
+	obj.data = data
+	obj.path = diskPath
+	return nil
+}
+
+// dropContents forgets the cached contents of obj.
+// The caller must hold fsys.mu.
+func (fsys *FS) dropContents(obj *object) {
+	if obj.path != "" {
+		// Open files remain readable on Unix systems.
+		os.Remove(obj.path)
+	}
+	obj.version = ""
+	obj.data = nil
+	obj.path = ""
+}
+
+func writeFileAtomic(name string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("objectfs: error adding file to cache: %w", err)
+	}
+	return nil
+}
+
+type fileInfo struct {
+	name string
+	info ObjectInfo
+}
+
+func (fi fileInfo) Name() string       { return path.Base(fi.name) }
+func (fi fileInfo) Size() int64        { return fi.info.Size }
+func (fi fileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi fileInfo) ModTime() time.Time { return fi.info.ModTime }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() any           { return fi.info }
+
+type memFile struct {
+	*bytes.Reader
+	fi fileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *memFile) Close() error               { return nil }
+
+type diskFile struct {
+	*os.File
+	fi fileInfo
+}
+
+func (f *diskFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+
+type rootInfo struct{}
+
+func (rootInfo) Name() string       { return "." }
+func (rootInfo) Size() int64        { return 0 }
+func (rootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (rootInfo) ModTime() time.Time { return time.Time{} }
+func (rootInfo) IsDir() bool        { return true }
+func (rootInfo) Sys() any           { return nil }
+
+type rootDir struct{}
+
+func (rootDir) Stat() (fs.FileInfo, error) { return rootInfo{}, nil }
+func (rootDir) Close() error               { return nil }
+
+func (rootDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (rootDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n > 0 {
+		return nil, io.EOF
+	}
+	return nil, nil
+}
@@ -0,0 +1,137 @@
+package objectfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cespare/assetserver"
+	"github.com/cespare/webtest"
+)
+
+func TestFS(t *testing.T) {
+	for _, cacheDir := range []bool{false, true} {
+		store := newFakeStore()
+		store.put("a.txt", "a0", "v0")
+		var opts []Option
+		if cacheDir {
+			opts = append(opts, WithCacheDir(t.TempDir()))
+		}
+		fsys := New(store, append(opts, WithMetadataTTL(time.Hour))...)
+
+		checkRead(t, fsys, "a.txt", "a0")
+		checkRead(t, fsys, "a.txt", "a0")
+		if store.stats != 1 || store.gets != 1 {
+			t.Fatalf("cacheDir=%t: after two reads, got %d stats and %d gets; want 1 and 1",
+				cacheDir, store.stats, store.gets)
+		}
+		if _, err := fsys.Open("b.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("cacheDir=%t: Open(b.txt): got error %v; want fs.ErrNotExist", cacheDir, err)
+		}
+
+		// A new version isn't seen until the metadata expires.
+		store.put("a.txt", "a1", "v1")
+		checkRead(t, fsys, "a.txt", "a0")
+		fsys.ttl = 0
+		checkRead(t, fsys, "a.txt", "a1")
+		if store.gets != 2 {
+			t.Fatalf("cacheDir=%t: got %d gets; want 2", cacheDir, store.gets)
+		}
+	}
+}
+
+func checkRead(t *testing.T, fsys fs.FS, name, want string) {
+	t.Helper()
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Fatalf("ReadFile(%s): got %q; want %q", name, b, want)
+	}
+}
+
+func TestHTTPStore(t *testing.T) {
+	// Act like an object store: serve only files (not directories).
+	bucket := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.Join("../testdata/assets", filepath.FromSlash(strings.TrimPrefix(r.URL.Path, "/bucket/")))
+		if fi, err := os.Stat(name); err != nil || !fi.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, name)
+	})
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+	store := &HTTPStore{BaseURL: server.URL + "/bucket/"}
+
+	info, err := store.Stat(context.Background(), "d/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len("style\n")) || info.ModTime.IsZero() {
+		t.Fatalf("Stat(d/style.css): got %+v", info)
+	}
+	if _, err := store.Stat(context.Background(), "nope.css"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat(nope.css): got error %v; want fs.ErrNotExist", err)
+	}
+
+	s := assetserver.New(New(store))
+	webtest.TestHandler(t, "../testdata/servehttp.txt", s)
+}
+
+type fakeStore struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	stats   int
+	gets    int
+}
+
+type fakeObject struct {
+	data string
+	info ObjectInfo
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{objects: make(map[string]fakeObject)}
+}
+
+func (s *fakeStore) put(name, data, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[name] = fakeObject{
+		data: data,
+		info: ObjectInfo{Size: int64(len(data)), ModTime: time.Now(), Version: version},
+	}
+}
+
+func (s *fakeStore) Stat(ctx context.Context, name string) (ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats++
+	obj, ok := s.objects[name]
+	if !ok {
+		return ObjectInfo{}, fs.ErrNotExist
+	}
+	return obj.info, nil
+}
+
+func (s *fakeStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets++
+	obj, ok := s.objects[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(obj.data)), nil
+}
@@ -0,0 +1,71 @@
+package assetserver
+
+import (
+	"encoding/binary"
+	"hash"
+	"io"
+)
+
+type partialHash struct {
+	n        int64
+	patterns []string // if empty, applies to all files
+}
+
+// WithPartialHashing changes how the Server computes tags for very large files
+// (such as videos or datasets), for which hashing the full contents each time
+// the file changes is too expensive.
+//
+// For a file larger than 2n bytes which matches at least one of the patterns
+// (or any file larger than 2n bytes, if no patterns are given), the tag is
+// computed from the file's size, its modification time, and the first and last
+// n bytes of its contents rather than from the full contents. See the package
+// documentation for the pattern syntax. WithPartialHashing panics if n <= 0 or
+// if a pattern is malformed.
+//
+// This makes tagging much cheaper, but it has two important downsides:
+//
+//   - A file's tag changes whenever its modification time changes, even if the
+//     contents are the same. For example, if a deploy process copies the files
+//     with fresh modification times, clients will download them again.
+//   - Different servers with identical copies of a file may compute different
+//     tags for it if the copies have different modification times, so
+//     partial hashing may not be appropriate for a file served by several
+//     servers behind a load balancer.
+//
+// (Note that the Server already relies on the size and modification time to
+// decide whether a file has changed and needs to be hashed again.)
+func WithPartialHashing(n int64, patterns ...string) Option {
+	if n <= 0 {
+		panic("assetserver: WithPartialHashing called with n <= 0")
+	}
+	ph := &partialHash{
+		n:        n,
+		patterns: compilePatterns(patterns),
+	}
+	return func(s *Server) {
+		s.partialHash = ph
+	}
+}
+
+func (ph *partialHash) applies(name string, size int64) bool {
+	if size <= 2*ph.n {
+		return false
+	}
+	return len(ph.patterns) == 0 || matchAny(ph.patterns, name)
+}
+
+// writeHeader writes the size and mtime of the file into h.
+func (ph *partialHash) writeHeader(h hash.Hash, fi *fileInfo) {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], uint64(fi.size))
+	binary.LittleEndian.PutUint64(b[8:], uint64(fi.mtime))
+	h.Write(b[:])
+}
+
+// hashTail writes the last n bytes of f into the hash.
+func (ph *partialHash) hashTail(hs *hashState, f io.ReadSeeker) error {
+	if _, err := f.Seek(-ph.n, io.SeekEnd); err != nil {
+		return err
+	}
+	return hs.copy(f)
+}
@@ -0,0 +1,64 @@
+package assetserver
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPartialHashing(t *testing.T) {
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	content := bytes.Repeat([]byte("x"), 100)
+	fsys := fstest.MapFS{
+		"big/f.txt": &fstest.MapFile{Data: content, ModTime: mtime},
+		"small.txt": &fstest.MapFile{Data: content[:20], ModTime: mtime},
+		"other.txt": &fstest.MapFile{Data: content, ModTime: mtime},
+	}
+	tag := func(name string) string {
+		t.Helper()
+		// Use a fresh Server each time to avoid caching.
+		s := New(fsys, WithPartialHashing(10, "big/**"))
+		tagged, err := s.Tag(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := removeTag(tagged)
+		return got
+	}
+
+	// Files which are small or don't match the pattern are fully hashed.
+	if got, want := tag("small.txt"), hashTag(string(content[:20])); got != want {
+		t.Errorf("small file: got tag %q; want %q", got, want)
+	}
+	if got, want := tag("other.txt"), hashTag(string(content)); got != want {
+		t.Errorf("non-matching file: got tag %q; want %q", got, want)
+	}
+
+	orig := tag("big/f.txt")
+	if orig == hashTag(string(content)) {
+		t.Fatal("large file was fully hashed")
+	}
+
+	// Changes in the middle of the file are not detected.
+	middle := bytes.Clone(content)
+	middle[50] = 'y'
+	fsys["big/f.txt"].Data = middle
+	if got := tag("big/f.txt"); got != orig {
+		t.Errorf("after changing the middle of the file, got tag %q; want %q", got, orig)
+	}
+
+	// Changes at either end, or to the mtime, are.
+	for i, change := range []func(){
+		func() { fsys["big/f.txt"].Data[0] = 'y' },
+		func() { fsys["big/f.txt"].Data[99] = 'y' },
+		func() { fsys["big/f.txt"].ModTime = mtime.Add(time.Second) },
+	} {
+		fsys["big/f.txt"].Data = bytes.Clone(content)
+		fsys["big/f.txt"].ModTime = mtime
+		change()
+		if got := tag("big/f.txt"); got == orig {
+			t.Errorf("change %d did not alter tag", i)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package assetserver
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// compilePatterns validates and normalizes patterns.
+// It panics if any pattern is malformed.
+func compilePatterns(patterns []string) []string {
+	compiled := make([]string, len(patterns))
+	for i, pat := range patterns {
+		pat = strings.TrimPrefix(pat, "/")
+		for _, elem := range strings.Split(pat, "/") {
+			if _, err := path.Match(elem, ""); err != nil {
+				panic(fmt.Sprintf("assetserver: bad pattern %q", patterns[i]))
+			}
+		}
+		compiled[i] = pat
+	}
+	return compiled
+}
+
+// matchAny reports whether name matches any of the (compiled) patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pat := range patterns {
+		if matchPattern(pat, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether name matches the (compiled) pattern.
+func matchPattern(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pat, elems []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			if len(pat) == 0 {
+				return true
+			}
+			for i := range elems {
+				if matchElems(pat, elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], elems[0]); !ok {
+			return false
+		}
+		pat, elems = pat[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
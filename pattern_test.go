@@ -0,0 +1,46 @@
+package assetserver
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"a.js", "a.js", true},
+		{"a.js", "b.js", false},
+		{"*.js", "a.js", true},
+		{"*.js", "d/a.js", false},
+		{"d/*.css", "d/style.css", true},
+		{"d/*.css", "d/sub/style.css", false},
+		{"d/**", "d/style.css", true},
+		{"d/**", "d/sub/noext", true},
+		{"d/**", "a.js", false},
+		{"**/*.css", "style.css", true},
+		{"**/*.css", "d/sub/style.css", true},
+		{"**/*.css", "d/sub/style.js", false},
+		{"d/**/noext", "d/noext", true},
+		{"d/**/noext", "d/sub/noext", true},
+		{"d/**/noext", "d/sub/noext2", false},
+		{"**", "anything/at/all", true},
+	} {
+		pats := compilePatterns([]string{tt.pattern})
+		if got := matchPattern(pats[0], tt.name); got != tt.want {
+			t.Errorf("matchPattern(%q, %q): got %t; want %t", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompilePatterns(t *testing.T) {
+	got := compilePatterns([]string{"/d/**", "a.js"})
+	if got[0] != "d/**" || got[1] != "a.js" {
+		t.Errorf("compilePatterns: got %q", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("compilePatterns did not panic on a bad pattern")
+		}
+	}()
+	compilePatterns([]string{"d/[a"})
+}
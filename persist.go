@@ -0,0 +1,211 @@
+package assetserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// WithCacheFile makes the Server persist the information it caches about each
+// file (its size, modification time, tag, and content type) to the named file
+// so that a restarted Server does not need to hash every file again.
+//
+// The cache is loaded from the file, if it exists, when the Server is created.
+// (A missing or unreadable cache file is not an error; the Server starts with
+// an empty cache.) The cache is saved by [Server.Close], by
+// [Server.SaveCache], and, if saveInterval is positive, periodically in the
+// background.
+//
+// The loaded information is validated like any other cached information:
+// using the size and modification time of each file. Therefore, WithCacheFile
+// should not be used with file systems which don't report meaningful
+// modification times, such as [embed.FS].
+func WithCacheFile(name string, saveInterval time.Duration) Option {
+	return func(s *Server) {
+		s.cacheFile = name
+		s.cacheSaveInterval = saveInterval
+	}
+}
+
+// cacheFileVersion is incremented whenever the cache file format changes.
+const cacheFileVersion = 1
+
+type cacheFileContents struct {
+	Version int
+	// Config identifies the Server options that affect how tags are
+	// computed. A cache file written with a different configuration is
+	// ignored.
+	Config  string
+	Entries []cacheFileEntry
+}
+
+type cacheFileEntry struct {
+	Name        string
+	MTime       int64
+	Size        int64
+	Tag         string
+	ContentType string
+}
+
+// tagConfig returns a string describing the options that affect how the
+// Server computes tags and the other information it saves.
+func (s *Server) tagConfig() string {
+	var config string
+	if ph := s.partialHash; ph != nil {
+		config += fmt.Sprintf("partial:%d:%q;", ph.n, ph.patterns)
+	}
+	if s.typeInTag {
+		config += "type;"
+	}
+	if s.sniffLen > 0 {
+		config += fmt.Sprintf("sniff:%d;", s.sniffLen)
+	}
+	if s.sniffer != nil {
+		config += "sniffer:" + stableID(s.sniffer) + ";"
+	}
+	if ts := s.transforms; ts != nil {
+		for _, tr := range ts.list {
+			config += fmt.Sprintf("transform:%s:%q;", stableID(tr.t), tr.patterns)
+		}
+	}
+	return config
+}
+
+// stableID identifies v in a way which is the same each time the program
+// runs: by its type and, for a function, by the function's name.
+func stableID(v any) string {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Func {
+		if f := runtime.FuncForPC(rv.Pointer()); f != nil {
+			return fmt.Sprintf("%T:%s", v, f.Name())
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// SaveCache writes the Server's cached information about each file to the
+// file given by [WithCacheFile]. The file is replaced atomically.
+//
+// It is an error to call SaveCache if the Server was not created with
+// WithCacheFile.
+func (s *Server) SaveCache() error {
+	if s.cacheFile == "" {
+		return errors.New("assetserver: SaveCache called without WithCacheFile")
+	}
+	contents := cacheFileContents{
+		Version: cacheFileVersion,
+		Config:  s.tagConfig(),
+	}
+	for _, name := range s.cache.names() {
+		e := s.cache.peek(name)
+		if e == nil {
+			continue
+		}
+		info := e.Load()
+		if info == nil || info.body != nil {
+			// Transformed output isn't saved.
+			continue
+		}
+		contents.Entries = append(contents.Entries, cacheFileEntry{
+			Name:        name,
+			MTime:       info.mtime,
+			Size:        info.size,
+			Tag:         info.tag,
+			ContentType: info.contentType,
+		})
+	}
+	sort.Slice(contents.Entries, func(i, j int) bool {
+		return contents.Entries[i].Name < contents.Entries[j].Name
+	})
+	b, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.cacheFile), filepath.Base(s.cacheFile)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		removeTemp(tmp)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.cacheFile); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// loadCache populates the cache from the cache file, if possible.
+func (s *Server) loadCache() {
+	ctx := context.Background()
+	b, err := os.ReadFile(s.cacheFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.log(ctx, slog.LevelWarn, "cannot read cache file", "err", err)
+		}
+		return
+	}
+	var contents cacheFileContents
+	if err := json.Unmarshal(b, &contents); err != nil {
+		s.log(ctx, slog.LevelWarn, "cannot parse cache file", "file", s.cacheFile, "err", err)
+		return
+	}
+	if contents.Version != cacheFileVersion || contents.Config != s.tagConfig() {
+		s.log(ctx, slog.LevelInfo, "ignoring cache file written with a different configuration", "file", s.cacheFile)
+		return
+	}
+	for _, ent := range contents.Entries {
+		if s.transforms.applies(ent.Name) {
+			// Transformed files aren't saved, so this entry
+			// describes the file's original contents.
+			continue
+		}
+		info := &fileInfo{
+			mtime:       ent.MTime,
+			size:        ent.Size,
+			tag:         ent.Tag,
+			contentType: ent.ContentType,
+		}
+		if !s.trustCache() {
+			s.cache.entry(ent.Name).Store(info)
+			continue
+		}
+		// The info won't be checked when it's used, so check it now.
+		fi, err := fs.Stat(s.fsys, ent.Name)
+		if err != nil || fi.IsDir() || !info.matches(fi) {
+			continue
+		}
+		e := s.cache.entry(ent.Name)
+		e.Store(info)
+		e.markValidated()
+	}
+}
+
+func (s *Server) saveCacheLoop(d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.SaveCache(); err != nil {
+				s.log(context.Background(), slog.LevelError, "error saving cache file", "err", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
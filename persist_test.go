@@ -0,0 +1,149 @@
+package assetserver
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheFile(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	fsys := &countingFS{FS: os.DirFS("testdata/assets")}
+
+	s := New(fsys, WithCacheFile(cacheFile, 0))
+	want, err := s.Tag("d/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := fsys.opens.Load(); n != 1 {
+		t.Fatalf("got %d opens; want 1", n)
+	}
+
+	// A new Server loads the cache and doesn't need to open the file.
+	s = New(fsys, WithCacheFile(cacheFile, 0))
+	got, err := s.Tag("d/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("Tag with loaded cache: got %q; want %q", got, want)
+	}
+	if n := fsys.opens.Load(); n != 1 {
+		t.Fatalf("after loading cache, got %d opens; want 1", n)
+	}
+
+	// A Server configured to compute tags differently ignores the cache.
+	s = New(fsys, WithCacheFile(cacheFile, 0), WithPartialHashing(1))
+	if s.cachedInfo("d/style.css") != nil {
+		t.Fatal("Server with different tag configuration loaded the cache")
+	}
+}
+
+func TestCacheFileStale(t *testing.T) {
+	dir := t.TempDir()
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	name := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(name, []byte("a0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithCacheFile(cacheFile, 0))
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(name, []byte("a11"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Even a Server which trusts its cache must not use stale persisted
+	// information.
+	s = New(os.DirFS(dir), WithCacheFile(cacheFile, 0), WithRevalidateInterval(time.Hour))
+	defer s.Close()
+	got, err := s.Tag("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a." + hashTag("a11") + ".txt"; got != want {
+		t.Fatalf("got tag %q; want %q", got, want)
+	}
+}
+
+func TestSaveCacheWithoutFile(t *testing.T) {
+	s := New(os.DirFS("testdata/assets"))
+	if err := s.SaveCache(); err == nil {
+		t.Fatal("SaveCache without WithCacheFile returned nil error")
+	}
+}
+
+func TestCacheFileTransformAdded(t *testing.T) {
+	dir := t.TempDir()
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	if err := os.WriteFile(filepath.Join(dir, "a.js"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithCacheFile(cacheFile, 0))
+	if _, err := s.Tag("a.js"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart with a transformer, the file is transformed rather
+	// than served with its saved information.
+	s = New(os.DirFS(dir), WithCacheFile(cacheFile, 0), WithTransform(TransformFunc(upper)))
+	defer s.Close()
+	b, err := s.ReadFile("a.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "HELLO"; got != want {
+		t.Errorf("ReadFile after restart: got %q; want %q", got, want)
+	}
+	got, err := s.Tag("a.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a." + hashTag("HELLO") + ".js"; got != want {
+		t.Errorf("Tag after restart: got %q; want %q", got, want)
+	}
+}
+
+func TestCacheFileSnifferAdded(t *testing.T) {
+	dir := t.TempDir()
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	if err := os.WriteFile(filepath.Join(dir, "model"), []byte("glTF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithCacheFile(cacheFile, 0))
+	if _, err := s.Tag("model"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart with a sniffer, the file's content type comes from
+	// the sniffer rather than from the saved information.
+	sniffer := func(name string, head []byte) string {
+		if bytes.HasPrefix(head, []byte("glTF")) {
+			return "model/gltf-binary"
+		}
+		return ""
+	}
+	s = New(os.DirFS(dir), WithCacheFile(cacheFile, 0), WithContentSniffer(sniffer))
+	defer s.Close()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/model", nil))
+	if got, want := w.Header().Get("Content-Type"), "model/gltf-binary"; got != want {
+		t.Errorf("Content-Type after restart: got %q; want %q", got, want)
+	}
+}
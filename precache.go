@@ -0,0 +1,104 @@
+package assetserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PrecacheManifestOptions configures [WithPrecacheManifest].
+type PrecacheManifestOptions struct {
+	// Name is the name at which the Server serves the manifest, such as
+	// "precache-manifest.json". A file of the same name in the Server's
+	// file system is hidden. If the name ends in ".js", the manifest is
+	// served as a script (for use with importScripts) which appends the
+	// entries to self.__precacheManifest; otherwise it is served as JSON.
+	Name string
+	// Prefix is the URL path at which the Server's files are served, such
+	// as "/static/". The URLs in the manifest are formed by joining the
+	// prefix with the file names.
+	Prefix string
+	// Patterns selects the files to include. If empty, all files are
+	// included. See the Patterns section of the package documentation for
+	// the syntax.
+	Patterns []string
+}
+
+// WithPrecacheManifest makes the Server serve a precache manifest for
+// Workbox (or another service worker library using the same format): a list
+// of the URLs of the Server's files, each paired with its current tag as the
+// revision. A service worker can use the manifest to precache exactly the
+// versions of the files that are currently deployed and to refresh only the
+// files whose revisions change.
+//
+// The manifest is computed for each request for it (hashing any files whose
+// tags aren't cached) and served with Cache-Control: no-cache and an ETag, so
+// browsers always revalidate it. A no-cache Server serves an empty manifest.
+func WithPrecacheManifest(opts PrecacheManifestOptions) Option {
+	opts.Name = strings.TrimPrefix(opts.Name, "/")
+	if !strings.HasSuffix(opts.Prefix, "/") {
+		opts.Prefix += "/"
+	}
+	opts.Patterns = compilePatterns(opts.Patterns)
+	return func(s *Server) {
+		s.precache = &opts
+	}
+}
+
+// A precacheEntry is an entry in a Workbox precache manifest.
+type precacheEntry struct {
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+}
+
+// precacheManifest returns the JSON precache manifest.
+func (s *Server) precacheManifest(ctx context.Context) ([]byte, error) {
+	opts := s.precache
+	entries := []precacheEntry{}
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || s.noCache || name == opts.Name {
+			return nil
+		}
+		if len(opts.Patterns) > 0 && !matchAny(opts.Patterns, name) {
+			return nil
+		}
+		info, err := s.currentInfo(ctx, name)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, precacheEntry{URL: opts.Prefix + escapePath(name), Revision: info.tag})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(entries)
+}
+
+// servePrecacheManifest responds with the precache manifest.
+func (s *Server) servePrecacheManifest(w http.ResponseWriter, r *http.Request) {
+	b, err := s.precacheManifest(r.Context())
+	if err != nil {
+		s.writeFSError(w, r, s.precache.Name, err)
+		return
+	}
+	contentType := "application/json"
+	if strings.HasSuffix(s.precache.Name, ".js") {
+		contentType = "text/javascript; charset=utf-8"
+		b = append(append([]byte("self.__precacheManifest = (self.__precacheManifest || []).concat("), b...), ");\n"...)
+	}
+	sum := sha256.Sum256(b)
+	h := w.Header()
+	h.Set("Cache-Control", "no-cache")
+	h.Set("ETag", `"`+makeTag(sum[:])+`"`)
+	h.Set("Content-Type", contentType)
+	http.ServeContent(w, r, s.precache.Name, time.Time{}, bytes.NewReader(b))
+}
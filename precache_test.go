@@ -0,0 +1,77 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPrecacheManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":        &fstest.MapFile{Data: []byte("app")},
+		"css/style.css": &fstest.MapFile{Data: []byte("style")},
+		"sw.js":         &fstest.MapFile{Data: []byte("sw")},
+	}
+	for _, tt := range []struct {
+		opts        PrecacheManifestOptions
+		path        string
+		contentType string
+		want        string
+	}{
+		{
+			PrecacheManifestOptions{Name: "precache.json", Prefix: "/static"},
+			"/precache.json",
+			"application/json",
+			`[{"url":"/static/app.js","revision":"` + hashTag("app") + `"},` +
+				`{"url":"/static/css/style.css","revision":"` + hashTag("style") + `"},` +
+				`{"url":"/static/sw.js","revision":"` + hashTag("sw") + `"}]`,
+		},
+		{
+			PrecacheManifestOptions{Name: "/sw/manifest.js", Prefix: "/", Patterns: []string{"css/*"}},
+			"/sw/manifest.js",
+			"text/javascript; charset=utf-8",
+			`self.__precacheManifest = (self.__precacheManifest || []).concat(` +
+				`[{"url":"/css/style.css","revision":"` + hashTag("style") + `"}]);` + "\n",
+		},
+	} {
+		s := New(fsys, WithPrecacheManifest(tt.opts))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s: got body\n%s\nwant\n%s", tt.path, got, tt.want)
+		}
+		h := w.Header()
+		if got := h.Get("Content-Type"); got != tt.contentType {
+			t.Errorf("GET %s: got Content-Type %q; want %q", tt.path, got, tt.contentType)
+		}
+		if got := h.Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("GET %s: got Cache-Control %q; want no-cache", tt.path, got)
+		}
+		etag := h.Get("ETag")
+		if etag != `"`+hashTag(tt.want)+`"` {
+			t.Errorf("GET %s: got ETag %s; want hash of body", tt.path, etag)
+		}
+
+		r := httptest.NewRequest("GET", tt.path, nil)
+		r.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != 304 {
+			t.Errorf("conditional GET %s: got status %d; want 304", tt.path, w.Code)
+		}
+	}
+}
+
+func TestPrecacheManifestNoCache(t *testing.T) {
+	s := NewNoCache(fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte("app")},
+	}, WithPrecacheManifest(PrecacheManifestOptions{Name: "precache.json"}))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/precache.json", nil))
+	if got, want := w.Body.String(), "[]"; got != want {
+		t.Errorf("got body %s; want %s", got, want)
+	}
+}
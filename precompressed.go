@@ -0,0 +1,82 @@
+package assetserver
+
+import (
+	"net/http"
+)
+
+// WithPrecompressed makes the Server serve precompressed versions of the
+// files matching any of the given patterns (or of all files, if there are no
+// patterns) to clients which accept them. A precompressed version is a file
+// alongside the original with ".br" (Brotli) or ".gz" (gzip) appended to its
+// name, such as "app.wasm.br" for "app.wasm"; Brotli is preferred when the
+// client accepts both. This is particularly worthwhile for large WebAssembly
+// modules, which compress well but are too expensive to compress for each
+// request.
+//
+// Responses for matching files have a Vary: Accept-Encoding header, and a
+// compressed response has a Content-Encoding header and an ETag formed from
+// the original file's tag and the encoding. The tag (including in tagged
+// names) is that of the original file, so a precompressed version is only
+// used if it is at least as new as the original; this keeps a stale version
+// from being served after the original changes. Files produced by
+// transformers are not served precompressed.
+func WithPrecompressed(patterns ...string) Option {
+	pc := &precompressed{patterns: compilePatterns(patterns)}
+	return func(s *Server) {
+		s.precompressed = pc
+	}
+}
+
+type precompressed struct {
+	patterns []string // if empty, match all files
+}
+
+// applies reports whether precompressed versions of the named file may be
+// served.
+func (pc *precompressed) applies(name string) bool {
+	if pc == nil {
+		return false
+	}
+	return len(pc.patterns) == 0 || matchAny(pc.patterns, name)
+}
+
+// precompressedEncodings lists the supported encodings in order of
+// preference.
+var precompressedEncodings = []struct {
+	encoding string
+	suffix   string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// openPrecompressed opens a precompressed version of the file described by
+// info which is acceptable to the client. It returns the file and its
+// encoding or, if there is no such file, nil and "".
+func (s *Server) openPrecompressed(r *http.Request, name string, info *fileInfo) (seekerFile, string) {
+	ae := r.Header.Get("Accept-Encoding")
+	if ae == "" {
+		return nil, ""
+	}
+	for _, pe := range precompressedEncodings {
+		if !headerAccepts(ae, pe.encoding) {
+			continue
+		}
+		fv, err := s.fsys.Open(name + pe.suffix)
+		if err != nil {
+			continue
+		}
+		stat, err := fv.Stat()
+		if err != nil || stat.IsDir() || stat.ModTime().UnixNano() < info.mtime {
+			fv.Close()
+			continue
+		}
+		f, err := toSeeker(fv)
+		if err != nil {
+			fv.Close()
+			continue
+		}
+		return f, pe.encoding
+	}
+	return nil, ""
+}
@@ -0,0 +1,106 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPrecompressed(t *testing.T) {
+	t0 := time.Now()
+	t1 := t0.Add(time.Second)
+	fsys := fstest.MapFS{
+		"app.wasm":    &fstest.MapFile{Data: []byte("wasm module"), ModTime: t0},
+		"app.wasm.br": &fstest.MapFile{Data: []byte("brotli"), ModTime: t1},
+		"app.wasm.gz": &fstest.MapFile{Data: []byte("gzip"), ModTime: t0},
+		"old.js":      &fstest.MapFile{Data: []byte("new js"), ModTime: t1},
+		"old.js.gz":   &fstest.MapFile{Data: []byte("stale gzip"), ModTime: t0},
+		"a.txt":       &fstest.MapFile{Data: []byte("text"), ModTime: t0},
+		"a.txt.gz":    &fstest.MapFile{Data: []byte("text gzip"), ModTime: t0},
+	}
+	s := New(fsys, WithPrecompressed("**/*.wasm", "**/*.js"))
+	wasmTag := hashTag("wasm module")
+	for _, tt := range []struct {
+		method       string
+		path         string
+		acceptEnc    string
+		want         string
+		wantEncoding string
+		wantVary     string
+	}{
+		{"GET", "/app.wasm", "gzip, deflate, br", "brotli", "br", "Accept-Encoding"},
+		{"GET", "/app.wasm", "gzip", "gzip", "gzip", "Accept-Encoding"},
+		{"GET", "/app.wasm", "br;q=0, gzip", "gzip", "gzip", "Accept-Encoding"},
+		{"GET", "/app.wasm", "", "wasm module", "", "Accept-Encoding"},
+		{"GET", "/app." + wasmTag + ".wasm", "br", "brotli", "br", "Accept-Encoding"},
+		{"HEAD", "/app.wasm", "br", "", "br", "Accept-Encoding"},
+		{"GET", "/old.js", "gzip", "new js", "", "Accept-Encoding"},
+		{"GET", "/a.txt", "gzip", "text", "", ""},
+	} {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.acceptEnc != "" {
+			r.Header.Set("Accept-Encoding", tt.acceptEnc)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		desc := tt.method + " " + tt.path + " (Accept-Encoding: " + tt.acceptEnc + ")"
+		if w.Code != 200 {
+			t.Errorf("%s: got status %d", desc, w.Code)
+			continue
+		}
+		h := w.Header()
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: got body %q; want %q", desc, got, tt.want)
+		}
+		if got := h.Get("Content-Encoding"); got != tt.wantEncoding {
+			t.Errorf("%s: got Content-Encoding %q; want %q", desc, got, tt.wantEncoding)
+		}
+		if got := h.Get("Vary"); got != tt.wantVary {
+			t.Errorf("%s: got Vary %q; want %q", desc, got, tt.wantVary)
+		}
+		if tt.wantEncoding != "" {
+			if got, want := h.Get("ETag"), `"`+wasmTag+"-"+tt.wantEncoding+`"`; got != want {
+				t.Errorf("%s: got ETag %s; want %s", desc, got, want)
+			}
+		}
+		if tt.path == "/app.wasm" || tt.wantEncoding != "" {
+			if got, want := h.Get("Content-Type"), "application/wasm"; got != want {
+				t.Errorf("%s: got Content-Type %q; want %q", desc, got, want)
+			}
+		}
+	}
+
+	// Conditional requests match the ETag of any representation: a client
+	// with a cached copy in another encoding can keep using it.
+	for _, tt := range []struct {
+		acceptEnc string
+		inm       string
+		code      int
+	}{
+		{"br", `"` + wasmTag + `-br"`, 304},
+		{"br", `"` + wasmTag + `"`, 304},
+		{"br", `"` + wasmTag + `-gzip"`, 304},
+		{"br", `"` + hashTag("old") + `-br"`, 200},
+		{"", `"` + wasmTag + `"`, 304},
+	} {
+		r := httptest.NewRequest("GET", "/app.wasm", nil)
+		r.Header.Set("If-None-Match", tt.inm)
+		if tt.acceptEnc != "" {
+			r.Header.Set("Accept-Encoding", tt.acceptEnc)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("GET with If-None-Match %s (Accept-Encoding: %s): got status %d; want %d", tt.inm, tt.acceptEnc, w.Code, tt.code)
+		}
+	}
+
+	// An uncompressed HEAD response still has the original length.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("HEAD", "/app.wasm", nil))
+	if got, want := w.Header().Get("Content-Length"), strconv.Itoa(len("wasm module")); got != want {
+		t.Errorf("HEAD: got Content-Length %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package assetserver
+
+import "strings"
+
+// WithPreHashed tells the Server that the files matching any of the given
+// patterns already have content hashes in their names, as many front-end
+// build tools produce (for example, "assets/app.3f9c2b1a.js"). Such files
+// are served with the long-lived Cache-Control header used for tagged names
+// even when they are requested by their plain names, and [Server.Tag]
+// returns their names unchanged rather than adding a second hash.
+//
+// Since the Server can't verify that a name changes whenever the file's
+// contents do, only use WithPreHashed for files whose names are derived from
+// their contents. For instance, with Vite's default output,
+//
+//	WithPreHashed("assets/**")
+//
+// See the Patterns section of the package documentation for the pattern
+// syntax.
+func WithPreHashed(patterns ...string) Option {
+	patterns = compilePatterns(patterns)
+	return func(s *Server) {
+		s.preHashed = append(s.preHashed, patterns...)
+	}
+}
+
+// isPreHashed reports whether the named file's name includes a content hash.
+func (s *Server) isPreHashed(name string) bool {
+	return len(s.preHashed) > 0 && matchAny(s.preHashed, name)
+}
+
+// WithImmutableDirs makes the Server serve every file under the given
+// directories (such as "assets/immutable") with the long-lived Cache-Control
+// header used for tagged names, whether or not the file is requested by a
+// tagged name. This suits asset pipelines which give each build's output a
+// new directory, so that a file's contents never change once published.
+//
+// Unlike [WithPreHashed], WithImmutableDirs doesn't change what [Server.Tag]
+// returns: tagging the files is harmless, but unnecessary.
+func WithImmutableDirs(dirs ...string) Option {
+	var patterns []string
+	for _, dir := range dirs {
+		dir = strings.Trim(dir, "/")
+		if dir == "" {
+			patterns = append(patterns, "**")
+			continue
+		}
+		patterns = append(patterns, dir+"/**")
+	}
+	patterns = compilePatterns(patterns)
+	return func(s *Server) {
+		s.immutableDirs = append(s.immutableDirs, patterns...)
+	}
+}
+
+// isImmutable reports whether the named file may be cached indefinitely even
+// when it is requested by its untagged name.
+func (s *Server) isImmutable(name string) bool {
+	return s.isPreHashed(name) || len(s.immutableDirs) > 0 && matchAny(s.immutableDirs, name)
+}
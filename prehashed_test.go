@@ -0,0 +1,98 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreHashed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/app.3f9c2b1a.js": &fstest.MapFile{Data: []byte("app")},
+		"index.html":             &fstest.MapFile{Data: []byte("index")},
+	}
+	s := New(fsys, WithPreHashed("assets/**"))
+	const immutable = "public, max-age=31536000, immutable"
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{"/assets/app.3f9c2b1a.js", immutable},
+		{"/index.html", "public, max-age=60"},
+		{"/index." + hashTag("index") + ".html", immutable},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("GET %s: got Cache-Control %q; want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"assets/app.3f9c2b1a.js", "assets/app.3f9c2b1a.js"},
+		{"/assets/app.3f9c2b1a.js", "/assets/app.3f9c2b1a.js"},
+		{"index.html", "index." + hashTag("index") + ".html"},
+	} {
+		got, err := s.Tag(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Tag(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := s.Tag("assets/missing.1234.js"); err == nil {
+		t.Error("Tag of missing pre-hashed file: got nil error")
+	}
+
+	s = NewNoCache(fsys, WithPreHashed("assets/**"))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/assets/app.3f9c2b1a.js", nil))
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("no-cache server: got Cache-Control %q; want no-cache", got)
+	}
+}
+
+func TestImmutableDirs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/immutable/v3/app.js": &fstest.MapFile{Data: []byte("app")},
+		"assets/immutable.js":        &fstest.MapFile{Data: []byte("not in dir")},
+		"assets/other/b.js":          &fstest.MapFile{Data: []byte("b")},
+	}
+	s := New(fsys, WithImmutableDirs("/assets/immutable/"))
+	const immutable = "public, max-age=31536000, immutable"
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{"/assets/immutable/v3/app.js", immutable},
+		{"/assets/immutable/v3/app." + hashTag("app") + ".js", immutable},
+		{"/assets/immutable.js", "public, max-age=60"},
+		{"/assets/other/b.js", "public, max-age=60"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("GET %s: got Cache-Control %q; want %q", tt.path, got, tt.want)
+		}
+	}
+	if got, err := s.Tag("assets/immutable/v3/app.js"); err != nil || got != "assets/immutable/v3/app."+hashTag("app")+".js" {
+		t.Errorf("Tag = %q, %v; want tagged name", got, err)
+	}
+
+	s = New(fsys, WithImmutableDirs("/"))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/assets/other/b.js", nil))
+	if got := w.Header().Get("Cache-Control"); got != immutable {
+		t.Errorf("with root directory: got Cache-Control %q; want %q", got, immutable)
+	}
+}
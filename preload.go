@@ -0,0 +1,140 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Preload walks the entire file system and computes the information (such as
+// the tag) for every file, in parallel, so that the first request for each
+// file doesn't have to pay the cost of hashing it.
+//
+// Preload is typically called right after creating the Server. It returns an
+// error if any file cannot be read, so that a misconfigured file system is
+// discovered at startup rather than by the first request for a broken file.
+// Files which disappear during the walk are ignored.
+//
+// If ctx is canceled, Preload stops early and returns the context's error.
+// For large file systems, [WithProgress] reports how far Preload has got, and
+// [WithCheckpoints] lets a Preload which was stopped (or whose process
+// exited) be resumed after a restart.
+func (s *Server) Preload(ctx context.Context, opts ...PreloadOption) (err error) {
+	var cfg preloadConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.checkpoint > 0 && s.cacheFile == "" {
+		panic("assetserver: Preload called with WithCheckpoints without WithCacheFile")
+	}
+	var names []string
+	err = fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if cfg.checkpoint > 0 {
+		defer func() {
+			if saveErr := s.SaveCache(); saveErr != nil && err == nil {
+				err = saveErr
+			}
+		}()
+	}
+
+	var mu sync.Mutex
+	done := 0
+	if cfg.progress != nil {
+		cfg.progress(0, len(names))
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+	for _, name := range names {
+		if egCtx.Err() != nil {
+			break
+		}
+		name := name
+		eg.Go(func() error {
+			if err := s.preloadFile(egCtx, name); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if cfg.progress != nil {
+				cfg.progress(done, len(names))
+			}
+			if cfg.checkpoint > 0 && done%cfg.checkpoint == 0 {
+				if err := s.SaveCache(); err != nil {
+					s.log(ctx, slog.LevelWarn, "cannot save preload checkpoint", "err", err)
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// A PreloadOption configures [Server.Preload].
+type PreloadOption func(*preloadConfig)
+
+type preloadConfig struct {
+	progress   func(done, total int)
+	checkpoint int // if > 0, save the cache after this many files
+}
+
+// WithProgress makes Preload call fn to report its progress: once with done
+// = 0 after it has found all of the files and then after each file is
+// processed, with the number of files processed so far and the total number
+// of files. The calls are made one at a time, so fn needn't be safe for
+// concurrent use, but it should return quickly.
+func WithProgress(fn func(done, total int)) PreloadOption {
+	return func(cfg *preloadConfig) {
+		cfg.progress = fn
+	}
+}
+
+// WithCheckpoints makes Preload save the Server's cache (see [WithCacheFile])
+// after every n files and again when it returns, including when it stops
+// early. After a restart, the new Server loads the saved information and
+// another call to Preload skips hashing the files which were already
+// processed and haven't changed since.
+//
+// Preload panics if it is given this option and the Server wasn't created
+// with WithCacheFile.
+func WithCheckpoints(n int) PreloadOption {
+	if n <= 0 {
+		panic("assetserver: WithCheckpoints called with non-positive count")
+	}
+	return func(cfg *preloadConfig) {
+		cfg.checkpoint = n
+	}
+}
+
+func (s *Server) preloadFile(ctx context.Context, name string) error {
+	if _, err := s.currentInfo(ctx, name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("assetserver: error loading %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreload(t *testing.T) {
+	s := New(os.DirFS("testdata/assets"))
+	if err := s.Preload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"a.js",
+		"b.min.js",
+		"d/style.css",
+		"d/sub/noext",
+	} {
+		if s.cachedInfo(name) == nil {
+			t.Errorf("after Preload, no cached info for %s", name)
+		}
+	}
+}
+
+func TestPreloadError(t *testing.T) {
+	errBad := errors.New("bad file")
+	fsys := errorFS{
+		FS: fstest.MapFS{
+			"a.txt":   &fstest.MapFile{Data: []byte("a")},
+			"d/b.txt": &fstest.MapFile{Data: []byte("b")},
+		},
+		errs: map[string]error{"d/b.txt": errBad},
+	}
+	s := New(fsys)
+	if err := s.Preload(context.Background()); !errors.Is(err, errBad) {
+		t.Fatalf("Preload: got error %v; want %v", err, errBad)
+	}
+}
+
+func TestPreloadCanceled(t *testing.T) {
+	s := New(os.DirFS("testdata/assets"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Preload(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Preload: got error %v; want %v", err, context.Canceled)
+	}
+}
+
+// errorFS is an fs.FS which returns the given errors when opening the
+// corresponding files.
+type errorFS struct {
+	fs.FS
+	errs map[string]error
+}
+
+func (fsys errorFS) Open(name string) (fs.File, error) {
+	if err, ok := fsys.errs[name]; ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return fsys.FS.Open(name)
+}
+
+func TestPreloadProgress(t *testing.T) {
+	s := New(os.DirFS("testdata/assets"))
+	defer s.Close()
+	var calls [][2]int
+	if err := s.Preload(context.Background(), WithProgress(func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) < 2 {
+		t.Fatalf("got progress calls %v; want at least 2", calls)
+	}
+	total := calls[0][1]
+	for i, c := range calls {
+		if c != [2]int{i, total} {
+			t.Fatalf("got progress calls %v; want done counting up from 0 to %d", calls, total)
+		}
+	}
+	if last := calls[len(calls)-1]; last[0] != total {
+		t.Errorf("last progress call was %v; want done = total", last)
+	}
+}
+
+func TestPreloadCheckpoints(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	var hashes atomic.Int64
+	hooks := WithMetricsHooks(MetricsHooks{HashStart: func() { hashes.Add(1) }})
+
+	// Stop the first Preload partway through.
+	s := New(os.DirFS("testdata/assets"), hooks, WithCacheFile(cacheFile, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	var total int
+	err := s.Preload(ctx, WithCheckpoints(1), WithProgress(func(done, n int) {
+		total = n
+		if done == 2 {
+			cancel()
+		}
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Preload: got error %v; want %v", err, context.Canceled)
+	}
+	first := hashes.Load()
+	if first >= int64(total) {
+		t.Fatalf("canceled Preload hashed %d of %d files", first, total)
+	}
+
+	// A new Server (without Close, as if the process had exited) resumes
+	// from the checkpoint.
+	s = New(os.DirFS("testdata/assets"), hooks, WithCacheFile(cacheFile, 0))
+	defer s.Close()
+	hashes.Store(0)
+	if err := s.Preload(context.Background(), WithCheckpoints(100)); err != nil {
+		t.Fatal(err)
+	}
+	if n := hashes.Load(); n+first < int64(total) || n == int64(total) {
+		t.Errorf("resumed Preload hashed %d files; first Preload hashed %d of %d", n, first, total)
+	}
+}
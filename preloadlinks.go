@@ -0,0 +1,104 @@
+package assetserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WithPreloadLinks makes the Server add Link headers to responses for the
+// file entry (typically an HTML page, such as "index.html") which tell
+// browsers to preload the given assets, such as the page's critical CSS and
+// JavaScript, before they discover them in the page. The links refer to the
+// assets by their tagged names, so they match the URLs used in the page.
+// WithPreloadLinks may be given multiple times, including for the same entry.
+//
+// The kind of each asset (the link's "as" attribute) is derived from its
+// extension: .css files are styles, .js files are scripts, .mjs files are
+// preloaded with rel=modulepreload, and common font, image, and JSON
+// extensions are supported as well. WithPreloadLinks panics if an asset has
+// any other extension.
+//
+// The links are relative to the entry's URL, so they work however the Server
+// is mounted. Assets which don't exist are left out.
+func WithPreloadLinks(entry string, assets ...string) Option {
+	entry = strings.TrimPrefix(entry, "/")
+	var links []preloadLink
+	for _, asset := range assets {
+		asset = strings.TrimPrefix(asset, "/")
+		l, ok := newPreloadLink(asset)
+		if !ok {
+			panic(fmt.Sprintf("assetserver: WithPreloadLinks: cannot determine the type of %s", asset))
+		}
+		links = append(links, l)
+	}
+	return func(s *Server) {
+		if s.preloadLinks == nil {
+			s.preloadLinks = make(map[string][]preloadLink)
+		}
+		s.preloadLinks[entry] = append(s.preloadLinks[entry], links...)
+	}
+}
+
+// A preloadLink describes a Link header for an asset.
+type preloadLink struct {
+	name  string
+	attrs string // following the URL, such as "; rel=preload; as=style"
+}
+
+func newPreloadLink(name string) (preloadLink, bool) {
+	var attrs string
+	switch path.Ext(name) {
+	case ".css":
+		attrs = "; rel=preload; as=style"
+	case ".js":
+		attrs = "; rel=preload; as=script"
+	case ".mjs":
+		attrs = "; rel=modulepreload"
+	case ".woff2", ".woff", ".ttf", ".otf":
+		// Fonts are always fetched in CORS mode.
+		attrs = "; rel=preload; as=font; crossorigin"
+	case ".avif", ".gif", ".jpeg", ".jpg", ".png", ".svg", ".webp":
+		attrs = "; rel=preload; as=image"
+	case ".json":
+		attrs = "; rel=preload; as=fetch; crossorigin"
+	default:
+		return preloadLink{}, false
+	}
+	return preloadLink{name: name, attrs: attrs}, true
+}
+
+// addPreloadLinks adds the Link headers for the named file, if any, to h.
+func (s *Server) addPreloadLinks(ctx context.Context, h http.Header, name string) {
+	for _, l := range s.preloadLinks[name] {
+		info, err := s.currentInfo(ctx, l.name)
+		if err != nil {
+			continue
+		}
+		target := l.name
+		if !s.noCache {
+			target = insertTag(target, info.tag)
+		}
+		h.Add("Link", "<"+escapeRelative(relativeURL(name, target))+">"+l.attrs)
+	}
+}
+
+// relativeURL returns a relative URL which refers to the file target from
+// the file name.
+func relativeURL(name, target string) string {
+	from := strings.Split(name, "/")
+	from = from[:len(from)-1] // directory components
+	to := strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	var b strings.Builder
+	for range from[i:] {
+		b.WriteString("../")
+	}
+	b.WriteString(strings.Join(to[i:], "/"))
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreloadLinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":        &fstest.MapFile{Data: []byte("<p>index</p>")},
+		"docs/page.html":    &fstest.MapFile{Data: []byte("<p>page</p>")},
+		"css/app.css":       &fstest.MapFile{Data: []byte("css")},
+		"js/app.js":         &fstest.MapFile{Data: []byte("js")},
+		"js/mod.mjs":        &fstest.MapFile{Data: []byte("mod")},
+		"fonts/body.woff2":  &fstest.MapFile{Data: []byte("font")},
+		"docs/img/fig.webp": &fstest.MapFile{Data: []byte("fig")},
+	}
+	opts := []Option{
+		WithPreloadLinks("/index.html", "/css/app.css", "js/app.js", "js/missing.js"),
+		WithPreloadLinks("index.html", "js/mod.mjs"),
+		WithPreloadLinks("docs/page.html", "css/app.css", "fonts/body.woff2", "docs/img/fig.webp"),
+	}
+	s := New(fsys, opts...)
+	for _, tt := range []struct {
+		path string
+		want []string
+	}{
+		{"/index.html", []string{
+			"<css/app." + hashTag("css") + ".css>; rel=preload; as=style",
+			"<js/app." + hashTag("js") + ".js>; rel=preload; as=script",
+			"<js/mod." + hashTag("mod") + ".mjs>; rel=modulepreload",
+		}},
+		{"/docs/page." + hashTag("<p>page</p>") + ".html", []string{
+			"<../css/app." + hashTag("css") + ".css>; rel=preload; as=style",
+			"<../fonts/body." + hashTag("font") + ".woff2>; rel=preload; as=font; crossorigin",
+			"<img/fig." + hashTag("fig") + ".webp>; rel=preload; as=image",
+		}},
+		{"/css/app.css", nil},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Header()["Link"]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s: got Link headers\n%q\nwant\n%q", tt.path, got, tt.want)
+		}
+	}
+
+	s = NewNoCache(fsys, opts...)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/docs/page.html", nil))
+	if got, want := w.Header().Get("Link"), "<../css/app.css>; rel=preload; as=style"; got != want {
+		t.Errorf("no-cache server: got Link header %q; want %q", got, want)
+	}
+}
+
+func TestPreloadLinksUnknownType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("WithPreloadLinks did not panic for an unknown file type")
+		}
+	}()
+	WithPreloadLinks("index.html", "data.bin")
+}
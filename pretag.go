@@ -0,0 +1,88 @@
+package assetserver
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// WithPretag makes the Server watch its file system for changes and hash
+// each file that changes (or is created) in the background as soon as the
+// change is noticed, so that the first request for the file after it changes
+// doesn't wait for it to be hashed. This matters most for large files that
+// are updated often, such as data files regenerated by a cron job. The
+// Server checks the files for changes (by walking the file system and
+// comparing their sizes and modification times) every interval d until
+// [Server.Close] is called.
+//
+// Unlike [WithRevalidateInterval], WithPretag doesn't change how requests
+// check that cached information is up to date, so a request which arrives
+// before a change is noticed (or before the file is hashed) still sees the
+// change. The two options may be combined.
+//
+// WithPretag has no effect for a file system that never changes (see
+// [WithImmutableFS]).
+func WithPretag(d time.Duration) Option {
+	if d <= 0 {
+		panic("assetserver: WithPretag called with non-positive interval")
+	}
+	return func(s *Server) {
+		s.pretag = d
+	}
+}
+
+// pretagLoop checks for changes every interval d, starting from the snapshot
+// files.
+func (s *Server) pretagLoop(d time.Duration, files map[string]fileStamp) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			files = s.pretagChanges(files)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// pretagChanges hashes the files which have changed since the snapshot old
+// was taken and returns a new snapshot.
+func (s *Server) pretagChanges(old map[string]fileStamp) map[string]fileStamp {
+	files := s.snapshotFiles()
+	for name, stamp := range files {
+		if prev, ok := old[name]; ok && prev == stamp {
+			continue
+		}
+		select {
+		case <-s.done:
+			return files
+		default:
+		}
+		// As with revalidation, errors leave the cached info alone;
+		// requests for the file will encounter them.
+		s.pretagFile(name)
+	}
+	for name := range old {
+		if _, ok := files[name]; !ok {
+			s.evict(name)
+		}
+	}
+	return files
+}
+
+// pretagFile computes the info for the named file, unless the cached info
+// is already up to date.
+func (s *Server) pretagFile(name string) error {
+	fi, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			s.evict(name)
+		}
+		return err
+	}
+	if fi.IsDir() {
+		return nil
+	}
+	return s.updateEntry(s.cache.entry(name), name, fi)
+}
@@ -0,0 +1,84 @@
+package assetserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/renameio"
+)
+
+func TestPretag(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, text string) {
+		t.Helper()
+		if err := renameio.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a.txt", "a0")
+	writeFile("b.txt", "b0")
+	s := New(os.DirFS(dir), WithPretag(time.Hour))
+	defer s.Close()
+	cachedTag := func(name string) string {
+		t.Helper()
+		e := s.cache.peek(name)
+		if e == nil {
+			return ""
+		}
+		if info := e.Load(); info != nil {
+			return info.tag
+		}
+		return ""
+	}
+	if _, err := s.Tag("b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	files := s.snapshotFiles()
+	writeFile("a.txt", "a11")
+	writeFile("c.txt", "c0")
+	if err := os.Remove(filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	s.pretagChanges(files)
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"a.txt", hashTag("a11")},
+		{"b.txt", ""},
+		{"c.txt", hashTag("c0")},
+	} {
+		if got := cachedTag(tt.name); got != tt.want {
+			t.Errorf("%s: got cached tag %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPretagInterval(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	if err := renameio.WriteFile(name, []byte("a0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithPretag(time.Millisecond))
+	defer s.Close()
+	if err := renameio.WriteFile(name, []byte("a11"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := hashTag("a11")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if e := s.cache.peek("a.txt"); e != nil {
+			if info := e.Load(); info != nil && info.tag == want {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("changed file wasn't hashed in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,76 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// WithProxyFallback makes the Server forward requests that it cannot serve
+// from its file system to the HTTP server at upstream, such as a frontend
+// development server (Vite, webpack-dev-server, and so on). This lets the Go
+// program be the single entry point for the application in development as
+// well as in production.
+//
+// Requests are forwarded if the requested file does not exist or if the
+// method is not GET or HEAD. WebSocket connections (which development
+// servers use for hot module replacement) are forwarded as well. The request
+// path is forwarded as the Server received it (that is, after any prefix is
+// removed by http.StripPrefix).
+func WithProxyFallback(upstream *url.URL) Option {
+	return func(s *Server) {
+		s.proxy = httputil.NewSingleHostReverseProxy(upstream)
+		s.proxyAll = false
+	}
+}
+
+// WithProxyAll is like [WithProxyFallback] except that the Server forwards
+// all requests to upstream without consulting its file system. The Server's
+// Tag method still works as usual.
+func WithProxyAll(upstream *url.URL) Option {
+	return func(s *Server) {
+		s.proxy = httputil.NewSingleHostReverseProxy(upstream)
+		s.proxyAll = true
+	}
+}
+
+// WithFallback makes the Server pass the requests that it cannot serve from
+// its file system to h, as [WithProxyFallback] forwards them to an upstream
+// server. See also [NewWithFallback].
+//
+// Requests are passed to h if the requested file does not exist, if the path
+// is /, or if the method is one the Server doesn't accept (see
+// [WithMethods]). A request for a tagged name whose tag is out of date
+// still gets a 404 response, since the file exists.
+func WithFallback(h http.Handler) Option {
+	return func(s *Server) {
+		s.proxy = h
+		s.proxyAll = false
+	}
+}
+
+// proxyUnmatched forwards a request that the Server cannot serve to the
+// upstream server, if there is one, and reports whether it did so.
+func (s *Server) proxyUnmatched(w http.ResponseWriter, r *http.Request) bool {
+	if s.proxy == nil {
+		return false
+	}
+	s.forward(w, r)
+	return true
+}
+
+// forward passes r to the upstream server or fallback handler, provided that
+// it passes the Server's authentication (see WithBasicAuth), if any. Since
+// the request needn't name a file, WithBasicAuth's patterns are matched
+// against its path, without the leading slash.
+func (s *Server) forward(w http.ResponseWriter, r *http.Request) {
+	if s.auth != nil {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if !s.auth.check(w, r, name) {
+			return
+		}
+	}
+	s.proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,146 @@
+package assetserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestUpstream(t *testing.T) *url.URL {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "upstream %s %s", r.Method, r.URL.Path)
+	}))
+	t.Cleanup(upstream.Close)
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestProxyFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("local a")},
+	}
+	s := New(fsys, WithProxyFallback(newTestUpstream(t)))
+	ts := httptest.NewServer(http.StripPrefix("/static/", s))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/static/a.txt", "local a"},
+		{"GET", "/static/src/main.ts", "upstream GET /src/main.ts"},
+		{"GET", "/static/", "upstream GET /"},
+		{"POST", "/static/a.txt", "upstream POST /a.txt"},
+	} {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 || string(body) != tt.want {
+			t.Errorf("%s %s: got %d %q; want 200 %q", tt.method, tt.path, resp.StatusCode, body, tt.want)
+		}
+	}
+}
+
+func TestProxyAll(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("local a")},
+	}
+	s := New(fsys, WithProxyAll(newTestUpstream(t)))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+	if got, want := w.Body.String(), "upstream GET /a.txt"; got != want {
+		t.Errorf("got body %q; want %q", got, want)
+	}
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Errorf("Tag: %s", err)
+	}
+}
+
+func TestNewWithFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: []byte("local a")},
+		"style.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "app %s %s", r.Method, r.URL.Path)
+	})
+	s := NewWithFallback(fsys, app)
+	defer s.Close()
+	tagged, err := s.Tag("/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := insertTag("/style.css", hashTag("old"))
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{"GET", "/a.txt", 200, "local a"},
+		{"HEAD", "/a.txt", 200, ""},
+		{"GET", tagged, 200, "body{}"},
+		{"GET", "/", 200, "app GET /"},
+		{"GET", "/users/123", 200, "app GET /users/123"},
+		{"POST", "/a.txt", 200, "app POST /a.txt"},
+		{"GET", stale, 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s %s: got status %d; want %d", tt.method, tt.path, w.Code, tt.status)
+			continue
+		}
+		if tt.status == 200 && w.Body.String() != tt.want {
+			t.Errorf("%s %s: got %q; want %q", tt.method, tt.path, w.Body, tt.want)
+		}
+	}
+}
+
+func TestNewWithFallbackWrappers(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("local a")}}
+	var gotRange string
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		w.Header().Set("Accept-Ranges", "bytes")
+		if _, ok := w.(*throttledWriter); ok {
+			t.Error("app response was throttled")
+		}
+		io.WriteString(w, "app")
+	})
+	s := NewWithFallback(fsys, app, WithoutRanges(), WithThrottle(ThrottleOptions{BytesPerSecond: 1}))
+	defer s.Close()
+	req := httptest.NewRequest("GET", "/users/123", nil)
+	req.Header.Set("Range", "bytes=0-1")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != 200 || w.Body.String() != "app" {
+		t.Fatalf("GET /users/123: got (%d, %q)", w.Code, w.Body)
+	}
+	if gotRange != "bytes=0-1" {
+		t.Errorf("app got Range %q; want %q", gotRange, "bytes=0-1")
+	}
+	if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("got Accept-Ranges %q; want bytes", got)
+	}
+}
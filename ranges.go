@@ -0,0 +1,95 @@
+package assetserver
+
+import (
+	"io"
+	"net/http"
+)
+
+// WithoutRanges makes the Server ignore the Range headers of requests for the
+// files matching any of the given patterns (or for all files, if there are no
+// patterns) and send the entire file, with an Accept-Ranges: none header to
+// tell clients not to ask for ranges.
+//
+// Byte ranges are important for large media: browsers use them to seek in
+// videos and to resume downloads. (Range requests are answered according to
+// the ETag, which is the quoted tag, so an If-Range header only matches the
+// current contents of the file.) For small text assets such as CSS and
+// JavaScript, though, ranges serve no purpose, and disabling them avoids
+// partial and multipart responses entirely.
+func WithoutRanges(patterns ...string) Option {
+	nr := &noRanges{patterns: compilePatterns(patterns)}
+	return func(s *Server) {
+		s.noRanges = nr
+	}
+}
+
+type noRanges struct {
+	patterns []string // if empty, match all files
+}
+
+// applies reports whether ranges are disabled for the named file.
+func (nr *noRanges) applies(name string) bool {
+	if nr == nil {
+		return false
+	}
+	return len(nr.patterns) == 0 || matchAny(nr.patterns, name)
+}
+
+// withoutRangeHeaders returns a shallow copy of r without the headers which
+// request byte ranges.
+func withoutRangeHeaders(r *http.Request) *http.Request {
+	if r.Header.Get("Range") == "" && r.Header.Get("If-Range") == "" {
+		return r
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = r.Header.Clone()
+	r2.Header.Del("Range")
+	r2.Header.Del("If-Range")
+	return r2
+}
+
+// A noRangesWriter wraps a ResponseWriter to replace the Accept-Ranges header
+// that http.ServeContent always sends.
+type noRangesWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *noRangesWriter) setHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if _, ok := w.Header()["Accept-Ranges"]; ok {
+		w.Header().Set("Accept-Ranges", "none")
+	}
+}
+
+func (w *noRangesWriter) WriteHeader(status int) {
+	if status >= 200 {
+		w.setHeader()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *noRangesWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+// ReadFrom passes through to the underlying ResponseWriter's ReadFrom method,
+// if it has one, so that net/http can still use sendfile.
+func (w *noRangesWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.setHeader()
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Unwrap returns the underlying ResponseWriter for the benefit of
+// http.ResponseController.
+func (w *noRangesWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
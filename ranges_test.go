@@ -0,0 +1,72 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRanges(t *testing.T) {
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	const video = "0123456789abcdef"
+	fsys := fstest.MapFS{
+		"clip.mp4": &fstest.MapFile{Data: []byte(video), ModTime: mtime},
+		"app.css":  &fstest.MapFile{Data: []byte("body{}"), ModTime: mtime},
+	}
+	tag := hashTag(video)
+	tagged := "/clip." + tag + ".mp4"
+	s := New(fsys, WithoutRanges("**/*.css"))
+	for _, tt := range []struct {
+		path             string
+		header           http.Header
+		code             int
+		want             string
+		wantContentRange string
+		wantAcceptRanges string
+	}{
+		{tagged, http.Header{"Range": {"bytes=4-7"}}, 206, "4567", "bytes 4-7/16", "bytes"},
+		{"/clip.mp4", http.Header{"Range": {"bytes=10-"}}, 206, "abcdef", "bytes 10-15/16", "bytes"},
+		// Seeking while the cached copy is current.
+		{tagged, http.Header{"Range": {"bytes=4-7"}, "If-Range": {`"` + tag + `"`}}, 206, "4567", "bytes 4-7/16", "bytes"},
+		{tagged, http.Header{"Range": {"bytes=4-7"}, "If-Range": {mtime.Format(http.TimeFormat)}}, 206, "4567", "bytes 4-7/16", "bytes"},
+		// The client's copy is outdated (or the validator isn't ours), so
+		// it must get the whole file.
+		{"/clip.mp4", http.Header{"Range": {"bytes=4-7"}, "If-Range": {`"` + hashTag("old") + `"`}}, 200, video, "", "bytes"},
+		{"/clip.mp4", http.Header{"Range": {"bytes=4-7"}, "If-Range": {`W/"` + tag + `"`}}, 200, video, "", "bytes"},
+		{"/clip.mp4", http.Header{"Range": {"bytes=4-7"}, "If-Range": {tag}}, 200, video, "", "bytes"},
+		{"/clip.mp4", http.Header{"Range": {"bytes=4-7"}, "If-Range": {mtime.Add(-time.Hour).Format(http.TimeFormat)}}, 200, video, "", "bytes"},
+		// Ranges are disabled for CSS.
+		{"/app.css", http.Header{"Range": {"bytes=0-1"}}, 200, "body{}", "", "none"},
+		{"/app.css", http.Header{"Range": {"bytes=0-1"}, "If-Range": {`"` + hashTag("body{}") + `"`}}, 200, "body{}", "", "none"},
+		{"/app.css", nil, 200, "body{}", "", "none"},
+	} {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		for k, v := range tt.header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("GET %s with %v: got status %d; want %d", tt.path, tt.header, w.Code, tt.code)
+			continue
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s with %v: got body %q; want %q", tt.path, tt.header, got, tt.want)
+		}
+		if got := w.Header().Get("Content-Range"); got != tt.wantContentRange {
+			t.Errorf("GET %s with %v: got Content-Range %q; want %q", tt.path, tt.header, got, tt.wantContentRange)
+		}
+		if got := w.Header().Get("Accept-Ranges"); got != tt.wantAcceptRanges {
+			t.Errorf("GET %s with %v: got Accept-Ranges %q; want %q", tt.path, tt.header, got, tt.wantAcceptRanges)
+		}
+	}
+
+	// A HEAD request answered from the cache also advertises no ranges.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("HEAD", "/app.css", nil))
+	if got := w.Header().Get("Accept-Ranges"); got != "none" {
+		t.Errorf("HEAD: got Accept-Ranges %q; want none", got)
+	}
+}
@@ -0,0 +1,151 @@
+package assetserver
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A RequestLimiter decides whether requests may proceed, to limit the rate
+// of requests from each client. See [WithRateLimit].
+type RequestLimiter interface {
+	// Allow reports whether a request from the client identified by key
+	// may proceed. If not, it also returns how long the client should
+	// wait before trying again. Allow must be safe for concurrent use.
+	Allow(key string) (ok bool, retryAfter time.Duration)
+}
+
+// NewRequestLimiter returns a RequestLimiter that allows each client an
+// average of perSecond requests per second, with bursts of up to burst
+// requests. It forgets clients that have been idle long enough to make a
+// full burst again, so its memory use is bounded by the number of recently
+// active clients. NewRequestLimiter panics if perSecond <= 0 or burst < 1.
+func NewRequestLimiter(perSecond float64, burst int) RequestLimiter {
+	if perSecond <= 0 {
+		panic("assetserver: NewRequestLimiter called with perSecond <= 0")
+	}
+	if burst < 1 {
+		panic("assetserver: NewRequestLimiter called with burst < 1")
+	}
+	return &tokenBuckets{
+		rate:    perSecond,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// tokenBuckets is the RequestLimiter returned by NewRequestLimiter.
+type tokenBuckets struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time // when tokens was computed
+}
+
+func (tb *tokenBuckets) Allow(key string) (bool, time.Duration) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	now := time.Now()
+	tb.sweep(now)
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: tb.burst, last: now}
+		tb.buckets[key] = b
+	}
+	b.tokens = math.Min(tb.burst, b.tokens+now.Sub(b.last).Seconds()*tb.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / tb.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep removes the buckets which have refilled, since they are
+// indistinguishable from new ones. It does so at most once per refill
+// period so that the cost is amortized over many calls.
+func (tb *tokenBuckets) sweep(now time.Time) {
+	refill := time.Duration(tb.burst / tb.rate * float64(time.Second))
+	if now.Sub(tb.lastSweep) < refill {
+		return
+	}
+	tb.lastSweep = now
+	for key, b := range tb.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+// WithRateLimit makes the Server limit the rate of requests for the files
+// matching any of the given patterns (or for all files, if there are no
+// patterns) using l, to damp scraping of assets which are expensive to serve.
+// Requests which l rejects receive 429 Too Many Requests with a Retry-After
+// header.
+//
+// The key function identifies the client that made a request. If it is nil,
+// clients are identified by IP address (from the request's RemoteAddr, so
+// behind a reverse proxy, key should use a header such as X-Forwarded-For
+// instead).
+//
+// For example, to allow each client 10 requests per second to resized
+// images, with bursts of 50:
+//
+//	WithRateLimit(NewRequestLimiter(10, 50), nil, "images/**")
+//
+// See the Patterns section of the package documentation for the pattern
+// syntax.
+func WithRateLimit(l RequestLimiter, key func(r *http.Request) string, patterns ...string) Option {
+	if key == nil {
+		key = clientIP
+	}
+	rl := &rateLimit{limiter: l, key: key, patterns: compilePatterns(patterns)}
+	return func(s *Server) {
+		s.rateLimit = rl
+	}
+}
+
+type rateLimit struct {
+	limiter  RequestLimiter
+	key      func(r *http.Request) string
+	patterns []string // if empty, match all files
+}
+
+// check reports whether the request for the named file may proceed. If not,
+// check writes a 429 response.
+func (rl *rateLimit) check(w http.ResponseWriter, r *http.Request, name string) bool {
+	if rl == nil || len(rl.patterns) > 0 && !matchAny(rl.patterns, name) {
+		return true
+	}
+	ok, retryAfter := rl.limiter.Allow(rl.key(r))
+	if ok {
+		return true
+	}
+	secs := int64(math.Ceil(retryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+	return false
+}
+
+// clientIP returns the IP address of the client that made r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,92 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/a.png": &fstest.MapFile{Data: []byte("a")},
+		"style.css":    &fstest.MapFile{Data: []byte("b")},
+	}
+	s := New(fsys, WithRateLimit(NewRequestLimiter(1, 2), nil, "images/**"))
+	defer s.Close()
+	for _, tt := range []struct {
+		addr       string
+		path       string
+		status     int
+		retryAfter string
+	}{
+		{"192.0.2.1:1234", "/images/a.png", 200, ""},
+		{"192.0.2.1:1235", "/images/a.png", 200, ""},
+		{"192.0.2.1:1236", "/images/a.png", 429, "1"},
+		{"192.0.2.1:1236", "/style.css", 200, ""}, // not limited
+		{"192.0.2.2:1234", "/images/a.png", 200, ""},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.addr
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("GET %s from %s: got status %d; want %d", tt.path, tt.addr, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("GET %s from %s: got Retry-After %q; want %q", tt.path, tt.addr, got, tt.retryAfter)
+		}
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	s := New(fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}},
+		WithRateLimit(NewRequestLimiter(1, 1), func(r *http.Request) string {
+			return r.Header.Get("X-Forwarded-For")
+		}))
+	defer s.Close()
+	for _, tt := range []struct {
+		forwardedFor string
+		status       int
+	}{
+		{"198.51.100.1", 200},
+		{"198.51.100.1", 429},
+		{"198.51.100.2", 200},
+	} {
+		req := httptest.NewRequest("GET", "/a.txt", nil)
+		req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("GET for %s: got status %d; want %d", tt.forwardedFor, w.Code, tt.status)
+		}
+	}
+}
+
+func TestRequestLimiter(t *testing.T) {
+	l := NewRequestLimiter(100, 2).(*tokenBuckets)
+	for i, want := range []bool{true, true, false} {
+		if ok, _ := l.Allow("k"); ok != want {
+			t.Fatalf("Allow #%d: got %t; want %t", i, ok, want)
+		}
+	}
+	_, wait := l.Allow("k")
+	if wait <= 0 || wait > 10*time.Millisecond {
+		t.Errorf("Allow: got retry after %s; want (0, 10ms]", wait)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ := l.Allow("k"); !ok {
+		t.Error("Allow after refilling: got false")
+	}
+	// Idle clients are forgotten.
+	l.Allow("other")
+	time.Sleep(30 * time.Millisecond)
+	l.Allow("k")
+	l.mu.Lock()
+	n := len(l.buckets)
+	l.mu.Unlock()
+	if n != 1 {
+		t.Errorf("after sweeping, got %d buckets; want 1", n)
+	}
+}
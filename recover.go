@@ -0,0 +1,43 @@
+package assetserver
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// WithPanicRecovery makes the Server recover from panics that occur while it
+// is serving a request (for instance, in a buggy fs.FS implementation). The
+// Server responds with 500 Internal Server Error (unless the response has
+// already begun) and then calls fn, if it is non-nil, with the panic value
+// and the stack trace of the panicking goroutine.
+//
+// Without this option, a panic propagates to net/http, which logs it and
+// closes the connection. As with net/http, a panic with the value
+// http.ErrAbortHandler is not recovered.
+func WithPanicRecovery(fn func(r *http.Request, v any, stack []byte)) Option {
+	return func(s *Server) {
+		s.recoverPanics = true
+		s.panicHook = fn
+	}
+}
+
+// recoverPanic is deferred by ServeHTTP if the Server recovers panics.
+func (s *Server) recoverPanic(w http.ResponseWriter, r *http.Request, rec *responseRecorder) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	stack := debug.Stack()
+	s.log(r.Context(), slog.LevelError, "panic serving request",
+		"path", r.URL.Path, "panic", v, "stack", string(stack))
+	if rec.status == 0 {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+	if s.panicHook != nil {
+		s.panicHook(r, v, stack)
+	}
+}
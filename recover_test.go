@@ -0,0 +1,88 @@
+package assetserver
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// panicFS is a file system that panics when opening certain files.
+type panicFS struct {
+	fs.FS
+	panics map[string]any
+}
+
+func (fsys panicFS) Open(name string) (fs.File, error) {
+	if v, ok := fsys.panics[name]; ok {
+		panic(v)
+	}
+	return fsys.FS.Open(name)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	fsys := panicFS{
+		FS: fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+			"b.txt": &fstest.MapFile{Data: []byte("b")},
+		},
+		panics: map[string]any{"b.txt": "kaboom"},
+	}
+	var (
+		gotPath  string
+		gotValue any
+		gotStack string
+		statuses []int
+	)
+	s := New(fsys,
+		WithPanicRecovery(func(r *http.Request, v any, stack []byte) {
+			gotPath = r.URL.Path
+			gotValue = v
+			gotStack = string(stack)
+		}),
+		WithMetricsHooks(MetricsHooks{
+			Request: func(status int, _ int64) { statuses = append(statuses, status) },
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+	if w.Code != 200 || gotValue != nil {
+		t.Fatalf("got status %d and panic %v for a.txt", w.Code, gotValue)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/b.txt", nil))
+	if w.Code != 500 {
+		t.Errorf("got status %d; want 500", w.Code)
+	}
+	if gotPath != "/b.txt" || gotValue != "kaboom" {
+		t.Errorf("panic hook got (%q, %v); want (/b.txt, kaboom)", gotPath, gotValue)
+	}
+	if !strings.Contains(gotStack, "panicFS.Open") {
+		t.Errorf("stack does not include the panicking function:\n%s", gotStack)
+	}
+	if len(statuses) != 2 || statuses[1] != 500 {
+		t.Errorf("got reported statuses %v; want [200 500]", statuses)
+	}
+}
+
+func TestPanicRecoveryAbortHandler(t *testing.T) {
+	fsys := panicFS{
+		FS:     fstest.MapFS{},
+		panics: map[string]any{"a.txt": http.ErrAbortHandler},
+	}
+	var called bool
+	s := New(fsys, WithPanicRecovery(func(*http.Request, any, []byte) { called = true }))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("got panic %v; want http.ErrAbortHandler", v)
+		}
+		if called {
+			t.Error("panic hook called for http.ErrAbortHandler")
+		}
+	}()
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a.txt", nil))
+}
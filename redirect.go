@@ -0,0 +1,69 @@
+package assetserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// WithRedirectStatus sets the status code of the redirects the Server sends
+// for paths with a trailing slash and (with [WithCaseInsensitivePaths]) for
+// paths in the wrong case. The code must be 301, 302, 307, or 308; the
+// default is 308 Permanent Redirect, which some old clients and proxies
+// don't understand.
+func WithRedirectStatus(code int) Option {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		panic(fmt.Sprintf("assetserver: WithRedirectStatus called with non-redirect status %d", code))
+	}
+	return func(s *Server) {
+		s.redirectStatus = code
+	}
+}
+
+// WithAbsoluteRedirects makes the Server's redirects use an absolute path
+// (such as /css/style.css) in the Location header rather than one relative
+// to the request's URL (such as ../style.css), for clients and proxies which
+// mishandle relative paths.
+//
+// The path is resolved against the request's original URL (its RequestURI),
+// so it remains correct when the Server is mounted under
+// [http.StripPrefix], but not if a proxy in front of the Server rewrites
+// paths.
+func WithAbsoluteRedirects() Option {
+	return func(s *Server) {
+		s.absoluteRedirects = true
+	}
+}
+
+// redirect redirects the request to target, a URL relative to the request's
+// URL.
+func (s *Server) redirect(w http.ResponseWriter, r *http.Request, target string) {
+	if s.absoluteRedirects {
+		target = absoluteURL(r, target)
+	}
+	code := s.redirectStatus
+	if code == 0 {
+		code = http.StatusPermanentRedirect
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(code)
+}
+
+// absoluteURL resolves target, a URL relative to the request's URL, against
+// the URL that the client requested.
+func absoluteURL(r *http.Request, target string) string {
+	ref, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	base := r.URL
+	if r.RequestURI != "" {
+		if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+			base = u
+		}
+	}
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,58 @@
+package assetserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRedirects(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css":   &fstest.MapFile{Data: []byte("body{}")},
+		"images/logo.png": &fstest.MapFile{Data: []byte("png")},
+	}
+	for _, tt := range []struct {
+		opts     []Option
+		prefix   string // mount the Server under http.StripPrefix
+		path     string
+		status   int
+		location string
+	}{
+		{nil, "", "/css/style.css/", 308, "../style.css"},
+		{[]Option{WithRedirectStatus(301)}, "", "/css/style.css/", 301, "../style.css"},
+		{[]Option{WithRedirectStatus(302)}, "", "/css/style.css/?v=1", 302, "../style.css?v=1"},
+		{[]Option{WithAbsoluteRedirects()}, "", "/css/style.css/", 308, "/css/style.css"},
+		{[]Option{WithAbsoluteRedirects()}, "", "/css/style.css/?v=1", 308, "/css/style.css?v=1"},
+		{[]Option{WithAbsoluteRedirects()}, "/static", "/static/css/style.css/", 308, "/static/css/style.css"},
+		{nil, "/static", "/static/css/style.css/", 308, "../style.css"},
+		{
+			[]Option{WithCaseInsensitivePaths(), WithAbsoluteRedirects(), WithRedirectStatus(301)},
+			"/static", "/static/Images/Logo.PNG", 301, "/static/images/logo.png",
+		},
+	} {
+		s := New(fsys, tt.opts...)
+		var h http.Handler = s
+		if tt.prefix != "" {
+			h = http.StripPrefix(tt.prefix, s)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Location"); got != tt.location {
+			t.Errorf("GET %s: got Location %q; want %q", tt.path, got, tt.location)
+		}
+		s.Close()
+	}
+}
+
+func TestRedirectStatusPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithRedirectStatus(200) didn't panic")
+		}
+	}()
+	WithRedirectStatus(200)
+}
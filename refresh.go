@@ -0,0 +1,41 @@
+package assetserver
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// WithRefreshHeader makes the Server discard its cached information about a
+// requested file, and hash the file again, when the request has the named
+// header with a non-empty value. For example, with
+//
+//	WithRefreshHeader("X-Asset-Refresh")
+//
+// a request made with
+//
+//	curl -H 'X-Asset-Refresh: 1' https://example.com/static/app.js
+//
+// serves app.js as if the Server had never seen it before. This is useful for
+// investigating reports of stale content without restarting the server.
+//
+// Since anyone who can send requests to the Server can make it hash files,
+// this option is best limited to development or internal deployments.
+func WithRefreshHeader(header string) Option {
+	return func(s *Server) {
+		if header == "" {
+			panic("assetserver: WithRefreshHeader called with empty header name")
+		}
+		s.refreshHeader = http.CanonicalHeaderKey(header)
+	}
+}
+
+// maybeRefresh discards the cached info for the named file if the request
+// asks for that using the refresh header.
+func (s *Server) maybeRefresh(r *http.Request, name string) {
+	if s.refreshHeader == "" || r.Header.Get(s.refreshHeader) == "" {
+		return
+	}
+	if s.cache.evict(name) {
+		s.log(r.Context(), slog.LevelInfo, "discarded cached info at client's request", "name", name)
+	}
+}
@@ -0,0 +1,58 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRefreshHeader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+	}
+	var hashes int
+	s := New(fsys,
+		WithImmutableFS(),
+		WithRefreshHeader("x-asset-refresh"),
+		WithMetricsHooks(MetricsHooks{HashStart: func() { hashes++ }}),
+	)
+	for _, tt := range []struct {
+		refresh    string
+		wantHashes int
+	}{
+		{"", 1},
+		{"", 1},
+		{"1", 2},
+		{"", 2},
+		{"yes", 3},
+	} {
+		r := httptest.NewRequest("GET", "/a.txt", nil)
+		if tt.refresh != "" {
+			r.Header.Set("X-Asset-Refresh", tt.refresh)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Fatalf("got status %d", w.Code)
+		}
+		if hashes != tt.wantHashes {
+			t.Fatalf("after request with refresh header %q, got %d hashes; want %d",
+				tt.refresh, hashes, tt.wantHashes)
+		}
+	}
+}
+
+func TestRefreshHeaderDisabled(t *testing.T) {
+	var hashes int
+	s := New(fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+	}, WithMetricsHooks(MetricsHooks{HashStart: func() { hashes++ }}))
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "/a.txt", nil)
+		r.Header.Set("X-Asset-Refresh", "1")
+		s.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if hashes != 1 {
+		t.Errorf("got %d hashes; want 1", hashes)
+	}
+}
@@ -0,0 +1,20 @@
+package assetserver
+
+import "net/http"
+
+// WithReleaseHeader makes the Server add the named header, with the given
+// value, to all of its responses. The value identifies the deployment (such
+// as a version number or commit hash) so that a response observed in a
+// browser or CDN cache can be traced back to the release that served it. For
+// example,
+//
+//	WithReleaseHeader("X-Asset-Release", "2024-06-01.3")
+func WithReleaseHeader(header, release string) Option {
+	return func(s *Server) {
+		if header == "" {
+			panic("assetserver: WithReleaseHeader called with empty header name")
+		}
+		s.releaseHeader = http.CanonicalHeaderKey(header)
+		s.release = release
+	}
+}
@@ -0,0 +1,33 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReleaseHeader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a")},
+	}
+	s := New(fsys, WithReleaseHeader("x-asset-release", "2024-06-01.3"))
+	for _, tt := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"GET", "/a.css", 200},
+		{"HEAD", "/a.css", 200},
+		{"GET", "/b.css", 404},
+		{"POST", "/a.css", 405},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s %s: got status %d; want %d", tt.method, tt.path, w.Code, tt.code)
+		}
+		if got, want := w.Header().Get("X-Asset-Release"), "2024-06-01.3"; got != want {
+			t.Errorf("%s %s: got X-Asset-Release %q; want %q", tt.method, tt.path, got, want)
+		}
+	}
+}
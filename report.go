@@ -0,0 +1,151 @@
+package assetserver
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// reportLargest is the number of files listed in Report.Largest.
+const reportLargest = 10
+
+// A Report summarizes the files served by a Server. See [Server.Report].
+type Report struct {
+	// Files and Bytes are the number of files and their total size. The
+	// size of a transformed file is the size of its transformed contents.
+	Files int
+	Bytes int64
+	// ByType breaks down the files by media type (the Content-Type
+	// without parameters, such as "text/css").
+	ByType map[string]ReportStats
+	// Largest lists the largest files, largest first.
+	Largest []ReportFile
+	// Precompressed breaks down the precompressed versions of files by
+	// encoding ("br" or "gzip"), if the Server was created with
+	// [WithPrecompressed]. The precompressed versions are not counted as
+	// files in their own right.
+	Precompressed map[string]CompressionStats
+}
+
+// ReportStats are the number and total size of a set of files.
+type ReportStats struct {
+	Files int
+	Bytes int64
+}
+
+// ReportFile describes a file in a [Report].
+type ReportFile struct {
+	Name        string
+	Bytes       int64
+	ContentType string
+}
+
+// CompressionStats describe the precompressed versions of files in a
+// [Report] which use one encoding.
+type CompressionStats struct {
+	// Files is the number of files with a precompressed version.
+	Files int
+	// Bytes and CompressedBytes are the total sizes of those files and
+	// of their precompressed versions.
+	Bytes           int64
+	CompressedBytes int64
+}
+
+// Ratio returns the ratio of the compressed size to the original size, or 0
+// if there are no files.
+func (cs CompressionStats) Ratio() float64 {
+	if cs.Bytes == 0 {
+		return 0
+	}
+	return float64(cs.CompressedBytes) / float64(cs.Bytes)
+}
+
+// Report walks the Server's file system and returns statistics about its
+// files, such as the total size by media type and the largest files, for
+// display on admin pages or for checks during deployment (for instance, to
+// fail a deploy which makes the JavaScript much larger). Like
+// [Server.Preload], Report reads every file whose information isn't cached.
+//
+// If ctx is canceled, Report stops early and returns the context's error.
+func (s *Server) Report(ctx context.Context) (*Report, error) {
+	var names []string
+	exists := make(map[string]bool)
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, name)
+			exists[name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rep := &Report{ByType: make(map[string]ReportStats)}
+	if s.precompressed != nil {
+		rep.Precompressed = make(map[string]CompressionStats)
+	}
+	for _, name := range names {
+		if s.isPrecompressedVersion(name, exists) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := s.currentInfo(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		size := info.contentLength()
+		rep.Files++
+		rep.Bytes += size
+		mediaType, _, _ := strings.Cut(info.contentType, ";")
+		ts := rep.ByType[mediaType]
+		ts.Files++
+		ts.Bytes += size
+		rep.ByType[mediaType] = ts
+		rep.Largest = append(rep.Largest, ReportFile{Name: name, Bytes: size, ContentType: info.contentType})
+
+		if !s.precompressed.applies(name) || info.body != nil {
+			continue
+		}
+		for _, pe := range precompressedEncodings {
+			if !exists[name+pe.suffix] {
+				continue
+			}
+			fi, err := fs.Stat(s.fsys, name+pe.suffix)
+			if err != nil || fi.ModTime().UnixNano() < info.mtime {
+				continue
+			}
+			cs := rep.Precompressed[pe.encoding]
+			cs.Files++
+			cs.Bytes += size
+			cs.CompressedBytes += fi.Size()
+			rep.Precompressed[pe.encoding] = cs
+		}
+	}
+	sort.SliceStable(rep.Largest, func(i, j int) bool {
+		return rep.Largest[i].Bytes > rep.Largest[j].Bytes
+	})
+	if len(rep.Largest) > reportLargest {
+		rep.Largest = rep.Largest[:reportLargest]
+	}
+	return rep, nil
+}
+
+// isPrecompressedVersion reports whether the named file is a precompressed
+// version of another file (in exists) which the Server serves.
+func (s *Server) isPrecompressedVersion(name string, exists map[string]bool) bool {
+	for _, pe := range precompressedEncodings {
+		if orig, ok := strings.CutSuffix(name, pe.suffix); ok && exists[orig] && s.precompressed.applies(orig) {
+			return true
+		}
+	}
+	return false
+}
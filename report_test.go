@@ -0,0 +1,66 @@
+package assetserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":       &fstest.MapFile{Data: []byte(strings.Repeat("a", 1000))},
+		"app.js.br":    &fstest.MapFile{Data: []byte(strings.Repeat("b", 100))},
+		"app.js.gz":    &fstest.MapFile{Data: []byte(strings.Repeat("g", 200))},
+		"style.css":    &fstest.MapFile{Data: []byte(strings.Repeat("s", 300))},
+		"logo.png":     &fstest.MapFile{Data: testPNG(t, 4, 4)},
+		"data.json.br": &fstest.MapFile{Data: []byte("orphan")},
+	}
+	for i := 0; i < 12; i++ {
+		fsys[fmt.Sprintf("img/%02d.svg", i)] = &fstest.MapFile{Data: []byte(strings.Repeat("x", i))}
+	}
+	s := New(fsys, WithPrecompressed("**/*.js", "**/*.json"))
+	rep, err := s.Report(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	png := int64(len(fsys["logo.png"].Data))
+	if rep.Files != 16 {
+		t.Errorf("got %d files; want 16", rep.Files)
+	}
+	if want := 1000 + 300 + png + 6 + 66; rep.Bytes != want {
+		t.Errorf("got %d bytes; want %d", rep.Bytes, want)
+	}
+	wantTypes := map[string]ReportStats{
+		"text/javascript": {1, 1000},
+		"text/css":        {1, 300},
+		"image/png":       {1, png},
+		"image/svg+xml":   {12, 66},
+		"text/plain":      {1, 6},
+	}
+	if diff := cmp.Diff(wantTypes, rep.ByType); diff != "" {
+		t.Errorf("ByType (-want +got):\n%s", diff)
+	}
+	if len(rep.Largest) != 10 {
+		t.Fatalf("got %d largest files; want 10", len(rep.Largest))
+	}
+	if got := rep.Largest[0]; got.Name != "app.js" || got.Bytes != 1000 || got.ContentType != "text/javascript; charset=utf-8" {
+		t.Errorf("largest file: got %+v", got)
+	}
+	if got := rep.Largest[9].Name; got != "img/06.svg" {
+		t.Errorf("10th largest file: got %s; want img/06.svg", got)
+	}
+	wantCompressed := map[string]CompressionStats{
+		"br":   {1, 1000, 100},
+		"gzip": {1, 1000, 200},
+	}
+	if diff := cmp.Diff(wantCompressed, rep.Precompressed); diff != "" {
+		t.Errorf("Precompressed (-want +got):\n%s", diff)
+	}
+	if got := rep.Precompressed["br"].Ratio(); got != 0.1 {
+		t.Errorf("br ratio: got %g; want 0.1", got)
+	}
+}
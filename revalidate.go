@@ -0,0 +1,107 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"time"
+)
+
+// WithRevalidateInterval changes how the Server notices that files have
+// changed. Normally, the Server calls Stat for every request (and every call
+// to Tag) to check whether the cached information for the file (such as its
+// tag) is up to date. With this option, the cached information is trusted
+// as-is and a background goroutine checks every cached file for changes every
+// interval d, hashing any files which have changed and forgetting any which
+// have been deleted.
+//
+// This removes a system call from the hot path in exchange for serving
+// outdated information for up to d after a file changes. It is intended for
+// disk-backed deployments with modest freshness requirements. (For file systems
+// that never change, use [WithImmutableFS] instead.)
+//
+// The background goroutine runs until [Server.Close] is called.
+func WithRevalidateInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.revalidate = d
+	}
+}
+
+// trustCache reports whether cached info may be used without checking that
+// it is up to date.
+func (s *Server) trustCache() bool {
+	return s.immutable || s.revalidate > 0
+}
+
+func (s *Server) revalidateLoop(d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.revalidateAll()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// revalidateAll brings the cached info for every file up to date.
+func (s *Server) revalidateAll() {
+	for _, name := range s.cache.names() {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		// Errors other than a missing file leave the cached info alone;
+		// they are likely to be transient, and if not, requests that
+		// don't trust the cache will encounter them.
+		s.revalidateFile(name)
+	}
+}
+
+func (s *Server) revalidateFile(name string) error {
+	fi, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			s.evict(name)
+		}
+		return err
+	}
+	if fi.IsDir() {
+		s.evict(name)
+		return nil
+	}
+	e := s.cache.peek(name)
+	if e == nil {
+		return nil
+	}
+	return s.updateEntry(e, name, fi)
+}
+
+// updateEntry brings the info in e for the named file, described by fi, up to
+// date, hashing the file if it has changed.
+func (s *Server) updateEntry(e *cacheEntry, name string, fi fs.FileInfo) error {
+	old := e.Load()
+	if old != nil && old.matches(fi) && s.depsCurrent(context.Background(), old) {
+		e.markValidated()
+		return nil
+	}
+	f, err := s.openWhole(name, fi)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := s.readInfo(context.Background(), name, f)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		s.log(context.Background(), slog.LevelInfo, "file changed; revalidated cached info",
+			"name", name, "old_tag", old.tag, "tag", info.tag)
+	}
+	s.storeInfo(e, name, info)
+	return nil
+}
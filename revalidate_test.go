@@ -0,0 +1,83 @@
+package assetserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/renameio"
+)
+
+func TestRevalidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, text string) {
+		t.Helper()
+		if err := renameio.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a.txt", "a0")
+	writeFile("b.txt", "b0")
+	s := New(os.DirFS(dir), WithRevalidateInterval(time.Hour))
+	defer s.Close()
+	tag := func(name string) string {
+		t.Helper()
+		tagged, err := s.Tag(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tagged
+	}
+	a0 := tag("a.txt")
+	tag("b.txt")
+
+	// Changes are not noticed until revalidation.
+	writeFile("a.txt", "a11")
+	if err := os.Remove(filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got := tag("a.txt"); got != a0 {
+		t.Fatalf("before revalidation, got tag %q; want %q", got, a0)
+	}
+	tag("b.txt")
+
+	s.revalidateAll()
+	if got, want := tag("a.txt"), "a."+hashTag("a11")+".txt"; got != want {
+		t.Fatalf("after revalidation, got tag %q; want %q", got, want)
+	}
+	if _, err := s.Tag("b.txt"); err == nil {
+		t.Fatal("after revalidation, Tag of deleted file returned nil error")
+	}
+}
+
+func TestRevalidateInterval(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	if err := renameio.WriteFile(name, []byte("a0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(os.DirFS(dir), WithRevalidateInterval(time.Millisecond))
+	defer s.Close()
+	if _, err := s.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := renameio.WriteFile(name, []byte("a11"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := "a." + hashTag("a11") + ".txt"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := s.Tag("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for background revalidation")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
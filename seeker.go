@@ -0,0 +1,126 @@
+package assetserver
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+)
+
+type seekerFile interface {
+	fs.File
+	io.Seeker
+}
+
+// maxMemBuffer is the size above which the contents of a file which can't seek
+// are buffered in a temporary file rather than in memory.
+const maxMemBuffer = 1 << 20
+
+// toSeeker returns f as a seekerFile. If f doesn't implement io.Seeker, the
+// result reads from f using io.ReaderAt, if possible, or else from a copy of
+// the contents of f. Closing the result closes f.
+func toSeeker(f fs.File) (seekerFile, error) {
+	if sf, ok := f.(seekerFile); ok {
+		return sf, nil
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if ra, ok := f.(io.ReaderAt); ok {
+		return &seekableFile{File: f, rs: io.NewSectionReader(ra, 0, stat.Size())}, nil
+	}
+	if stat.Size() <= maxMemBuffer {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		return &seekableFile{File: f, rs: bytes.NewReader(b)}, nil
+	}
+	tmp, err := os.CreateTemp("", "assetserver-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, f); err != nil {
+		removeTemp(tmp)
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		removeTemp(tmp)
+		return nil, err
+	}
+	return &seekableFile{File: f, rs: tmp, tmp: tmp}, nil
+}
+
+// A seekableFile adds seeking to a file which doesn't support it by reading
+// from some other io.ReadSeeker.
+type seekableFile struct {
+	fs.File // for Stat and Close
+	rs      io.ReadSeeker
+	tmp     *os.File // if non-nil, the temporary file underlying rs
+}
+
+func (f *seekableFile) Read(b []byte) (int, error) {
+	return f.rs.Read(b)
+}
+
+func (f *seekableFile) Seek(offset int64, whence int) (int64, error) {
+	return f.rs.Seek(offset, whence)
+}
+
+func (f *seekableFile) Close() error {
+	if f.tmp != nil {
+		removeTemp(f.tmp)
+	}
+	return f.File.Close()
+}
+
+func removeTemp(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// readsWhole reports whether the Server reads the file described by fi whole,
+// using fs.ReadFile, to hash it. It does so if the file system implements
+// fs.ReadFileFS (which for some file systems, such as ones backed by object
+// stores, is much cheaper than Open followed by Stat and Read) and the file
+// is small enough to hold in memory.
+func (s *Server) readsWhole(fi fs.FileInfo) bool {
+	_, ok := s.fsys.(fs.ReadFileFS)
+	return ok && fi.Size() <= maxMemBuffer
+}
+
+// openWhole returns the named file, described by fi, as a seekerFile for
+// hashing. If readsWhole reports true for fi, the file is read with ReadFile
+// rather than opened; if its size doesn't match fi (because it is changing),
+// it is opened instead.
+func (s *Server) openWhole(name string, fi fs.FileInfo) (seekerFile, error) {
+	if s.readsWhole(fi) {
+		b, err := s.fsys.(fs.ReadFileFS).ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(b)) == fi.Size() {
+			return &memFile{Reader: bytes.NewReader(b), fi: fi}, nil
+		}
+	}
+	fv, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := toSeeker(fv)
+	if err != nil {
+		fv.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// A memFile is a file whose contents were read with ReadFile.
+type memFile struct {
+	*bytes.Reader
+	fi fs.FileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *memFile) Close() error               { return nil }
@@ -0,0 +1,95 @@
+package assetserver
+
+import (
+	"io"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cespare/webtest"
+)
+
+func TestServeHTTPNoSeek(t *testing.T) {
+	s := New(noSeekFS{FS: os.DirFS("testdata/assets")})
+	webtest.TestHandler(t, "testdata/servehttp.txt", s)
+}
+
+func TestServeHTTPReaderAt(t *testing.T) {
+	// The files of a MapFS implement io.ReaderAt.
+	fsys := make(fstest.MapFS)
+	err := fs.WalkDir(os.DirFS("testdata/assets"), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile("testdata/assets/" + name)
+		if err != nil {
+			return err
+		}
+		fsys[name] = &fstest.MapFile{Data: b}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(noSeekFS{FS: fsys, readerAt: true})
+	webtest.TestHandler(t, "testdata/servehttp.txt", s)
+}
+
+// Check that a large file, which is buffered in a temporary file, can be
+// served (including ranges).
+func TestServeLargeFileNoSeek(t *testing.T) {
+	content := make([]byte, maxMemBuffer+100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	fsys := noSeekFS{FS: fstest.MapFS{
+		"f": &fstest.MapFile{Data: content},
+	}}
+	s := New(fsys)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/f", nil)
+	s.ServeHTTP(w, req)
+	resp := w.Result()
+	checkResponseCode(t, resp, 200)
+	checkResponseBody(t, resp, content)
+	checkResponseHeader(t, resp, "ETag", `"`+hashTag(string(content))+`"`)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/f", nil)
+	req.Header.Set("Range", "bytes=1048576-1048675")
+	s.ServeHTTP(w, req)
+	resp = w.Result()
+	checkResponseCode(t, resp, 206)
+	checkResponseBody(t, resp, content[maxMemBuffer:])
+}
+
+// noSeekFS wraps an fs.FS such that its files don't implement io.Seeker.
+// They implement io.ReaderAt only if readerAt is set (and the underlying file
+// implements it).
+type noSeekFS struct {
+	fs.FS
+	readerAt bool
+}
+
+func (fsys noSeekFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if ra, ok := f.(io.ReaderAt); ok && fsys.readerAt {
+		return readerAtFile{f, ra}, nil
+	}
+	return noSeekFile{f}, nil
+}
+
+type noSeekFile struct {
+	fs.File
+}
+
+type readerAtFile struct {
+	fs.File
+	io.ReaderAt
+}
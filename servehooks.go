@@ -0,0 +1,62 @@
+package assetserver
+
+import "net/http"
+
+// ServeHooks contains functions that a Server calls around writing the
+// response for each request that it resolves to a file (that is, each
+// request for which [AssetInfoFromContext] reports an AssetInfo). Requests
+// rejected before a file is found, such as those receiving 404 Not Found,
+// don't cause calls to the hooks. Any of the functions may be nil. They are
+// called synchronously by ServeHTTP, so they must be fast and safe for
+// concurrent use.
+//
+// The hooks are useful for accounting that must distinguish assets from
+// other responses, such as tracking the bandwidth used by each tenant (see
+// [WithFSSelector]).
+type ServeHooks struct {
+	// Start is called once the status code and header of the response
+	// are determined, before the header is written and the body is sent.
+	Start func(r *http.Request, info *AssetInfo, status int, header http.Header)
+	// Done is called after the response body is sent (or the attempt to
+	// send it fails), with the number of body bytes written.
+	Done func(r *http.Request, info *AssetInfo, status int, bytes int64)
+}
+
+// WithServeHooks registers functions to be called by the Server around
+// writing responses for files. WithServeHooks may be given more than once.
+func WithServeHooks(hooks ServeHooks) Option {
+	return func(s *Server) {
+		s.serveHooks = append(s.serveHooks, hooks)
+	}
+}
+
+// watchServe arranges for the serve hooks to be called for the response
+// to r, which is recorded by rec. The caller must call the returned function
+// once the response is complete.
+func (s *Server) watchServe(r *http.Request, rec *responseRecorder) func() {
+	rec.onStatus = func(status int) {
+		info := AssetInfoFromContext(r.Context())
+		if info == nil {
+			return
+		}
+		for _, hooks := range s.serveHooks {
+			if hooks.Start != nil {
+				hooks.Start(r, info, status, rec.Header())
+			}
+		}
+	}
+	return func() {
+		// If nothing was written, net/http sends a 200 with an
+		// empty body once the handler returns.
+		rec.setStatus(http.StatusOK)
+		info := AssetInfoFromContext(r.Context())
+		if info == nil {
+			return
+		}
+		for _, hooks := range s.serveHooks {
+			if hooks.Done != nil {
+				hooks.Done(r, info, rec.status, rec.bytes)
+			}
+		}
+	}
+}
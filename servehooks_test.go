@@ -0,0 +1,63 @@
+package assetserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeHooks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"style.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	var events []string
+	s := New(fsys, WithServeHooks(ServeHooks{
+		Start: func(r *http.Request, info *AssetInfo, status int, header http.Header) {
+			events = append(events, fmt.Sprintf("start %s %s %d %s", r.Method, info.Name, status, header.Get("Content-Type")))
+		},
+		Done: func(r *http.Request, info *AssetInfo, status int, bytes int64) {
+			events = append(events, fmt.Sprintf("done %s %s %d %d", r.Method, info.Name, status, bytes))
+		},
+	}))
+	defer s.Close()
+	tagged, err := s.Tag("style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, _ := removeTag(tagged)
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		header string // If-None-Match
+		want   []string
+	}{
+		{
+			"GET", "/style.css", "",
+			[]string{"start GET style.css 200 text/css; charset=utf-8", "done GET style.css 200 6"},
+		},
+		{
+			"HEAD", "/" + tagged, "",
+			[]string{"start HEAD style.css 200 text/css; charset=utf-8", "done HEAD style.css 200 0"},
+		},
+		{
+			"GET", "/style.css", `"` + tag + `"`,
+			[]string{"start GET style.css 304 ", "done GET style.css 304 0"},
+		},
+		{"GET", "/missing.css", "", nil},
+		{"POST", "/style.css", "", nil},
+	} {
+		events = nil
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("If-None-Match", tt.header)
+		}
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		if !reflect.DeepEqual(events, tt.want) {
+			t.Errorf("%s %s: got events %q; want %q", tt.method, tt.path, events, tt.want)
+		}
+	}
+}
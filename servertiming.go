@@ -0,0 +1,148 @@
+package assetserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithServerTiming makes the Server add a Server-Timing header to its
+// responses reporting how long it spent in each phase of handling the
+// request, so that browser developer tools and performance audits can show
+// where time goes. The metrics are:
+//
+//   - cache: whether the file's information was found in the cache (with a
+//     description of "hit" or "miss")
+//   - stat: the time taken to stat the file
+//   - open: the time taken to open the file
+//   - hash: the time taken to hash the file (or to wait for a concurrent
+//     request to hash it)
+//
+// Metrics are omitted for phases that didn't happen. The time spent sending
+// the response body is not included since the header must be written first.
+func WithServerTiming() Option {
+	return func(s *Server) {
+		s.serverTiming = true
+	}
+}
+
+// A timingPhase is one of the phases reported by the Server-Timing header.
+type timingPhase int
+
+const (
+	phaseStat timingPhase = iota
+	phaseOpen
+	phaseHash
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"stat", "open", "hash"}
+
+// A serverTiming records the phases of a request for the Server-Timing
+// header. A nil *serverTiming ignores everything.
+type serverTiming struct {
+	lookup string // "hit", "miss", or ""
+	phases [numPhases]time.Duration
+}
+
+type serverTimingKey struct{}
+
+// timing returns the serverTiming for the request with context ctx, or nil
+// if there isn't one.
+func (s *Server) timing(ctx context.Context) *serverTiming {
+	if !s.serverTiming {
+		return nil
+	}
+	t, _ := ctx.Value(serverTimingKey{}).(*serverTiming)
+	return t
+}
+
+// start returns the current time if t records anything.
+func (t *serverTiming) start() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// add records the time since start (as returned by t.start) for a phase.
+func (t *serverTiming) add(phase timingPhase, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.phases[phase] += time.Since(start)
+}
+
+func (t *serverTiming) cacheLookup(hit bool) {
+	if t == nil {
+		return
+	}
+	if hit {
+		t.lookup = "hit"
+	} else {
+		t.lookup = "miss"
+	}
+}
+
+func (t *serverTiming) header() string {
+	var metrics []string
+	if t.lookup != "" {
+		metrics = append(metrics, `cache;desc="`+t.lookup+`"`)
+	}
+	for phase, d := range t.phases {
+		if d > 0 {
+			ms := float64(d.Microseconds()) / 1000
+			metrics = append(metrics, phaseNames[phase]+";dur="+strconv.FormatFloat(ms, 'f', -1, 64))
+		}
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// A timingWriter wraps a ResponseWriter to add the Server-Timing header just
+// before the response header is written.
+type timingWriter struct {
+	http.ResponseWriter
+	t           *serverTiming
+	wroteHeader bool
+}
+
+func (w *timingWriter) writeTiming() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if v := w.t.header(); v != "" {
+		w.Header().Set("Server-Timing", v)
+	}
+}
+
+func (w *timingWriter) WriteHeader(status int) {
+	if status >= 200 {
+		w.writeTiming()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	w.writeTiming()
+	return w.ResponseWriter.Write(b)
+}
+
+// ReadFrom passes through to the underlying ResponseWriter's ReadFrom method,
+// if it has one, so that net/http can still use sendfile.
+func (w *timingWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.writeTiming()
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Unwrap returns the underlying ResponseWriter for the benefit of
+// http.ResponseController.
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,95 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServerTiming(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello")},
+	}
+	s := New(fsys, WithServerTiming())
+	etag := `"` + hashTag("hello") + `"`
+	for _, tt := range []struct {
+		name    string
+		target  string
+		inm     string
+		code    int
+		metrics []string
+	}{
+		{"first request", "/a.txt", "", 200, []string{"cache", "open", "stat", "hash"}},
+		{"cached", "/a.txt", "", 200, []string{"cache", "open", "stat"}},
+		{"not modified", "/a.txt", etag, 304, []string{"cache", "stat"}},
+		{"not found", "/b.txt", "", 404, []string{"open"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.inm != "" {
+				r.Header.Set("If-None-Match", tt.inm)
+			}
+			s.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d; want %d", w.Code, tt.code)
+			}
+			got := parseServerTiming(t, w.Header().Get("Server-Timing"))
+			if len(got) != len(tt.metrics) {
+				t.Errorf("got metrics %q; want %q", got, tt.metrics)
+			}
+			for _, m := range tt.metrics {
+				if _, ok := got[m]; !ok {
+					t.Errorf("missing metric %q (got %q)", m, got)
+				}
+			}
+		})
+	}
+}
+
+func TestServerTimingCacheLookup(t *testing.T) {
+	s := New(fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello")},
+	}, WithServerTiming())
+	for _, want := range []string{`desc="miss"`, `desc="hit"`} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+		got := parseServerTiming(t, w.Header().Get("Server-Timing"))["cache"]
+		if got != want {
+			t.Errorf("got cache metric %q; want %q", got, want)
+		}
+	}
+}
+
+func TestNoServerTiming(t *testing.T) {
+	s := New(fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello")},
+	})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+	if got := w.Header().Get("Server-Timing"); got != "" {
+		t.Errorf("got Server-Timing header %q; want none", got)
+	}
+}
+
+var serverTimingMetric = regexp.MustCompile(`^([a-z]+)(?:;(desc="[a-z]+"|dur=[0-9.]+))?$`)
+
+// parseServerTiming parses a Server-Timing header into a map from metric name
+// to its parameter.
+func parseServerTiming(t *testing.T, header string) map[string]string {
+	t.Helper()
+	metrics := make(map[string]string)
+	if header == "" {
+		return metrics
+	}
+	for _, m := range strings.Split(header, ", ") {
+		match := serverTimingMetric.FindStringSubmatch(m)
+		if match == nil {
+			t.Fatalf("malformed Server-Timing metric %q", m)
+		}
+		metrics[match[1]] = match[2]
+	}
+	return metrics
+}
@@ -0,0 +1,147 @@
+package assetserver
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// A SharedCache holds the information (such as tags) that Servers compute
+// about the files they serve, so that several Servers for the same file
+// system can share it. See [WithSharedCache].
+type SharedCache struct {
+	cache *infoCache
+	loads singleflight.Group
+
+	mu     sync.Mutex
+	used   bool
+	fsys   fs.FS
+	config string
+}
+
+// NewSharedCache creates a SharedCache which holds information about at most
+// maxEntries files, or an unlimited number if maxEntries is 0. (See
+// [WithMaxCacheEntries].)
+func NewSharedCache(maxEntries int) *SharedCache {
+	if maxEntries < 0 {
+		panic("assetserver: NewSharedCache called with maxEntries < 0")
+	}
+	return &SharedCache{cache: newInfoCache(maxEntries)}
+}
+
+// WithSharedCache makes the Server keep the information it computes about
+// its files in c rather than in a cache of its own. An application which
+// serves the same files through several Servers (with different options, or
+// mounted under different prefixes) can give them all the same SharedCache
+// so that each file is only hashed once, rather than once per Server.
+//
+// Every Server using c must be created with the same file system, by the
+// same constructor (New and NewNoCache compute different information), and
+// with the same options that affect the information about files: those which
+// change their contents or tags, such as [WithTransform], [WithBundle],
+// [WithContentSniffer], [WithPartialHashing], and [WithContentTypeInTag].
+// New panics if any of these differ from those of the first Server to use c.
+// (Transformers are compared by value, except that pointers, such as those
+// returned by [NewCommandTransformer], are compared by identity, and
+// functions, such as sniffers and TransformFuncs, are compared by their code,
+// so two closures of the same function literal are taken to be the same.)
+//
+// The things a Server does when it hashes a file (such as calling the hooks
+// given to [WithMetricsHooks] and [WithChangeHook], or recording versions
+// for [WithVersionHistory]) are only done by the Server that did the
+// hashing. WithSharedCache cannot be combined with [WithMaxCacheEntries]
+// (give the limit to [NewSharedCache] instead) or [WithCacheFile]. Tenants
+// and generations (see [WithFSSelector] and [Server.BeginGeneration]) have
+// their own caches.
+func WithSharedCache(c *SharedCache) Option {
+	if c == nil {
+		panic("assetserver: WithSharedCache called with nil SharedCache")
+	}
+	return func(s *Server) {
+		s.sharedCache = c
+	}
+}
+
+// attach checks that the Server s, whose file system is fsys, may use the
+// cache and records the settings of the first Server to do so.
+func (c *SharedCache) attach(s *Server, fsys fs.FS) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	config := s.sharedCacheConfig()
+	if !c.used {
+		c.used = true
+		c.fsys = fsys
+		c.config = config
+		return
+	}
+	if !sameFS(fsys, c.fsys) {
+		panic("assetserver: WithSharedCache used by Servers with different file systems")
+	}
+	if config != c.config {
+		panic("assetserver: WithSharedCache used by Servers with different options for file contents or tags")
+	}
+}
+
+// sharedCacheConfig describes the Server's settings which affect the
+// information it computes about its files, for comparison with those of the
+// other Servers using its SharedCache.
+func (s *Server) sharedCacheConfig() string {
+	config := s.tagConfig()
+	if s.noCache {
+		config += "nocache;"
+	}
+	if ts := s.transforms; ts != nil {
+		for _, tr := range ts.list {
+			config += fmt.Sprintf("transform:%s:%q;", valueID(tr.t), tr.patterns)
+		}
+	}
+	fsys := s.fsys
+	if sfs, ok := fsys.(*symlinkFS); ok {
+		fsys = sfs.FS
+	}
+	if bfs, ok := fsys.(*bundleFS); ok {
+		names := make([]string, 0, len(bfs.bundles))
+		for name := range bfs.bundles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			config += fmt.Sprintf("bundle:%q:%q;", name, bfs.bundles[name])
+		}
+	}
+	return config
+}
+
+// valueID identifies v by its type and value. Functions are identified by
+// their code and pointers by their addresses, so that mutable state doesn't
+// affect the result.
+func valueID(v any) string {
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Func:
+		return fmt.Sprintf("%T@%x", v, rv.Pointer())
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		return fmt.Sprintf("%T@%p", v, v)
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// sameFS reports whether a and b are the same file system, as far as can be
+// told: file systems of incomparable types other than maps (such as
+// fstest.MapFS) are assumed to be the same if their types are.
+func sameFS(a, b fs.FS) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) {
+		return false
+	}
+	if ta.Comparable() {
+		return a == b
+	}
+	if ta.Kind() == reflect.Map {
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return true
+}
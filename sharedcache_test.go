@@ -0,0 +1,110 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSharedCache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+		"js/app.js":     &fstest.MapFile{Data: []byte("app")},
+	}
+	var hashes atomic.Int64
+	hooks := WithMetricsHooks(MetricsHooks{
+		HashStart: func() { hashes.Add(1) },
+		HashDone:  func(time.Duration, error) {},
+	})
+	c := NewSharedCache(0)
+	public := New(fsys, WithSharedCache(c), hooks)
+	defer public.Close()
+	admin := New(fsys, WithSharedCache(c), hooks, WithTimingAllowOrigin())
+	defer admin.Close()
+
+	tag, err := public.Tag("css/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := admin.Tag("css/style.css"); err != nil || got != tag {
+		t.Errorf("admin.Tag: got (%q, %v); want (%q, nil)", got, err, tag)
+	}
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest("GET", "/js/app.js", nil))
+	if w.Code != 200 {
+		t.Fatalf("GET /js/app.js: got status %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	public.ServeHTTP(w, httptest.NewRequest("GET", "/js/app.js", nil))
+	if w.Code != 200 {
+		t.Fatalf("GET /js/app.js: got status %d", w.Code)
+	}
+	if n := hashes.Load(); n != 2 {
+		t.Errorf("got %d hashes; want 2", n)
+	}
+}
+
+func TestSharedCacheMismatch(t *testing.T) {
+	fsys := fstest.MapFS{"a.js": &fstest.MapFile{Data: []byte("a")}}
+	other := fstest.MapFS{"a.js": &fstest.MapFile{Data: []byte("b")}}
+	strip := WithStripSourceMapComments()
+	for _, tt := range []struct {
+		desc    string
+		fsys    fstest.MapFS
+		noCache bool
+		opts    []Option
+		panics  bool
+	}{
+		{"same options", fsys, false, []Option{WithStripSourceMapComments()}, false},
+		{"different file system", other, false, []Option{strip}, true},
+		{"different tag options", fsys, false, []Option{strip, WithContentTypeInTag()}, true},
+		{"no transforms", fsys, false, nil, true},
+		{"different transforms", fsys, false, []Option{WithTransform(TransformFunc(upper))}, true},
+		{"no-cache", fsys, true, []Option{strip}, true},
+		{"max entries", fsys, false, []Option{strip, WithMaxCacheEntries(10)}, true},
+	} {
+		c := NewSharedCache(0)
+		New(fsys, strip, WithSharedCache(c)).Close()
+		func() {
+			defer func() {
+				if panicked := recover() != nil; panicked != tt.panics {
+					t.Errorf("%s: got panic %t; want %t", tt.desc, panicked, tt.panics)
+				}
+			}()
+			opts := append(tt.opts, WithSharedCache(c))
+			if tt.noCache {
+				NewNoCache(tt.fsys, opts...).Close()
+			} else {
+				New(tt.fsys, opts...).Close()
+			}
+		}()
+	}
+}
+
+func TestSharedCacheTransformerInstance(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	fsys := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+	tr := NewCommandTransformer(CommandOptions{Command: []string{"cat"}})
+	c := NewSharedCache(0)
+	s1 := New(fsys, WithSharedCache(c), WithTransform(tr))
+	defer s1.Close()
+	if _, err := s1.Tag("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// The same transformer may be used by another Server.
+	s2 := New(fsys, WithSharedCache(c), WithTransform(tr))
+	defer s2.Close()
+
+	other := NewCommandTransformer(CommandOptions{Command: []string{"cat"}})
+	defer func() {
+		if recover() == nil {
+			t.Error("New with a different transformer did not panic")
+		}
+	}()
+	New(fsys, WithSharedCache(c), WithTransform(other)).Close()
+}
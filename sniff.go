@@ -0,0 +1,68 @@
+package assetserver
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// defaultSniffLen is the number of bytes read to sniff a file's content type
+// by default; http.DetectContentType uses at most this many.
+const defaultSniffLen = 512
+
+// WithSniffLength sets the number of bytes at the start of a file that the
+// Server reads to determine its content type when the file's extension
+// doesn't give one. The default is 512, which is all that
+// http.DetectContentType uses, so a larger value is only useful with
+// [WithContentSniffer].
+func WithSniffLength(n int) Option {
+	if n <= 0 {
+		panic(fmt.Sprintf("assetserver: WithSniffLength called with non-positive length %d", n))
+	}
+	return func(s *Server) {
+		s.sniffLen = n
+	}
+}
+
+// WithContentSniffer makes the Server determine the content types of files
+// whose extensions don't give one by calling fn with the file's name and the
+// first bytes of its contents (see [WithSniffLength]), rather than using only
+// http.DetectContentType, which doesn't recognize some newer formats (such
+// as AVIF images). If fn returns "", the Server falls back to
+// http.DetectContentType.
+//
+// Files whose extensions have known content types (including those
+// registered with mime.AddExtensionType) aren't sniffed.
+func WithContentSniffer(fn func(name string, head []byte) string) Option {
+	return func(s *Server) {
+		s.sniffer = fn
+	}
+}
+
+// sniffLength returns the number of bytes to sniff.
+func (s *Server) sniffLength() int {
+	if s.sniffLen > 0 {
+		return s.sniffLen
+	}
+	return defaultSniffLen
+}
+
+// sniffBuffer returns a buffer of the sniff length, using hs's buffer if it
+// is large enough.
+func (s *Server) sniffBuffer(hs *hashState) []byte {
+	n := s.sniffLength()
+	if n > len(hs.buf) {
+		return make([]byte, n)
+	}
+	return hs.buf[:n]
+}
+
+// sniffContentType returns the content type of the named file, given the
+// first bytes of its contents.
+func (s *Server) sniffContentType(name string, head []byte) string {
+	if s.sniffer != nil {
+		if t := s.sniffer(name, head); t != "" {
+			return t
+		}
+	}
+	return http.DetectContentType(head)
+}
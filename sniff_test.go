@@ -0,0 +1,56 @@
+package assetserver
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func sniffAVIF(name string, head []byte) string {
+	if len(head) >= 12 && string(head[4:12]) == "ftypavif" {
+		return "image/avif"
+	}
+	return ""
+}
+
+func TestContentSniffer(t *testing.T) {
+	avif := append([]byte("\x00\x00\x00\x1cftypavif"), make([]byte, 100)...)
+	late := append(bytes.Repeat([]byte(" "), 1000), "MAGIC"...)
+	fsys := fstest.MapFS{
+		"photo":     &fstest.MapFile{Data: avif},
+		"page":      &fstest.MapFile{Data: []byte("<html><body></body></html>")},
+		"photo.txt": &fstest.MapFile{Data: avif},
+		"late":      &fstest.MapFile{Data: late},
+	}
+	sniffLate := func(name string, head []byte) string {
+		if bytes.Contains(head, []byte("MAGIC")) {
+			return "application/x-magic"
+		}
+		return ""
+	}
+	for _, tt := range []struct {
+		opts []Option
+		name string
+		want string
+	}{
+		{nil, "photo", "application/octet-stream"},
+		{[]Option{WithContentSniffer(sniffAVIF)}, "photo", "image/avif"},
+		{[]Option{WithContentSniffer(sniffAVIF)}, "page", "text/html; charset=utf-8"},
+		{[]Option{WithContentSniffer(sniffAVIF)}, "photo.txt", "text/plain; charset=utf-8"},
+		{[]Option{WithContentSniffer(sniffLate)}, "late", "text/plain; charset=utf-8"},
+		{[]Option{WithContentSniffer(sniffLate), WithSniffLength(2048)}, "late", "application/x-magic"},
+		{[]Option{WithContentSniffer(sniffLate), WithSniffLength(64 << 10)}, "late", "application/x-magic"},
+		{[]Option{WithContentSniffer(sniffAVIF), WithStreamingHash()}, "photo", "image/avif"},
+		{[]Option{WithContentSniffer(sniffLate), WithTransform(TransformFunc(upper), "late")}, "late", "text/plain; charset=utf-8"},
+		{[]Option{WithContentSniffer(sniffLate), WithTransform(TransformFunc(upper), "late"), WithSniffLength(2048)}, "late", "application/x-magic"},
+	} {
+		s := New(fsys, tt.opts...)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tt.name, nil))
+		if got := w.Header().Get("Content-Type"); got != tt.want {
+			t.Errorf("%s (%d options): got Content-Type %q; want %q", tt.name, len(tt.opts), got, tt.want)
+		}
+		s.Close()
+	}
+}
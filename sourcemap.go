@@ -0,0 +1,67 @@
+package assetserver
+
+import (
+	"context"
+	"path"
+	"regexp"
+)
+
+// WithSourceMapHeader makes the Server add a SourceMap header to responses
+// for JavaScript and CSS files which have a source map alongside them (that
+// is, a file with the same name plus ".map", such as "app.js.map" for
+// "app.js"). The header refers to the tagged name of the source map, so
+// browser developer tools find the right version of the map even as files
+// change, and the files themselves don't need a sourceMappingURL comment.
+func WithSourceMapHeader() Option {
+	return func(s *Server) {
+		s.sourceMaps = true
+	}
+}
+
+// sourceMapURL returns the URL of the source map for the named file, relative
+// to the file's URL, or "" if it doesn't have one.
+func (s *Server) sourceMapURL(ctx context.Context, name string) string {
+	switch path.Ext(name) {
+	case ".js", ".mjs", ".css":
+	default:
+		return ""
+	}
+	mapName := name + ".map"
+	info, err := s.currentInfo(ctx, mapName)
+	if err != nil {
+		return ""
+	}
+	base := path.Base(mapName)
+	if !s.noCache {
+		base = insertTag(base, info.tag)
+	}
+	return escapeRelative(base)
+}
+
+// WithStripSourceMapComments makes the Server remove sourceMappingURL
+// comments (such as "//# sourceMappingURL=app.js.map") from the files
+// matching any of the given patterns, or from all .js, .mjs, and .css files
+// if there are no patterns. This keeps the locations of source maps out of
+// the files served to the public while the maps themselves remain available
+// (to internal tools, or through [WithSourceMapHeader]). The removal is a
+// transformation (see [WithTransform]).
+//
+// Only comments on lines of their own are removed. The option has no effect
+// on a no-cache Server, since source maps are useful in development.
+func WithStripSourceMapComments(patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = []string{"**/*.js", "**/*.mjs", "**/*.css"}
+	}
+	addTransform := WithTransform(TransformFunc(stripSourceMapComments), patterns...)
+	return func(s *Server) {
+		if !s.noCache {
+			addTransform(s)
+		}
+	}
+}
+
+var sourceMapComment = regexp.MustCompile(`(?m)^[ \t]*(?://[#@] ?sourceMappingURL=[^\r\n]*|/\*[#@] ?sourceMappingURL=[^*]*\*/)[ \t]*(?:\r?\n|$)`)
+
+func stripSourceMapComments(name string, in []byte) ([]byte, error) {
+	return sourceMapComment.ReplaceAll(in, nil), nil
+}
@@ -0,0 +1,100 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSourceMapHeader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"js/app.js":       &fstest.MapFile{Data: []byte("app")},
+		"js/app.js.map":   &fstest.MapFile{Data: []byte("map1")},
+		"js/other.js":     &fstest.MapFile{Data: []byte("other")},
+		"css/app.css":     &fstest.MapFile{Data: []byte("css")},
+		"css/app.css.map": &fstest.MapFile{Data: []byte("cssmap")},
+		"data.json":       &fstest.MapFile{Data: []byte("{}")},
+		"data.json.map":   &fstest.MapFile{Data: []byte("{}")},
+	}
+	for _, tt := range []struct {
+		noCache bool
+		path    string
+		want    string
+	}{
+		{false, "/js/app.js", "app." + hashTag("map1") + ".js.map"},
+		{false, "/js/app." + hashTag("app") + ".js", "app." + hashTag("map1") + ".js.map"},
+		{false, "/css/app.css", "app." + hashTag("cssmap") + ".css.map"},
+		{false, "/js/other.js", ""},
+		{false, "/data.json", ""},
+		{true, "/js/app.js", "app.js.map"},
+	} {
+		var s *Server
+		if tt.noCache {
+			s = NewNoCache(fsys, WithSourceMapHeader())
+		} else {
+			s = New(fsys, WithSourceMapHeader())
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tt.path, w.Code)
+		}
+		if got := w.Header().Get("SourceMap"); got != tt.want {
+			t.Errorf("GET %s (noCache=%t): got SourceMap %q; want %q", tt.path, tt.noCache, got, tt.want)
+		}
+	}
+}
+
+func TestSourceMapHeaderChangingMap(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":     &fstest.MapFile{Data: []byte("app")},
+		"app.js.map": &fstest.MapFile{Data: []byte("map1")},
+	}
+	s := New(fsys, WithSourceMapHeader())
+	get := func() string {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/app.js", nil))
+		return w.Header().Get("SourceMap")
+	}
+	if got, want := get(), "app."+hashTag("map1")+".js.map"; got != want {
+		t.Fatalf("got SourceMap %q; want %q", got, want)
+	}
+	fsys["app.js.map"] = &fstest.MapFile{Data: []byte("map22")}
+	if got, want := get(), "app."+hashTag("map22")+".js.map"; got != want {
+		t.Errorf("after changing the map, got SourceMap %q; want %q", got, want)
+	}
+}
+
+func TestStripSourceMapComments(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":  &fstest.MapFile{Data: []byte("var x = 1;\n//# sourceMappingURL=app.js.map\n")},
+		"old.js":  &fstest.MapFile{Data: []byte("var y;\r\n//@ sourceMappingURL=old.js.map")},
+		"str.js":  &fstest.MapFile{Data: []byte(`var s = "//# sourceMappingURL=x";` + "\n")},
+		"app.css": &fstest.MapFile{Data: []byte("a{}\n/*# sourceMappingURL=app.css.map */\n")},
+		"app.txt": &fstest.MapFile{Data: []byte("//# sourceMappingURL=x\n")},
+	}
+	for _, tt := range []struct {
+		noCache bool
+		path    string
+		want    string
+	}{
+		{false, "/app.js", "var x = 1;\n"},
+		{false, "/old.js", "var y;\r\n"},
+		{false, "/str.js", `var s = "//# sourceMappingURL=x";` + "\n"},
+		{false, "/app.css", "a{}\n"},
+		{false, "/app.txt", "//# sourceMappingURL=x\n"},
+		{true, "/app.js", "var x = 1;\n//# sourceMappingURL=app.js.map\n"},
+	} {
+		var s *Server
+		if tt.noCache {
+			s = NewNoCache(fsys, WithStripSourceMapComments())
+		} else {
+			s = New(fsys, WithStripSourceMapComments())
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s (noCache=%t): got %q; want %q", tt.path, tt.noCache, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package assetserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// WithStreamingHash makes the Server hash a file while sending it in response
+// to a request, rather than reading the file once to hash it and then again
+// to send it, when the file's tag is not yet known. This halves the I/O
+// required on a cache miss, which matters for large files.
+//
+// The trade-off is that such a response cannot include an ETag header (since
+// the tag is not known until the whole file has been read). Streaming is used
+// only for plain GET requests for untagged names (those without Range or
+// conditional headers); other requests, including requests for tagged names
+// (whose tags must be verified before the file is sent), hash the file first
+// as usual.
+func WithStreamingHash() Option {
+	return func(s *Server) {
+		s.streamingHash = true
+	}
+}
+
+var errIncompleteStream = errors.New("file was not completely streamed")
+
+// isPlainGet reports whether r is a GET request without any headers that
+// would require more than sending the entire file.
+func isPlainGet(r *http.Request) bool {
+	if r.Method != "GET" {
+		return false
+	}
+	for _, name := range []string{
+		"Range",
+		"If-Range",
+		"If-Match",
+		"If-None-Match",
+		"If-Modified-Since",
+		"If-Unmodified-Since",
+	} {
+		if r.Header.Get(name) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// serveStreamingHash serves the named file, hashing it as it is sent and then
+// storing its info in the cache. It reports whether it handled the request; if
+// not (because there is already cached info for the file, the file can't be
+// streamed, or there was an error opening it), the caller should serve the
+// file as usual. Negotiated is as for setFileHeaders.
+func (s *Server) serveStreamingHash(w http.ResponseWriter, r *http.Request, name string, negotiated bool) bool {
+	if s.cachedInfo(name) != nil {
+		// Even if the info is outdated, it's likely that the file is
+		// being requested regularly, so prefer to send an ETag.
+		return false
+	}
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	if s.partialHash != nil && s.partialHash.applies(name, stat.Size()) {
+		return false
+	}
+	if s.transforms.applies(name) || s.tooLarge(stat.Size()) {
+		return false
+	}
+
+	s.reportCacheLookup(r.Context(), false)
+	hashDone := s.reportHash(r.Context(), name)
+	var hashErr error
+	defer hashDone(&hashErr)
+
+	hs := hashStatePool.Get().(*hashState)
+	defer hs.release()
+	info := &fileInfo{
+		mtime: stat.ModTime().UnixNano(),
+		size:  stat.Size(),
+	}
+	var head []byte
+	info.contentType = typeByExtension(path.Ext(stat.Name()))
+	if info.contentType == "" {
+		sniff := s.sniffBuffer(hs)
+		n, err := io.ReadFull(f, sniff)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			hashErr = err
+			s.writeFSError(w, r, name, err)
+			return true
+		}
+		head = sniff[:n]
+		info.contentType = s.sniffContentType(name, head)
+	}
+
+	asset := &AssetInfo{Name: name, Size: info.size, ContentType: info.contentType}
+	setAssetInfo(r.Context(), asset)
+	h := w.Header()
+	s.setFileHeaders(r, h, name, "", info, negotiated)
+	if modtime := stat.ModTime(); !modtime.IsZero() && !modtime.Equal(time.Unix(0, 0)) {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Content-Length", strconv.FormatInt(info.size, 10))
+	w.WriteHeader(http.StatusOK)
+
+	// Write head (which aliases hs.buf) before the copy reuses the buffer.
+	hs.h.Write(head)
+	if _, err := w.Write(head); err != nil {
+		hashErr = err
+		return true
+	}
+	n, err := io.CopyBuffer(w, io.TeeReader(f, hs.h), hs.buf)
+	if err != nil || int64(len(head))+n != info.size {
+		// The client went away or the file changed as we read it.
+		// Either way, we don't know the tag.
+		hashErr = errIncompleteStream
+		return true
+	}
+	if stat, err := f.Stat(); err != nil || !info.matches(stat) {
+		hashErr = errIncompleteStream
+		return true
+	}
+	info.tag = s.typedTag(makeTag(hs.h.Sum(nil)), info.contentType)
+	asset.Tag = info.tag
+	s.storeInfo(s.cache.entry(name), name, info)
+	return true
+}
@@ -0,0 +1,87 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStreamingHash(t *testing.T) {
+	fsys := &countingFS{FS: os.DirFS("testdata/assets")}
+	s := New(fsys, WithStreamingHash())
+	for _, name := range []string{"a.js", "d/sub/noext"} {
+		content := readTestAsset(t, name)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+name, nil))
+		resp := w.Result()
+		checkResponseCode(t, resp, 200)
+		checkResponseBody(t, resp, content)
+		checkResponseHeader(t, resp, "ETag", "")
+		checkResponseHeader(t, resp, "Cache-Control", "public, max-age=60")
+		if resp.Header.Get("Content-Type") == "" {
+			t.Errorf("GET %s: no Content-Type", name)
+		}
+
+		info := s.cachedInfo(name)
+		if info == nil {
+			t.Fatalf("GET %s: no info cached after streaming", name)
+		}
+		if want := hashTag(string(content)); info.tag != want {
+			t.Fatalf("GET %s: cached tag is %q; want %q", name, info.tag, want)
+		}
+
+		// The next request uses the cached tag.
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+name, nil))
+		resp = w.Result()
+		checkResponseCode(t, resp, 200)
+		checkResponseHeader(t, resp, "ETag", `"`+info.tag+`"`)
+	}
+	if n := fsys.opens.Load(); n != 4 {
+		t.Fatalf("got %d opens; want 4", n)
+	}
+}
+
+func TestStreamingHashTagged(t *testing.T) {
+	// Tagged requests can't be streamed before the tag is known.
+	s := New(os.DirFS("testdata/assets"), WithStreamingHash())
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.sI22qapGJ0.js", nil))
+	resp := w.Result()
+	checkResponseCode(t, resp, 200)
+	checkResponseHeader(t, resp, "ETag", `"sI22qapGJ0"`)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/b.sI22qapGJ0.min.js", nil))
+	checkResponseCode(t, w.Result(), 404)
+}
+
+func TestStreamingHashHeaders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":     &fstest.MapFile{Data: []byte("app()")},
+		"app.js.map": &fstest.MapFile{Data: []byte("{}")},
+		"index.html": &fstest.MapFile{Data: []byte("<html>")},
+		"style.css":  &fstest.MapFile{Data: []byte("body{}")},
+	}
+	s := New(fsys, WithStreamingHash(), WithSourceMapHeader(), WithPreloadLinks("index.html", "style.css"))
+	for _, tt := range []struct {
+		name   string
+		header string
+	}{
+		{"app.js", "SourceMap"},
+		{"index.html", "Link"},
+	} {
+		// The first response is streamed; the second is sent after
+		// the file is hashed. Apart from the ETag, their headers match.
+		var got []string
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tt.name, nil))
+			got = append(got, w.Header().Get(tt.header))
+		}
+		if got[0] == "" || got[0] != got[1] {
+			t.Errorf("GET %s: got %s headers %q (streamed) and %q", tt.name, tt.header, got[0], got[1])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package assetserver
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A SymlinkPolicy says how a Server treats symbolic links in its file system.
+// See [WithSymlinkPolicy].
+type SymlinkPolicy int
+
+const (
+	// FollowSymlinks serves files through symbolic links wherever they
+	// point. This is the default.
+	FollowSymlinks SymlinkPolicy = iota
+	// RefuseSymlinks treats files reached through symbolic links (to the
+	// files themselves or to directories containing them) as if they
+	// didn't exist.
+	RefuseSymlinks
+	// RestrictSymlinks follows symbolic links whose targets are within
+	// the file system and treats files reached through any others
+	// (including all links with absolute targets) as if they didn't
+	// exist.
+	RestrictSymlinks
+)
+
+// A ReadLinkFS is a file system that can describe symbolic links. Its
+// methods are those of io/fs.ReadLinkFS in Go 1.25 and later, which
+// os.DirFS implements.
+type ReadLinkFS interface {
+	fs.FS
+	// ReadLink returns the destination of the named symbolic link.
+	ReadLink(name string) (string, error)
+	// Lstat returns a FileInfo describing the named file without
+	// following a symbolic link at the end of the name.
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// WithSymlinkPolicy sets how the Server treats symbolic links. Following
+// links out of the asset directory can expose files that were never meant
+// to be served (a link to / would expose everything), so disk-backed
+// deployments whose directories might contain such links should refuse them
+// or restrict them to the directory.
+//
+// The policy can only be enforced if the file system given to New or
+// NewNoCache implements [ReadLinkFS], so New panics if a policy other than
+// FollowSymlinks is used with a file system that doesn't (such as os.DirFS
+// before Go 1.25, or [embed.FS], which has no symbolic links to refuse).
+// Checking the policy takes a call to Lstat for each element of a file's name
+// each time the file is opened. The check isn't atomic with opening the file,
+// so it doesn't protect against links which are created while the Server is
+// running by someone who can write to the directory.
+func WithSymlinkPolicy(p SymlinkPolicy) Option {
+	if p < FollowSymlinks || p > RestrictSymlinks {
+		panic(fmt.Sprintf("assetserver: WithSymlinkPolicy called with unknown policy %d", p))
+	}
+	return func(s *Server) {
+		s.symlinkPolicy = p
+	}
+}
+
+// errSymlink is reported for files that the symlink policy doesn't allow.
+var errSymlink = fmt.Errorf("symbolic link not allowed: %w", fs.ErrNotExist)
+
+// maxSymlinkHops is the most symbolic links that are followed to resolve a
+// name, to stop loops.
+const maxSymlinkHops = 40
+
+// A symlinkFS enforces a SymlinkPolicy other than FollowSymlinks.
+type symlinkFS struct {
+	fs.FS
+	links  ReadLinkFS
+	policy SymlinkPolicy
+}
+
+func (sfs *symlinkFS) Open(name string) (fs.File, error) {
+	if err := sfs.check(name); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return sfs.FS.Open(name)
+}
+
+// Stat and ReadFile keep the fast paths of the underlying file system (such
+// as os.DirFS), which would otherwise be hidden by the wrapper.
+func (sfs *symlinkFS) Stat(name string) (fs.FileInfo, error) {
+	if err := sfs.check(name); err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fs.Stat(sfs.FS, name)
+}
+
+func (sfs *symlinkFS) ReadFile(name string) ([]byte, error) {
+	if err := sfs.check(name); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return fs.ReadFile(sfs.FS, name)
+}
+
+// check returns errSymlink if the policy forbids opening the named file.
+func (sfs *symlinkFS) check(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return nil // let Open report the error
+	}
+	resolved := "" // the part of the name checked so far, without links
+	rest := strings.Split(name, "/")
+	hops := 0
+	for len(rest) > 0 {
+		p := path.Join(resolved, rest[0])
+		rest = rest[1:]
+		fi, err := sfs.links.Lstat(p)
+		if err != nil {
+			return nil // let Open report the error
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = p
+			continue
+		}
+		if sfs.policy == RefuseSymlinks {
+			return errSymlink
+		}
+		if hops++; hops > maxSymlinkHops {
+			return errSymlink
+		}
+		target, err := sfs.links.ReadLink(p)
+		if err != nil {
+			return err
+		}
+		if filepath.IsAbs(target) || path.IsAbs(filepath.ToSlash(target)) {
+			return errSymlink
+		}
+		target = path.Join(resolved, filepath.ToSlash(target))
+		if target == ".." || strings.HasPrefix(target, "../") {
+			return errSymlink
+		}
+		// Continue from the root with the link's target in place of
+		// the link.
+		resolved = ""
+		if target != "." {
+			rest = append(strings.Split(target, "/"), rest...)
+		}
+	}
+	return nil
+}
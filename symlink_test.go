@@ -0,0 +1,158 @@
+package assetserver
+
+import (
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSymlinkPolicy(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "root")
+	if _, ok := os.DirFS(root).(ReadLinkFS); !ok {
+		t.Skip("os.DirFS doesn't implement ReadLinkFS before Go 1.25")
+	}
+	for name, contents := range map[string]string{
+		"root/css/style.css":     "style",
+		"root/real/inner.txt":    "inner",
+		"outside/secret.txt":     "secret",
+		"outside/dir/secret.txt": "secret",
+	} {
+		p := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"root/link.css":     "css/style.css",
+		"root/css/up.txt":   "../real/inner.txt",
+		"root/alias":        "real",
+		"root/escape.txt":   "../outside/secret.txt",
+		"root/escapedir":    "../outside/dir",
+		"root/absolute.txt": filepath.Join(tmp, "outside", "secret.txt"),
+		"root/loop1":        "loop2",
+		"root/loop2":        "loop1",
+	} {
+		if err := os.Symlink(filepath.FromSlash(target), filepath.Join(tmp, filepath.FromSlash(link))); err != nil {
+			t.Skipf("cannot create symlinks: %s", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		path     string
+		follow   int
+		refuse   int
+		restrict int
+	}{
+		{"/css/style.css", 200, 200, 200},
+		{"/link.css", 200, 404, 200},
+		{"/css/up.txt", 200, 404, 200},
+		{"/alias/inner.txt", 200, 404, 200},
+		{"/escape.txt", 200, 404, 404},
+		{"/escapedir/secret.txt", 200, 404, 404},
+		{"/absolute.txt", 200, 404, 404},
+		{"/loop1", 500, 404, 404},
+	} {
+		for _, c := range []struct {
+			policy SymlinkPolicy
+			want   int
+		}{
+			{FollowSymlinks, tt.follow},
+			{RefuseSymlinks, tt.refuse},
+			{RestrictSymlinks, tt.restrict},
+		} {
+			s := New(os.DirFS(root), WithSymlinkPolicy(c.policy))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != c.want {
+				t.Errorf("policy %d: GET %s: got status %d; want %d", c.policy, tt.path, w.Code, c.want)
+			}
+			s.Close()
+		}
+	}
+}
+
+func TestSymlinkPolicyUnenforceable(t *testing.T) {
+	// Hide the methods that fstest.MapFS has in later versions of Go.
+	fsys := struct{ fs.FS }{fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}}
+	New(fsys, WithSymlinkPolicy(FollowSymlinks)).Close()
+	defer func() {
+		if recover() == nil {
+			t.Error("New with RefuseSymlinks and a file system without ReadLink did not panic")
+		}
+	}()
+	New(fsys, WithSymlinkPolicy(RefuseSymlinks)).Close()
+}
+
+// A fastFS is a countingFS which also implements ReadLinkFS and
+// fs.ReadFileFS.
+type fastFS struct {
+	*countingFS
+}
+
+func (ffs fastFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(ffs.countingFS.FS, name)
+}
+
+func (ffs fastFS) ReadLink(name string) (string, error) {
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+}
+
+func (ffs fastFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Stat(ffs.countingFS.FS, name)
+}
+
+func TestWrapperFastPaths(t *testing.T) {
+	mtime := time.Unix(1e9, 0)
+	for _, tt := range []struct {
+		desc string
+		opts []Option
+		dev  bool
+	}{
+		{"plain", nil, false},
+		{"symlink policy", []Option{WithSymlinkPolicy(RestrictSymlinks)}, false},
+		{"bundle", []Option{WithBundle("all.css", "a.css", "b.css")}, false},
+		{"overlay", nil, true},
+	} {
+		cfs := &countingFS{FS: fstest.MapFS{
+			"a.css": &fstest.MapFile{Data: []byte("a{}"), ModTime: mtime},
+			"b.css": &fstest.MapFile{Data: []byte("b{}"), ModTime: mtime},
+		}}
+		var s *Server
+		if tt.dev {
+			s = NewDev(fastFS{cfs}, t.TempDir(), tt.opts...)
+		} else {
+			s = New(fastFS{cfs}, tt.opts...)
+		}
+		// Hashing a file reads it with ReadFile, and checking that its
+		// info is up to date only needs a Stat, so neither Tag nor a
+		// conditional request opens the file. (NewDev's live reloading
+		// opens the root directory to list it.)
+		before := cfs.opens.Load()
+		tagged, err := s.Tag("a.css")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Tag("a.css"); err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/"+tagged, nil)
+		req.Header.Set("If-None-Match", `"`+hashTag("a{}")+`"`)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != 304 {
+			t.Errorf("%s: conditional GET %s: got status %d; want 304", tt.desc, tagged, w.Code)
+		}
+		if n := cfs.opens.Load() - before; n != 0 {
+			t.Errorf("%s: got %d calls to Open; want 0", tt.desc, n)
+		}
+		s.Close()
+	}
+}
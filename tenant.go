@@ -0,0 +1,103 @@
+package assetserver
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"reflect"
+)
+
+// WithFSSelector makes the Server choose the file system to serve each
+// request from by calling fn, so that one Server can serve different asset
+// roots for different tenants or hostnames (as in a white-label deployment).
+// For example,
+//
+//	WithFSSelector(func(r *http.Request) fs.FS {
+//		return tenantDirs[r.Host] // os.DirFS results, by hostname
+//	})
+//
+// If fn returns nil, the request is served from the file system given to New
+// or NewNoCache. Otherwise, it is served by a separate Server for the returned
+// file system, which is created, with the same options, the first time fn
+// returns that file system and kept until the Server is closed. Each such
+// Server has its own cache, so fn should return the same value each time for
+// a given tenant. The values must be comparable, as with map keys; the
+// results of os.DirFS are, but an fstest.MapFS (for example) should be
+// returned as a pointer. The file systems for tenants don't use the cache
+// file given by WithCacheFile.
+//
+// Methods such as Tag operate on the Server's own file system; use
+// [Server.Tenant] to find the Server for a request.
+func WithFSSelector(fn func(r *http.Request) fs.FS) Option {
+	return func(s *Server) {
+		s.fsSelector = fn
+	}
+}
+
+// Tenant returns the Server that serves r: the one for the file system that
+// the function given to WithFSSelector selects for r or, if the Server wasn't
+// created with WithFSSelector or the function returns nil, the current
+// generation (see [Server.Current]). Page handlers use Tenant to compute
+// tagged names for a tenant's assets:
+//
+//	tagged, err := s.Tenant(r).Tag("css/style.css")
+func (s *Server) Tenant(r *http.Request) *Server {
+	if t := s.tenant(r); t != nil {
+		return t
+	}
+	return s.Current()
+}
+
+// tenant returns the Server for the file system selected for r, or nil if
+// none is.
+func (s *Server) tenant(r *http.Request) *Server {
+	if s.fsSelector == nil {
+		return nil
+	}
+	fsys := s.fsSelector(r)
+	if fsys == nil {
+		return nil
+	}
+	if !reflect.TypeOf(fsys).Comparable() {
+		panic(fmt.Sprintf("assetserver: WithFSSelector function returned an incomparable %T", fsys))
+	}
+	s.tenantsMu.Lock()
+	defer s.tenantsMu.Unlock()
+	if t, ok := s.tenants[fsys]; ok {
+		return t
+	}
+	select {
+	case <-s.done:
+		// Don't start background goroutines for a closed Server.
+		return nil
+	default:
+	}
+	t := s.newChild(fsys)
+	if s.tenants == nil {
+		s.tenants = make(map[fs.FS]*Server)
+	}
+	s.tenants[fsys] = t
+	return t
+}
+
+// newChild returns a Server for fsys (a tenant or a generation) with the same
+// options as s.
+func (s *Server) newChild(fsys fs.FS) *Server {
+	opts := append(s.opts[:len(s.opts):len(s.opts)], func(c *Server) {
+		c.fsSelector = nil
+		c.cacheFile = ""
+		c.sharedCache = nil
+	})
+	return newServer(fsys, s.noCache, opts)
+}
+
+// allTenants returns the Servers created for WithFSSelector.
+func (s *Server) allTenants() []*Server {
+	s.tenantsMu.Lock()
+	defer s.tenantsMu.Unlock()
+	ts := make([]*Server, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		ts = append(ts, t)
+	}
+	return ts
+}
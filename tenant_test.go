@@ -0,0 +1,110 @@
+package assetserver
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSSelector(t *testing.T) {
+	defaultFS := fstest.MapFS{"style.css": &fstest.MapFile{Data: []byte("default")}}
+	tenants := map[string]*fstest.MapFS{
+		"a.example.com": {"style.css": &fstest.MapFile{Data: []byte("a")}},
+		"b.example.com": {
+			"style.css": &fstest.MapFile{Data: []byte("b")},
+			"b.txt":     &fstest.MapFile{Data: []byte("only b")},
+		},
+	}
+	s := New(defaultFS, WithFSSelector(func(r *http.Request) fs.FS {
+		if fsys, ok := tenants[r.Host]; ok {
+			return fsys
+		}
+		return nil
+	}), WithReleaseHeader("X-Release", "1"))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{"a.example.com", "/style.css", 200, "a"},
+		{"b.example.com", "/style.css", 200, "b"},
+		{"b.example.com", "/b.txt", 200, "only b"},
+		{"a.example.com", "/b.txt", 404, ""},
+		{"other.example.com", "/style.css", 200, "default"},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		resp := w.Result()
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s%s: got status %d; want %d", tt.host, tt.path, resp.StatusCode, tt.status)
+			continue
+		}
+		if got := resp.Header.Get("X-Release"); got != "1" {
+			t.Errorf("GET %s%s: got X-Release %q; want %q", tt.host, tt.path, got, "1")
+		}
+		if tt.status != 200 {
+			continue
+		}
+		b, _ := io.ReadAll(resp.Body)
+		if string(b) != tt.body {
+			t.Errorf("GET %s%s: got body %q; want %q", tt.host, tt.path, b, tt.body)
+		}
+	}
+
+	// The tagged names computed by a tenant's Server are served to the
+	// tenant.
+	for host, want := range map[string]string{
+		"a.example.com":     "a",
+		"b.example.com":     "b",
+		"other.example.com": "default",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		tagged, err := s.Tenant(req).Tag("style.css")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = httptest.NewRequest("GET", "/"+tagged, nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != 200 || w.Body.String() != want {
+			t.Errorf("GET %s/%s: got status %d, body %q; want 200, %q", host, tagged, w.Code, w.Body, want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "a.example.com"
+	if s.Tenant(req) != s.Tenant(req) {
+		t.Error("Tenant returned different Servers for the same file system")
+	}
+	req.Host = "other.example.com"
+	if s.Tenant(req) != s {
+		t.Error("Tenant didn't return the Server itself for the default file system")
+	}
+
+	// Purging the Server purges its tenants.
+	if n := s.purge(nil); n != 4 {
+		t.Errorf("purge: got %d files; want 4", n)
+	}
+}
+
+func TestFSSelectorIncomparable(t *testing.T) {
+	tenant := fstest.MapFS{"style.css": &fstest.MapFile{Data: []byte("a")}}
+	s := New(fstest.MapFS{}, WithFSSelector(func(*http.Request) fs.FS { return tenant }))
+	defer s.Close()
+	defer func() {
+		if recover() == nil {
+			t.Error("Tenant didn't panic for an incomparable file system")
+		}
+	}()
+	s.Tenant(httptest.NewRequest("GET", "/style.css", nil))
+}
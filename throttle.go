@@ -0,0 +1,162 @@
+package assetserver
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ThrottleOptions configures [WithThrottle].
+type ThrottleOptions struct {
+	// BytesPerSecond is the maximum rate at which response bodies are
+	// sent.
+	BytesPerSecond int64
+	// Shared makes all of the responses for matching files share the
+	// limit, so that together they are sent no faster than
+	// BytesPerSecond. Otherwise, each response is limited separately.
+	Shared bool
+	// Patterns selects the files to throttle. If it is empty, all files
+	// are throttled. See the Patterns section of the package
+	// documentation for the pattern syntax.
+	Patterns []string
+}
+
+// WithThrottle makes the Server limit the rate at which it sends the bodies
+// of responses for files matching the patterns given in opts. This is useful
+// when large downloads share a server with small, latency-sensitive assets,
+// so that the downloads don't saturate the network. For example, to cap the
+// bandwidth used by everything under downloads/ at 10 MB/s:
+//
+//	WithThrottle(ThrottleOptions{
+//		BytesPerSecond: 10e6,
+//		Shared:         true,
+//		Patterns:       []string{"downloads/**"},
+//	})
+//
+// WithThrottle may be given more than once, and a response is limited by
+// every throttle whose patterns match its file. Throttled files can't be sent
+// using sendfile. WithThrottle panics if opts.BytesPerSecond <= 0 or a
+// pattern is malformed.
+func WithThrottle(opts ThrottleOptions) Option {
+	if opts.BytesPerSecond <= 0 {
+		panic("assetserver: WithThrottle called with BytesPerSecond <= 0")
+	}
+	opts.Patterns = compilePatterns(opts.Patterns)
+	return func(s *Server) {
+		th := &throttle{opts: opts}
+		if opts.Shared {
+			th.shared = newRateLimiter(opts.BytesPerSecond)
+		}
+		s.throttles = append(s.throttles, th)
+	}
+}
+
+type throttle struct {
+	opts   ThrottleOptions
+	shared *rateLimiter // nil unless opts.Shared
+}
+
+// throttleLimiters returns the limiters for a response for the named file.
+func (s *Server) throttleLimiters(name string) []*rateLimiter {
+	var ls []*rateLimiter
+	for _, th := range s.throttles {
+		if len(th.opts.Patterns) > 0 && !matchAny(th.opts.Patterns, name) {
+			continue
+		}
+		l := th.shared
+		if l == nil {
+			l = newRateLimiter(th.opts.BytesPerSecond)
+		}
+		ls = append(ls, l)
+	}
+	return ls
+}
+
+// A rateLimiter spaces out writes so that they proceed at a fixed rate.
+type rateLimiter struct {
+	rate float64 // bytes per second
+	// chunk is the most that is written at once, so that the writes
+	// are spread out smoothly.
+	chunk int
+
+	mu   sync.Mutex
+	next time.Time // when the writes reserved so far will have finished
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	chunk := bytesPerSecond / 10
+	if chunk > 32*1024 {
+		chunk = 32 * 1024
+	}
+	if chunk < 1 {
+		chunk = 1
+	}
+	return &rateLimiter{rate: float64(bytesPerSecond), chunk: int(chunk)}
+}
+
+// reserve reserves n bytes of the limiter's capacity and returns how long to
+// wait before writing them.
+func (l *rateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	return wait
+}
+
+// A throttledWriter wraps a ResponseWriter to limit the rate at which the
+// body is written.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	limiters []*rateLimiter
+	chunk    int // the smallest of the limiters' chunk sizes
+}
+
+func newThrottledWriter(ctx context.Context, w http.ResponseWriter, limiters []*rateLimiter) *throttledWriter {
+	tw := &throttledWriter{ResponseWriter: w, ctx: ctx, limiters: limiters}
+	for _, l := range limiters {
+		if tw.chunk == 0 || l.chunk < tw.chunk {
+			tw.chunk = l.chunk
+		}
+	}
+	return tw
+}
+
+func (w *throttledWriter) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		n := min(len(b), w.chunk)
+		var wait time.Duration
+		for _, l := range w.limiters {
+			wait = max(wait, l.reserve(n))
+		}
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-w.ctx.Done():
+				t.Stop()
+				return written, w.ctx.Err()
+			}
+		}
+		m, err := w.ResponseWriter.Write(b[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// Unwrap returns the underlying ResponseWriter for the benefit of
+// http.ResponseController.
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package assetserver
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 200e3)
+	fsys := fstest.MapFS{
+		"downloads/a.bin": &fstest.MapFile{Data: body},
+		"downloads/b.bin": &fstest.MapFile{Data: body},
+		"big/c.bin":       &fstest.MapFile{Data: body},
+		"big/d.bin":       &fstest.MapFile{Data: body},
+		"style.css":       &fstest.MapFile{Data: body},
+	}
+	s := New(fsys,
+		WithThrottle(ThrottleOptions{BytesPerSecond: 1e6, Shared: true, Patterns: []string{"downloads/**"}}),
+		WithThrottle(ThrottleOptions{BytesPerSecond: 1e6, Patterns: []string{"big/**"}}),
+	)
+	defer s.Close()
+
+	// fetch gets the paths concurrently and returns the time taken.
+	fetch := func(paths ...string) time.Duration {
+		t.Helper()
+		start := time.Now()
+		var wg sync.WaitGroup
+		for _, p := range paths {
+			wg.Add(1)
+			go func(p string) {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, httptest.NewRequest("GET", p, nil))
+				if w.Code != 200 || w.Body.Len() != len(body) {
+					t.Errorf("GET %s: got status %d, %d bytes", p, w.Code, w.Body.Len())
+				}
+			}(p)
+		}
+		wg.Wait()
+		return time.Since(start)
+	}
+
+	// Unthrottled.
+	if d := fetch("/style.css"); d > 100*time.Millisecond {
+		t.Errorf("unthrottled file took %s", d)
+	}
+	// Each response is limited to 1 MB/s, so 200 kB takes about 200ms
+	// (less the first chunk, which is sent right away).
+	if d := fetch("/big/c.bin", "/big/d.bin"); d < 150*time.Millisecond {
+		t.Errorf("two separately throttled files took %s; want about 200ms", d)
+	}
+	// The responses share 1 MB/s, so 400 kB takes about 400ms.
+	if d := fetch("/downloads/a.bin", "/downloads/b.bin"); d < 350*time.Millisecond {
+		t.Errorf("two files with a shared throttle took %s; want about 400ms", d)
+	}
+}
+
+func TestThrottleCanceled(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.bin": &fstest.MapFile{Data: bytes.Repeat([]byte("x"), 1e6)},
+	}
+	s := New(fsys, WithThrottle(ThrottleOptions{BytesPerSecond: 1e3}))
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.bin", nil).WithContext(ctx))
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("canceled request took %s", d)
+	}
+	if w.Body.Len() >= 1e6 {
+		t.Errorf("canceled request sent the whole file")
+	}
+}
@@ -0,0 +1,26 @@
+package assetserver
+
+import "strings"
+
+// WithTimingAllowOrigin makes the Server add a Timing-Allow-Origin header to
+// all of its responses, listing the origins (such as
+// "https://www.example.com") of pages which may see the detailed Resource
+// Timing information (including the Server-Timing header; see
+// [WithServerTiming]) for its assets. Browsers hide that information from
+// pages on other origins, so this is needed to measure the performance of
+// assets served from a CDN or other separate hostname. If no origins are
+// given, the header is "*", which allows all origins.
+func WithTimingAllowOrigin(origins ...string) Option {
+	for _, o := range origins {
+		if o == "" || strings.ContainsAny(o, ", \t\r\n") {
+			panic("assetserver: WithTimingAllowOrigin called with invalid origin " + o)
+		}
+	}
+	value := "*"
+	if len(origins) > 0 {
+		value = strings.Join(origins, ", ")
+	}
+	return func(s *Server) {
+		s.timingAllowOrigin = value
+	}
+}
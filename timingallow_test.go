@@ -0,0 +1,52 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTimingAllowOrigin(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a")},
+	}
+	for _, tt := range []struct {
+		origins []string
+		want    string
+	}{
+		{nil, "*"},
+		{[]string{"https://www.example.com"}, "https://www.example.com"},
+		{[]string{"https://a.example.com", "https://b.example.com"}, "https://a.example.com, https://b.example.com"},
+	} {
+		s := New(fsys, WithTimingAllowOrigin(tt.origins...))
+		for _, path := range []string{"/a.css", "/b.css"} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if got := w.Header().Get("Timing-Allow-Origin"); got != tt.want {
+				t.Errorf("origins %q: GET %s: got Timing-Allow-Origin %q; want %q", tt.origins, path, got, tt.want)
+			}
+		}
+		s.Close()
+	}
+
+	s := New(fsys)
+	defer s.Close()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.css", nil))
+	if got := w.Header().Get("Timing-Allow-Origin"); got != "" {
+		t.Errorf("got Timing-Allow-Origin %q by default; want none", got)
+	}
+}
+
+func TestTimingAllowOriginPanics(t *testing.T) {
+	for _, origin := range []string{"", "https://a.example.com, https://b.example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithTimingAllowOrigin(%q) didn't panic", origin)
+				}
+			}()
+			WithTimingAllowOrigin(origin)
+		}()
+	}
+}
@@ -0,0 +1,98 @@
+package assetserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// TraceHooks contains functions that a Server calls to describe its work to a
+// tracing system. Any of the functions may be nil. They are called
+// synchronously from the goroutines doing the work, so they must be fast and
+// safe for concurrent use.
+//
+// The assetservertrace package uses TraceHooks to record OpenTelemetry spans.
+type TraceHooks struct {
+	// StartRequest is called when the Server begins to serve r. It
+	// returns the context in which the Server serves the request (which
+	// may carry a span, say) and a function that the Server calls after
+	// it responds, with the response status code, the number of body
+	// bytes written, and the response header.
+	StartRequest func(r *http.Request) (context.Context, func(status int, bytes int64, h http.Header))
+	// SetAttribute is called to describe the request being served in
+	// ctx. The value is a string, a bool, or an int64. The keys are:
+	//
+	//   - assetserver.name: the name of the requested file, without any
+	//     tag (a string)
+	//   - assetserver.tag: the file's current tag (a string)
+	//   - assetserver.cache_hit: whether the file's information was
+	//     found in the cache; if false, the file was hashed (a bool)
+	//   - assetserver.size: the size of the file (an int64)
+	SetAttribute func(ctx context.Context, key string, value any)
+	// StartHash is called when the Server begins to hash the named file.
+	// The context is that of the request being served (as returned by
+	// StartRequest) or the one given to [Server.Preload]. It returns a
+	// function that the Server calls with the result when the hash is
+	// complete.
+	StartHash func(ctx context.Context, name string) func(err error)
+}
+
+// WithTraceHooks registers functions to be called by the Server to describe
+// the requests it serves and the files it hashes to a tracing system.
+// WithTraceHooks may be given more than once.
+func WithTraceHooks(hooks TraceHooks) Option {
+	return func(s *Server) {
+		s.traces = append(s.traces, hooks)
+	}
+}
+
+// startTrace reports the start of a request to the trace hooks. The caller
+// must call the returned function after serving the request.
+func (s *Server) startTrace(r *http.Request, rec *responseRecorder) (*http.Request, func()) {
+	var ends []func(int, int64, http.Header)
+	for _, t := range s.traces {
+		if t.StartRequest != nil {
+			ctx, end := t.StartRequest(r)
+			r = r.WithContext(ctx)
+			ends = append(ends, end)
+		}
+	}
+	return r, func() {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](rec.code(), rec.bytes, rec.Header())
+		}
+	}
+}
+
+// setTraceAttribute reports an attribute of the request being served in ctx
+// to the trace hooks.
+func (s *Server) setTraceAttribute(ctx context.Context, key string, value any) {
+	for _, t := range s.traces {
+		if t.SetAttribute != nil {
+			t.SetAttribute(ctx, key, value)
+		}
+	}
+}
+
+// setFileTraceAttributes reports the attributes describing the file being
+// served in ctx to the trace hooks.
+func (s *Server) setFileTraceAttributes(ctx context.Context, info *fileInfo) {
+	s.setTraceAttribute(ctx, "assetserver.tag", info.tag)
+	s.setTraceAttribute(ctx, "assetserver.size", info.size)
+}
+
+// startHashTrace reports the start of hashing the named file to the trace
+// hooks. The caller must call the returned function with the result of the
+// hash operation.
+func (s *Server) startHashTrace(ctx context.Context, name string) func(error) {
+	var ends []func(error)
+	for _, t := range s.traces {
+		if t.StartHash != nil {
+			ends = append(ends, t.StartHash(ctx, name))
+		}
+	}
+	return func(err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package assetserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type traceKey struct{}
+
+func TestTraceHooks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello")},
+	}
+	var events []string
+	s := New(fsys, WithTraceHooks(TraceHooks{
+		StartRequest: func(r *http.Request) (context.Context, func(int, int64, http.Header)) {
+			events = append(events, "start "+r.URL.Path)
+			ctx := context.WithValue(r.Context(), traceKey{}, r.URL.Path)
+			return ctx, func(status int, bytes int64, h http.Header) {
+				events = append(events, fmt.Sprintf("end %d %d", status, bytes))
+			}
+		},
+		SetAttribute: func(ctx context.Context, key string, value any) {
+			events = append(events, fmt.Sprintf("%v: %s=%v", ctx.Value(traceKey{}), key, value))
+		},
+		StartHash: func(ctx context.Context, name string) func(error) {
+			events = append(events, fmt.Sprintf("%v: hash %s", ctx.Value(traceKey{}), name))
+			return func(err error) {
+				events = append(events, fmt.Sprintf("hash done %v", err))
+			}
+		},
+	}))
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+		if w.Code != 200 {
+			t.Fatalf("got status %d", w.Code)
+		}
+	}
+	tag := hashTag("hello")
+	want := []string{
+		"start /a.txt",
+		"/a.txt: assetserver.name=a.txt",
+		"/a.txt: assetserver.cache_hit=false",
+		"/a.txt: hash a.txt",
+		"hash done <nil>",
+		"/a.txt: assetserver.tag=" + tag,
+		"/a.txt: assetserver.size=5",
+		"end 200 5",
+		"start /a.txt",
+		"/a.txt: assetserver.name=a.txt",
+		"/a.txt: assetserver.cache_hit=true",
+		"/a.txt: assetserver.tag=" + tag,
+		"/a.txt: assetserver.size=5",
+		"end 200 5",
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("trace events (-want +got):\n%s", diff)
+	}
+}
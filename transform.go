@@ -0,0 +1,276 @@
+package assetserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+)
+
+// A Transformer modifies the contents of files before a Server serves them.
+// See [WithTransform].
+type Transformer interface {
+	// Transform returns the transformed contents of the named file, given
+	// its original contents. It must not modify in.
+	//
+	// The output must depend only on the name and the input; the Server
+	// reuses the output for as long as the file's contents don't change.
+	Transform(name string, in []byte) ([]byte, error)
+}
+
+// A TransformFunc is a function that implements [Transformer].
+type TransformFunc func(name string, in []byte) ([]byte, error)
+
+// Transform calls f(name, in).
+func (f TransformFunc) Transform(name string, in []byte) ([]byte, error) {
+	return f(name, in)
+}
+
+// WithTransform makes the Server apply t to the files matching any of the
+// given patterns (or to all files, if there are no patterns) and serve the
+// result instead of the original contents. This can be used to minify files,
+// substitute text, inject configuration, and so on without a separate build
+// step. If WithTransform is given multiple times, the transformers are
+// applied in order, each to the output of the previous one.
+//
+// The tag and ETag of a transformed file are derived from the transformed
+// contents. The Server runs the transformers when it first serves (or tags) a
+// file and again only when the file's contents change: the output is cached
+// according to the hash of the input. The output is held in memory, so
+// transformers are best applied to modestly sized text files such as
+// JavaScript, CSS, and HTML.
+//
+// If a transformer returns an error, requests for the file fail with 500
+// Internal Server Error.
+//
+// See the Patterns section of the package documentation for the pattern
+// syntax.
+func WithTransform(t Transformer, patterns ...string) Option {
+	tr := transform{t: t, patterns: compilePatterns(patterns)}
+	return func(s *Server) {
+		if s.transforms == nil {
+			s.transforms = &transforms{outputs: make(map[string]*transformOutput)}
+		}
+		s.transforms.list = append(s.transforms.list, tr)
+	}
+}
+
+type transform struct {
+	t        Transformer
+	patterns []string // if empty, match all files
+}
+
+func (tr transform) applies(name string) bool {
+	return len(tr.patterns) == 0 || matchAny(tr.patterns, name)
+}
+
+// transforms is the list of a Server's transformers along with their
+// cached output.
+type transforms struct {
+	list []transform
+
+	mu sync.Mutex
+	// outputs holds the latest output for each file, keyed by name.
+	// Entries are removed along with the files' cache entries.
+	outputs map[string]*transformOutput
+}
+
+type transformOutput struct {
+	inputHash [sha256.Size]byte
+	body      []byte
+	tag       string
+	deps      []fileDep
+}
+
+// forget discards the output for the named file, whose cached info has been
+// evicted.
+func (ts *transforms) forget(name string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.outputs, name)
+}
+
+// applies reports whether any transformers apply to the named file.
+func (ts *transforms) applies(name string) bool {
+	if ts == nil {
+		return false
+	}
+	for _, tr := range ts.list {
+		if tr.applies(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// A refTransformer is a Transformer (provided by this package) which rewrites
+// references to other files in a file's contents to include their tags.
+type refTransformer interface {
+	Transformer
+	// transformRefs is like Transform, but it uses refs to look up
+	// information about the files that the named file refers to.
+	transformRefs(name string, in []byte, refs *refResolver) ([]byte, error)
+}
+
+// A refResolver looks up information about the files referred to by a file
+// being transformed, recording them as dependencies.
+type refResolver struct {
+	s    *Server
+	ctx  context.Context
+	deps []fileDep
+}
+
+// info returns the info for the named file. It reports false if the file
+// cannot be referred to by tag, in which case the reference should be left
+// as-is.
+func (rr *refResolver) info(name string) (*fileInfo, bool) {
+	// Don't follow references to files which are themselves transformed:
+	// they might refer back to this one.
+	if rr.s.noCache || rr.s.transforms.applies(name) {
+		return nil, false
+	}
+	info, err := rr.s.currentInfo(rr.ctx, name)
+	if err != nil {
+		return nil, false
+	}
+	for _, dep := range rr.deps {
+		if dep.name == name {
+			return info, true
+		}
+	}
+	rr.deps = append(rr.deps, fileDep{name: name, tag: info.tag})
+	return info, true
+}
+
+// tag returns the tag for the named file. It reports false if the reference
+// should be left as-is.
+func (rr *refResolver) tag(name string) (string, bool) {
+	info, ok := rr.info(name)
+	if !ok {
+		return "", false
+	}
+	return info.tag, true
+}
+
+// integrity returns a subresource integrity value (a SHA-256 digest) for
+// the named file. It reports false if the file cannot be read.
+func (rr *refResolver) integrity(name string) (string, bool) {
+	if _, ok := rr.info(name); !ok {
+		return "", false
+	}
+	f, err := rr.s.fsys.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	return "sha256-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), true
+}
+
+// A fileDep records that a transformed file referred to another file which
+// had a particular tag.
+type fileDep struct {
+	name string
+	tag  string
+}
+
+// depsCurrent reports whether the tags of the files referenced by the
+// transformed file described by info are unchanged.
+func (s *Server) depsCurrent(ctx context.Context, info *fileInfo) bool {
+	for _, dep := range info.deps {
+		cur, err := s.currentInfo(ctx, dep.name)
+		if err != nil || cur.tag != dep.tag {
+			return false
+		}
+	}
+	return true
+}
+
+// readTransformedInfo is the version of readInfo for files to which
+// transformers apply.
+func (s *Server) readTransformedInfo(ctx context.Context, name string, f seekerFile) (info *fileInfo, err error) {
+	defer s.reportHash(ctx, name)(&err)
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fi := &fileInfo{
+		mtime: stat.ModTime().UnixNano(),
+		size:  stat.Size(),
+	}
+	// Only limit the concurrency of reading the file, not of transforming
+	// it: transformers may look up (and therefore hash) other files.
+	release, err := s.acquireHashSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in, err := io.ReadAll(ctxReader{ctx, f})
+	release()
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.transform(ctx, name, in)
+	if err != nil {
+		return nil, err
+	}
+	fi.body = out.body
+	fi.deps = out.deps
+	fi.contentType = typeByExtension(path.Ext(stat.Name()))
+	if fi.contentType == "" {
+		head := fi.body
+		if n := s.sniffLength(); len(head) > n {
+			head = head[:n]
+		}
+		fi.contentType = s.sniffContentType(name, head)
+	}
+	fi.tag = s.typedTag(out.tag, fi.contentType)
+	return fi, nil
+}
+
+// transform applies the transformers for the named file to its contents,
+// reusing the previous output if the contents (and the tags of any files
+// they refer to) haven't changed.
+func (s *Server) transform(ctx context.Context, name string, in []byte) (*transformOutput, error) {
+	ts := s.transforms
+	inputHash := sha256.Sum256(in)
+	ts.mu.Lock()
+	prev := ts.outputs[name]
+	ts.mu.Unlock()
+	if prev != nil && prev.inputHash == inputHash && s.depsCurrent(ctx, &fileInfo{deps: prev.deps}) {
+		return prev, nil
+	}
+
+	refs := &refResolver{s: s, ctx: ctx}
+	b := in
+	for _, tr := range ts.list {
+		if !tr.applies(name) {
+			continue
+		}
+		var err error
+		if rt, ok := tr.t.(refTransformer); ok {
+			b, err = rt.transformRefs(name, b, refs)
+		} else {
+			b, err = tr.t.Transform(name, b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("assetserver: error transforming %s: %w", name, err)
+		}
+	}
+	outHash := sha256.Sum256(b)
+	out := &transformOutput{
+		inputHash: inputHash,
+		body:      b,
+		tag:       makeTag(outHash[:]),
+		deps:      refs.deps,
+	}
+	ts.mu.Lock()
+	ts.outputs[name] = out
+	ts.mu.Unlock()
+	return out, nil
+}
@@ -0,0 +1,189 @@
+package assetserver
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func upper(name string, in []byte) ([]byte, error) {
+	return bytes.ToUpper(in), nil
+}
+
+func TestTransform(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":  &fstest.MapFile{Data: []byte("hello")},
+		"b.html": &fstest.MapFile{Data: []byte("<p>hi</p>")},
+	}
+	s := New(fsys,
+		WithTransform(TransformFunc(upper), "*.txt"),
+		WithTransform(TransformFunc(func(name string, in []byte) ([]byte, error) {
+			return append([]byte(name+": "), in...), nil
+		})),
+	)
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "a.txt: HELLO"},
+		{"b.html", "b.html: <p>hi</p>"},
+	} {
+		tagged, err := s.Tag(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tag := hashTag(tt.want)
+		if gotTag, _ := removeTag(tagged); gotTag != tag {
+			t.Errorf("Tag(%q) = %q; want tag %s", tt.name, tagged, tag)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tagged, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s: got status %d", tagged, w.Code)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("GET %s: got body %q; want %q", tagged, got, tt.want)
+		}
+		if got, want := w.Header().Get("ETag"), `"`+tag+`"`; got != want {
+			t.Errorf("GET %s: got ETag %s; want %s", tagged, got, want)
+		}
+
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("HEAD", "/"+tt.name, nil))
+		if got, want := w.Header().Get("Content-Length"), strconv.Itoa(len(tt.want)); got != want {
+			t.Errorf("HEAD %s: got Content-Length %s; want %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello")},
+		"b.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	s := New(fsys, WithTransform(TransformFunc(upper), "*.txt"))
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "HELLO"},
+		{"/a.txt", "HELLO"},
+		{"b.css", "body{}"},
+	} {
+		got, err := s.ReadFile(tt.name)
+		if err != nil {
+			t.Fatalf("ReadFile(%q): %s", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("ReadFile(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := s.ReadFile("nonexistent.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile(nonexistent.txt): got error %v; want fs.ErrNotExist", err)
+	}
+}
+
+func TestTransformRunsOncePerContent(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	write := func(contents string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var runs int
+	s := New(os.DirFS(dir), WithTransform(TransformFunc(func(name string, in []byte) ([]byte, error) {
+		runs++
+		return upper(name, in)
+	})))
+	get := func(want string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+		if got := w.Body.String(); got != want {
+			t.Fatalf("got body %q; want %q", got, want)
+		}
+	}
+
+	t0 := time.Now().Add(-time.Hour)
+	write("a", t0)
+	get("A")
+	get("A")
+	if runs != 1 {
+		t.Fatalf("after two requests, got %d transformer runs; want 1", runs)
+	}
+	// Touching the file requires hashing it again, but not transforming
+	// it again.
+	write("a", t0.Add(time.Minute))
+	get("A")
+	if runs != 1 {
+		t.Fatalf("after touching the file, got %d transformer runs; want 1", runs)
+	}
+	write("b", t0.Add(2*time.Minute))
+	get("B")
+	if runs != 2 {
+		t.Fatalf("after changing the file, got %d transformer runs; want 2", runs)
+	}
+}
+
+func TestTransformError(t *testing.T) {
+	errBad := errors.New("bad input")
+	var hookErr error
+	s := New(fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+	},
+		WithTransform(TransformFunc(func(string, []byte) ([]byte, error) {
+			return nil, errBad
+		})),
+		WithErrorHook(func(_ *http.Request, _ string, err error) { hookErr = err }),
+	)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+	if w.Code != 500 {
+		t.Errorf("got status %d; want 500", w.Code)
+	}
+	if !errors.Is(hookErr, errBad) {
+		t.Errorf("error hook got %v; want %v", hookErr, errBad)
+	}
+}
+
+func TestTransformOutputsEvicted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+		"b.txt": &fstest.MapFile{Data: []byte("b")},
+		"c.txt": &fstest.MapFile{Data: []byte("c")},
+	}
+	s := New(fsys, WithTransform(TransformFunc(upper)), WithMaxCacheEntries(2))
+	defer s.Close()
+	outputs := func() int {
+		s.transforms.mu.Lock()
+		defer s.transforms.mu.Unlock()
+		return len(s.transforms.outputs)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if _, err := s.Tag(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := outputs(); n != 2 {
+		t.Errorf("after tagging 3 files with a limit of 2: got %d outputs; want 2", n)
+	}
+	delete(fsys, "b.txt")
+	delete(fsys, "c.txt")
+	s.Prune()
+	if n := outputs(); n != 0 {
+		t.Errorf("after pruning: got %d outputs; want 0", n)
+	}
+}
@@ -0,0 +1,28 @@
+package assetserver
+
+import "crypto/sha256"
+
+// WithContentTypeInTag makes a file's tag depend on its content type as
+// well as its contents. Normally, a file whose contents are unchanged keeps
+// its tag (and ETag) when the content type it is served with changes (for
+// example, after a change to the system's MIME tables, a call to
+// mime.AddExtensionType, or a new [WithContentSniffer] function), so caches
+// holding the file under its tagged URL keep the old Content-Type header.
+// With this option, such a change also changes the tag.
+//
+// Turning the option on or off changes the tags of all files.
+func WithContentTypeInTag() Option {
+	return func(s *Server) {
+		s.typeInTag = true
+	}
+}
+
+// typedTag returns the tag for a file whose contents have the given tag
+// and which is served with the given content type.
+func (s *Server) typedTag(tag, contentType string) string {
+	if !s.typeInTag {
+		return tag
+	}
+	sum := sha256.Sum256([]byte(tag + "\x00" + contentType))
+	return makeTag(sum[:])
+}
@@ -0,0 +1,49 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContentTypeInTag(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photo": &fstest.MapFile{Data: []byte("\x00\x00\x00\x1cftypavif")},
+	}
+	tagWith := func(opts ...Option) string {
+		t.Helper()
+		s := New(fsys, opts...)
+		defer s.Close()
+		tagged, err := s.Tag("photo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tagged
+	}
+	plain := tagWith()
+	sniffed := tagWith(WithContentSniffer(sniffAVIF))
+	if plain != sniffed {
+		t.Errorf("without WithContentTypeInTag, changing the content type changed the tag from %s to %s", plain, sniffed)
+	}
+	typedPlain := tagWith(WithContentTypeInTag())
+	typedSniffed := tagWith(WithContentTypeInTag(), WithContentSniffer(sniffAVIF))
+	if typedPlain == typedSniffed {
+		t.Errorf("with WithContentTypeInTag, changing the content type didn't change the tag %s", typedPlain)
+	}
+	if typedPlain == plain {
+		t.Errorf("WithContentTypeInTag didn't change the tag %s", plain)
+	}
+
+	// The tag is the same when computed while streaming the file.
+	s := New(fsys, WithContentTypeInTag(), WithContentSniffer(sniffAVIF), WithStreamingHash())
+	defer s.Close()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/photo", nil))
+	tagged, err := s.Tag("photo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tagged != typedSniffed {
+		t.Errorf("after streaming hash: got tagged name %s; want %s", tagged, typedSniffed)
+	}
+}
@@ -0,0 +1,131 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// An Uploader stores files in an object store or CDN. See [Server.Upload].
+type Uploader interface {
+	// Upload stores obj under obj.Name. Since the names of uploaded
+	// objects include their tags, an object with a given name always has
+	// the same contents, so Upload may skip objects which already exist.
+	Upload(ctx context.Context, obj *Object) error
+}
+
+// An Object is a file to be stored by an [Uploader].
+type Object struct {
+	// Name is the tagged name of the file, such as
+	// "css/style.1b2cf9aa.css".
+	Name string
+	// Body is the content that the Server serves for the file, after any
+	// transformations.
+	Body []byte
+	// ContentType and CacheControl are the values of the Content-Type
+	// and Cache-Control headers that the Server sends for the tagged
+	// name. Object stores usually allow these to be set as object
+	// metadata.
+	ContentType  string
+	CacheControl string
+	// ContentEncoding is the value of the Content-Encoding header that
+	// the Server sends for files whose types are always compressed (such
+	// as gzipped SVG images, .svgz), or "" for other files. Object stores
+	// which serve the object directly must be given it as well, or the
+	// compressed contents are served as if they were not.
+	ContentEncoding string
+}
+
+// UploadOptions configures [Server.Upload].
+type UploadOptions struct {
+	// Patterns selects the files to upload. If empty, all files are
+	// uploaded. See the Patterns section of the package documentation for
+	// the syntax.
+	Patterns []string
+	// Concurrency is the maximum number of concurrent calls to Upload.
+	// If zero, 8 is used.
+	Concurrency int
+}
+
+// Upload walks the Server's file system and uploads each file with u under
+// its tagged name, along with the headers that the Server would send for
+// it. This is for deployments in which a CDN or object store, rather than
+// the Server itself, serves the assets: the application still uses
+// [Server.Tag] (or the returned manifest) to refer to them.
+//
+// Upload returns a manifest mapping the name of each uploaded file to its
+// tagged name. It returns an error if the Server is a no-cache Server, which
+// doesn't use tagged names, or if any file cannot be read or uploaded. If ctx
+// is canceled, Upload stops early and returns the context's error.
+func (s *Server) Upload(ctx context.Context, u Uploader, opts UploadOptions) (map[string]string, error) {
+	if s.noCache {
+		return nil, errors.New("assetserver: Upload called on a no-cache Server")
+	}
+	patterns := compilePatterns(opts.Patterns)
+	n := opts.Concurrency
+	if n <= 0 {
+		n = 8
+	}
+	var (
+		mu       sync.Mutex
+		manifest = make(map[string]string)
+	)
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(n)
+	walkErr := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || (len(patterns) > 0 && !matchAny(patterns, name)) {
+			return nil
+		}
+		eg.Go(func() error {
+			obj, err := s.uploadObject(ctx, name)
+			if err != nil {
+				return err
+			}
+			if err := u.Upload(ctx, obj); err != nil {
+				return fmt.Errorf("assetserver: error uploading %s: %w", obj.Name, err)
+			}
+			mu.Lock()
+			manifest[name] = obj.Name
+			mu.Unlock()
+			return nil
+		})
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	return manifest, nil
+}
+
+// uploadObject returns the Object to upload for the named file.
+func (s *Server) uploadObject(ctx context.Context, name string) (*Object, error) {
+	b, info, err := s.readFile(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("assetserver: error reading %s: %w", name, err)
+	}
+	obj := &Object{
+		Name:         insertTag(name, info.tag),
+		Body:         b,
+		ContentType:  info.contentType,
+		CacheControl: s.cacheControl(name, info.tag),
+
+		ContentEncoding: inherentEncoding(name),
+	}
+	if s.isPreHashed(name) {
+		obj.Name = name
+	}
+	return obj, nil
+}
@@ -0,0 +1,89 @@
+package assetserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type memUploader struct {
+	mu      sync.Mutex
+	objects map[string]*Object
+	err     error
+}
+
+func (u *memUploader) Upload(ctx context.Context, obj *Object) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.err != nil {
+		return u.err
+	}
+	if u.objects == nil {
+		u.objects = make(map[string]*Object)
+	}
+	u.objects[obj.Name] = obj
+	return nil
+}
+
+func TestUpload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/style.css":       &fstest.MapFile{Data: []byte("body{}")},
+		"js/main.js":          &fstest.MapFile{Data: []byte("main()")},
+		"js/vendor.1a2b3c.js": &fstest.MapFile{Data: []byte("vendor()")},
+		"a.txt":               &fstest.MapFile{Data: []byte("hello")},
+		"img/logo.svgz":       &fstest.MapFile{Data: []byte("gzipped")},
+	}
+	s := New(fsys,
+		WithPreHashed("js/vendor.*.js"),
+		WithTransform(TransformFunc(upper), "*.txt"),
+	)
+	var u memUploader
+	manifest, err := s.Upload(context.Background(), &u, UploadOptions{
+		Patterns: []string{"**/*.css", "**/*.js", "*.txt", "**/*.svgz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	css := "css/style." + hashTag("body{}") + ".css"
+	js := "js/main." + hashTag("main()") + ".js"
+	txt := "a." + hashTag("HELLO") + ".txt"
+	svgz := "img/logo." + hashTag("gzipped") + ".svgz"
+	wantManifest := map[string]string{
+		"css/style.css":       css,
+		"js/main.js":          js,
+		"js/vendor.1a2b3c.js": "js/vendor.1a2b3c.js",
+		"a.txt":               txt,
+		"img/logo.svgz":       svgz,
+	}
+	if diff := cmp.Diff(wantManifest, manifest); diff != "" {
+		t.Errorf("manifest (-want +got):\n%s", diff)
+	}
+	const immutable = "public, max-age=31536000, immutable"
+	wantObjects := map[string]*Object{
+		css:                   {css, []byte("body{}"), "text/css; charset=utf-8", immutable, ""},
+		js:                    {js, []byte("main()"), "text/javascript; charset=utf-8", immutable, ""},
+		"js/vendor.1a2b3c.js": {"js/vendor.1a2b3c.js", []byte("vendor()"), "text/javascript; charset=utf-8", immutable, ""},
+		txt:                   {txt, []byte("HELLO"), "text/plain; charset=utf-8", immutable, ""},
+		svgz:                  {svgz, []byte("gzipped"), "image/svg+xml", immutable, "gzip"},
+	}
+	if diff := cmp.Diff(wantObjects, u.objects); diff != "" {
+		t.Errorf("uploaded objects (-want +got):\n%s", diff)
+	}
+}
+
+func TestUploadError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": &fstest.MapFile{Data: []byte("a")},
+	}
+	errUpload := errors.New("upload failed")
+	if _, err := New(fsys).Upload(context.Background(), &memUploader{err: errUpload}, UploadOptions{}); !errors.Is(err, errUpload) {
+		t.Errorf("got error %v; want %v", err, errUpload)
+	}
+	if _, err := NewNoCache(fsys).Upload(context.Background(), &memUploader{}, UploadOptions{}); err == nil {
+		t.Error("Upload on a no-cache Server: got nil error")
+	}
+}
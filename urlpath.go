@@ -0,0 +1,42 @@
+package assetserver
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+)
+
+// errInvalidName is reported for requests for names which can't be the names
+// of files (see validName).
+var errInvalidName = fmt.Errorf("invalid file name: %w", fs.ErrNotExist)
+
+// validName reports whether name, taken from a request path, can be the name
+// of a file. Names must satisfy fs.ValidPath (which, among other things,
+// requires them to be UTF-8). Names containing NUL bytes are also rejected,
+// since no operating system allows them and os.DirFS reports them as
+// invalid arguments rather than missing files.
+func validName(name string) bool {
+	return fs.ValidPath(name) && !strings.ContainsRune(name, 0)
+}
+
+// escapePath returns the slash-separated file name p escaped for use as the
+// path of a URL.
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, e := range elems {
+		elems[i] = url.PathEscape(e)
+	}
+	return strings.Join(elems, "/")
+}
+
+// escapeRelative is like escapePath, but for a relative URL reference: if
+// the first element of p contains a colon, it is prefixed by "./" so that it
+// isn't taken for a URL scheme.
+func escapeRelative(p string) string {
+	first, _, _ := strings.Cut(p, "/")
+	if strings.Contains(first, ":") {
+		return "./" + escapePath(p)
+	}
+	return escapePath(p)
+}
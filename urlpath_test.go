@@ -0,0 +1,100 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpecialCharacterPaths(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"my file.txt":     "space",
+		"a+b.txt":         "plus",
+		"café/menü.txt":   "utf-8",
+		"100%.txt":        "percent",
+		"what?.txt":       "question",
+		"a#b.txt":         "hash",
+		"x:y.txt":         "colon",
+		"dir name/a.css":  "dir",
+		"tagged file.css": "tagged",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := New(os.DirFS(dir))
+	defer s.Close()
+	tagged, err := s.Tag("tagged file.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		target   string // the request URL
+		status   int
+		body     string
+		location string
+	}{
+		{"/my%20file.txt", 200, "space", ""},
+		{"/a+b.txt", 200, "plus", ""},
+		{"/a%2Bb.txt", 200, "plus", ""},
+		{"/a%2bb.txt", 200, "plus", ""},
+		{"/caf%C3%A9/men%C3%BC.txt", 200, "utf-8", ""},
+		{"/100%25.txt", 200, "percent", ""},
+		{"/what%3F.txt", 200, "question", ""},
+		{"/a%23b.txt", 200, "hash", ""},
+		{"/x:y.txt", 200, "colon", ""},
+		{"/dir%20name/a.css", 200, "dir", ""},
+		{"/" + escapePath(tagged), 200, "tagged", ""},
+		{"/my%20file.txt/", 308, "", "../my%20file.txt"},
+		{"/what%3F.txt/", 308, "", "../what%3F.txt"},
+		{"/my+file.txt", 404, "", ""},
+		// Names that can't be file names are not found, rather than
+		// causing errors.
+		{"/a%00b.txt", 404, "", ""},
+		{"/%FF.txt", 404, "", ""},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: got status %d; want %d", tt.target, w.Code, tt.status)
+			continue
+		}
+		if tt.status == 200 && w.Body.String() != tt.body {
+			t.Errorf("GET %s: got body %q; want %q", tt.target, w.Body, tt.body)
+		}
+		if got := w.Header().Get("Location"); got != tt.location {
+			t.Errorf("GET %s: got Location %q; want %q", tt.target, got, tt.location)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		wantPath     string
+		wantRelative string
+	}{
+		{"css/style.css", "css/style.css", "css/style.css"},
+		{"my file.txt", "my%20file.txt", "my%20file.txt"},
+		{"a+b/c?d#e.txt", "a+b/c%3Fd%23e.txt", "a+b/c%3Fd%23e.txt"},
+		{"100%.txt", "100%25.txt", "100%25.txt"},
+		{"café/menü.txt", "caf%C3%A9/men%C3%BC.txt", "caf%C3%A9/men%C3%BC.txt"},
+		{"x:y/z.txt", "x:y/z.txt", "./x:y/z.txt"},
+		{"x/y:z.txt", "x/y:z.txt", "x/y:z.txt"},
+		{"../a,b.txt", "../a%2Cb.txt", "../a%2Cb.txt"},
+	} {
+		if got := escapePath(tt.name); got != tt.wantPath {
+			t.Errorf("escapePath(%q): got %q; want %q", tt.name, got, tt.wantPath)
+		}
+		if got := escapeRelative(tt.name); got != tt.wantRelative {
+			t.Errorf("escapeRelative(%q): got %q; want %q", tt.name, got, tt.wantRelative)
+		}
+	}
+}
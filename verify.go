@@ -0,0 +1,117 @@
+package assetserver
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// ErrContentMismatch is reported (wrapped in an error giving the file's name)
+// to the hook given to [WithErrorHook] when a Server created with
+// [WithVerifyOnServe] finds that a file's contents don't match its tag.
+var ErrContentMismatch = errors.New("file contents do not match the tag")
+
+// WithVerifyOnServe makes the Server check the contents of each file it
+// serves against the file's tag, as it sends them, to detect files which
+// were corrupted (by a failing disk, say, or a write that tore without
+// changing the file's size and modification time) after they were hashed.
+//
+// If the contents don't match, the Server logs an error, reports
+// [ErrContentMismatch] to the error hook (see [WithErrorHook]), forgets its
+// cached information about the file so that the next request hashes it
+// again, and aborts the response (by panicking with http.ErrAbortHandler)
+// before sending the end of the body, so that the client sees a truncated
+// response rather than one that it might cache.
+//
+// Checking takes a hash of each response body, and it stops net/http from
+// using sendfile to send files from an os.DirFS. The Server only checks
+// responses which include the whole of a file's original contents: it doesn't
+// check range requests, or files which are transformed (see [WithTransform]),
+// precompressed (see [WithPrecompressed]), or partially hashed (see
+// [WithPartialHashing]).
+func WithVerifyOnServe() Option {
+	return func(s *Server) {
+		s.verifyOnServe = true
+	}
+}
+
+// verifies reports whether the Server checks the contents of the named file,
+// which has the given info, as it serves them.
+func (s *Server) verifies(name string, info *fileInfo) bool {
+	if !s.verifyOnServe || info.body != nil || info.size == 0 {
+		return false
+	}
+	return s.partialHash == nil || !s.partialHash.applies(name, info.size)
+}
+
+// A verifyingReader hashes the contents of a file as they are read from the
+// start and checks the hash against the file's tag once all of the contents
+// have been read. Reads after seeking elsewhere aren't hashed.
+type verifyingReader struct {
+	io.ReadSeeker
+	s    *Server
+	r    *http.Request
+	name string
+	info *fileInfo
+
+	h      hash.Hash
+	off    int64 // the current offset
+	hashed int64 // the number of bytes hashed
+	done   bool
+}
+
+func (s *Server) newVerifyingReader(r *http.Request, name string, info *fileInfo, f io.ReadSeeker) *verifyingReader {
+	return &verifyingReader{
+		ReadSeeker: f,
+		s:          s,
+		r:          r,
+		name:       name,
+		info:       info,
+		h:          sha256.New(),
+	}
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	n, err := vr.ReadSeeker.Read(p)
+	if vr.off == vr.hashed && !vr.done {
+		m := min(int64(n), vr.info.size-vr.hashed)
+		vr.h.Write(p[:m])
+		vr.hashed += m
+		if vr.hashed == vr.info.size {
+			vr.done = true
+			vr.check()
+		}
+	}
+	vr.off += int64(n)
+	return n, err
+}
+
+func (vr *verifyingReader) Seek(offset int64, whence int) (int64, error) {
+	off, err := vr.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		vr.off = off
+	}
+	return off, err
+}
+
+// check compares the hash of the contents with the file's tag and aborts the
+// response if they don't match.
+func (vr *verifyingReader) check() {
+	s := vr.s
+	tag := s.typedTag(makeTag(vr.h.Sum(nil)), vr.info.contentType)
+	if tag == vr.info.tag {
+		return
+	}
+	ctx := vr.r.Context()
+	s.log(ctx, slog.LevelError, "file contents do not match tag",
+		"name", vr.name, "tag", vr.info.tag, "contents_tag", tag)
+	if s.errorHook != nil {
+		s.errorHook(vr.r, vr.name, fmt.Errorf("%s: %w", vr.name, ErrContentMismatch))
+	}
+	s.cache.evict(vr.name)
+	panic(http.ErrAbortHandler)
+}
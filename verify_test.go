@@ -0,0 +1,76 @@
+package assetserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestVerifyOnServe(t *testing.T) {
+	mtime := time.Unix(1e9, 0)
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("hello, world"), ModTime: mtime},
+		"b.txt": &fstest.MapFile{Data: []byte("unchanged"), ModTime: mtime},
+	}
+	var hookErr error
+	s := New(fsys, WithVerifyOnServe(), WithErrorHook(func(r *http.Request, name string, err error) {
+		hookErr = err
+	}))
+	defer s.Close()
+	tag, err := s.Tag("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unchanged file is served as usual.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/b.txt", nil))
+	if w.Code != 200 || w.Body.String() != "unchanged" {
+		t.Fatalf("GET /b.txt: got (%d, %q)", w.Code, w.Body)
+	}
+
+	// Corrupt the file without changing its size or modification time.
+	fsys["a.txt"].Data = []byte("hello, World")
+
+	// Range requests aren't checked.
+	req := httptest.NewRequest("GET", "/"+tag, nil)
+	req.Header.Set("Range", "bytes=0-4")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "hello" {
+		t.Fatalf("GET /%s (range): got (%d, %q)", tag, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	func() {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("got panic %v; want http.ErrAbortHandler", v)
+			}
+		}()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/"+tag, nil))
+	}()
+	if w.Body.Len() == len("hello, World") {
+		t.Errorf("corrupt contents were sent in full: %q", w.Body)
+	}
+	if !errors.Is(hookErr, ErrContentMismatch) {
+		t.Errorf("error hook got %v; want ErrContentMismatch", hookErr)
+	}
+
+	// The file is hashed again for the next request.
+	newTag, err := s.Tag("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newTag == tag {
+		t.Fatalf("tag unchanged after mismatch: %s", tag)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/"+newTag, nil))
+	if w.Code != 200 || w.Body.String() != "hello, World" {
+		t.Errorf("GET /%s: got (%d, %q)", newTag, w.Code, w.Body)
+	}
+}
@@ -0,0 +1,47 @@
+package assetserver
+
+import "net/http"
+
+// versionParam is the query parameter that carries the global version (see
+// WithGlobalVersion).
+const versionParam = "v"
+
+// WithGlobalVersion gives the Server a version string for the whole set of
+// assets, such as a build number or commit hash, which the application adds
+// to asset URLs as a "v" query parameter:
+//
+//	<script src="/static/app.js?v=3f9c2b1"></script>
+//
+// A request whose v parameter matches the version is served with the
+// long-lived Cache-Control header used for tagged names. This gives coarse
+// cache busting to applications which can't use [Server.Tag] for every
+// reference: when the version changes with each deploy, every URL changes
+// with it. A request with any other version (such as one from a page served
+// by an earlier deploy) is served the current file with the usual short
+// max-age.
+//
+// Since the Server can't check that the files haven't changed without the
+// version changing, the version must be derived from the assets themselves
+// or change with every deploy.
+func WithGlobalVersion(version string) Option {
+	if version == "" {
+		panic("assetserver: WithGlobalVersion called with empty version")
+	}
+	return func(s *Server) {
+		s.globalVersion = version
+	}
+}
+
+// versioned reports whether r has the global version as its v parameter.
+func (s *Server) versioned(r *http.Request) bool {
+	return s.globalVersion != "" && r.URL.RawQuery != "" && r.URL.Query().Get(versionParam) == s.globalVersion
+}
+
+// responseCacheControl returns the Cache-Control header for a response to r
+// for the named file, requested with the given tag (if any).
+func (s *Server) responseCacheControl(r *http.Request, name, tag string) string {
+	if !s.noCache && tag == "" && s.versioned(r) {
+		return s.immutableCacheControl(name)
+	}
+	return s.cacheControl(name, tag)
+}
@@ -0,0 +1,62 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGlobalVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":   &fstest.MapFile{Data: []byte("app")},
+		"logo.png": &fstest.MapFile{Data: testPNG(t, 40, 20)},
+	}
+	const immutable = "public, max-age=31536000, immutable"
+	s := New(fsys,
+		WithGlobalVersion("3f9c2b1"),
+		WithImageResizing(ImageResizeOptions{Patterns: []string{"*.png"}, Widths: []int{20}}),
+	)
+	for _, tt := range []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/app.js?v=3f9c2b1", 200, immutable},
+		{"/app.js?v=old", 200, "public, max-age=60"},
+		{"/app.js", 200, "public, max-age=60"},
+		{"/logo.png?w=20&v=3f9c2b1", 200, immutable},
+		{"/logo.png?w=20", 200, "public, max-age=60"},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s: got status %d; want %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("GET %s: got Cache-Control %q; want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Without a global version, v is not a valid resizing parameter, and
+	// it doesn't affect caching.
+	s = New(fsys, WithImageResizing(ImageResizeOptions{Patterns: []string{"*.png"}, Widths: []int{20}}))
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{"/logo.png?w=20&v=3f9c2b1", 400},
+		{"/app.js?v=3f9c2b1", 200},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("no version: GET %s: got status %d; want %d", tt.path, w.Code, tt.code)
+		}
+		if tt.code == 200 {
+			if got, want := w.Header().Get("Cache-Control"), "public, max-age=60"; got != want {
+				t.Errorf("no version: GET %s: got Cache-Control %q; want %q", tt.path, got, want)
+			}
+		}
+	}
+}
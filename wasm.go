@@ -0,0 +1,29 @@
+package assetserver
+
+import (
+	"mime"
+	"strings"
+)
+
+// builtinTypes maps file extensions to the content types that the Server
+// always uses for them, regardless of the system's MIME tables.
+//
+// Browsers only compile WebAssembly as it streams in (with
+// WebAssembly.instantiateStreaming) if it is served as application/wasm, but
+// some systems' tables map .wasm to something else or not at all.
+//
+// For file types which are always compressed (see inherentEncodings), the
+// type is that of the decompressed contents.
+var builtinTypes = map[string]string{
+	".wasm": "application/wasm",
+	".svgz": "image/svg+xml",
+}
+
+// typeByExtension returns the content type for a file with the given
+// extension, or "" if it is unknown.
+func typeByExtension(ext string) string {
+	if t, ok := builtinTypes[strings.ToLower(ext)]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
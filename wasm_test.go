@@ -0,0 +1,32 @@
+package assetserver
+
+import (
+	"mime"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWASMContentType(t *testing.T) {
+	// Simulate a system whose MIME tables have the wrong type for .wasm.
+	orig := mime.TypeByExtension(".wasm")
+	if err := mime.AddExtensionType(".wasm", "application/octet-stream"); err != nil {
+		t.Fatal(err)
+	}
+	defer mime.AddExtensionType(".wasm", orig)
+
+	fsys := fstest.MapFS{
+		"main.wasm": &fstest.MapFile{Data: []byte("\x00asm\x01\x00\x00\x00")},
+	}
+	for _, s := range []*Server{
+		New(fsys),
+		New(fsys, WithStreamingHash()),
+		New(fsys, WithTransform(TransformFunc(upper))),
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/main.wasm", nil))
+		if got, want := w.Header().Get("Content-Type"), "application/wasm"; got != want {
+			t.Errorf("got Content-Type %q; want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package assetserver
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// WellKnownOptions configures [WithWellKnown].
+type WellKnownOptions struct {
+	// FS, if non-nil, is the file system that the well-known files are
+	// served from, in place of the Server's. Names are looked up in FS as
+	// they are in the request (such as ".well-known/security.txt").
+	FS fs.FS
+	// MaxAge is the max-age of the Cache-Control header of responses for
+	// well-known files. If it is zero, one minute is used.
+	MaxAge time.Duration
+}
+
+// wellKnownPatterns match the names of the well-known files.
+var wellKnownPatterns = compilePatterns([]string{
+	".well-known/**",
+	"robots.txt",
+	"security.txt",
+})
+
+// defaultWellKnownMaxAge is the default for WellKnownOptions.MaxAge.
+const defaultWellKnownMaxAge = time.Minute
+
+// WithWellKnown makes the Server serve the files that crawlers and other
+// sites expect at fixed URLs (anything under /.well-known/, as well as
+// /robots.txt and /security.txt) as plain files. Their names are used as
+// they are, without looking for tags, and they are served with a short-lived
+// Cache-Control header (see [WellKnownOptions]) even if other options (such
+// as [WithImmutableDirs]) would otherwise make them cacheable indefinitely.
+// Since they are never requested by tagged names, they aren't hashed and
+// responses for them don't have ETags.
+//
+// With opts.FS, the files can be kept separately from the Server's assets,
+// for example:
+//
+//	WithWellKnown(WellKnownOptions{FS: os.DirFS("/etc/site/well-known")})
+func WithWellKnown(opts WellKnownOptions) Option {
+	if opts.MaxAge < 0 {
+		panic(fmt.Sprintf("assetserver: WithWellKnown called with negative max age %s", opts.MaxAge))
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = defaultWellKnownMaxAge
+	}
+	return func(s *Server) {
+		s.wellKnown = &opts
+	}
+}
+
+// isWellKnown reports whether the named file is a well-known file which the
+// Server serves as-is.
+func (s *Server) isWellKnown(name string) bool {
+	return s.wellKnown != nil && matchAny(wellKnownPatterns, name)
+}
+
+// wellKnownFS returns the file system of the well-known files.
+func (s *Server) wellKnownFS() fs.FS {
+	if s.wellKnown.FS != nil {
+		return s.wellKnown.FS
+	}
+	return s.fsys
+}
+
+// serveWellKnown serves the named well-known file.
+func (s *Server) serveWellKnown(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := s.wellKnownFS().Open(name)
+	if err != nil {
+		s.writeFSError(w, r, name, err)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		s.writeFSError(w, r, name, err)
+		return
+	}
+	if stat.IsDir() {
+		s.writeFSError(w, r, name, fs.ErrNotExist)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/") {
+		// See the trailing slash redirect in serveHTTP.
+		target := "../" + escapePath(path.Base(name))
+		if q := r.URL.RawQuery; q != "" {
+			target += "?" + q
+		}
+		s.redirect(w, r, target)
+		return
+	}
+	sf, err := toSeeker(f)
+	if err != nil {
+		s.writeFSError(w, r, name, err)
+		return
+	}
+	h := w.Header()
+	if s.noCache {
+		h.Set("Cache-Control", "no-cache")
+	} else {
+		h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(s.wellKnown.MaxAge/time.Second)))
+	}
+	// Only set Content-Type if it wasn't set by the caller. If the
+	// extension doesn't give one, ServeContent sniffs it.
+	if _, ok := h["Content-Type"]; !ok {
+		if t := typeByExtension(path.Ext(name)); t != "" {
+			h.Set("Content-Type", t)
+		}
+	}
+	setAssetInfo(r.Context(), &AssetInfo{Name: name, Size: stat.Size(), ContentType: h.Get("Content-Type")})
+	http.ServeContent(w, r, name, stat.ModTime(), sf)
+}
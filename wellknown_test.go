@@ -0,0 +1,110 @@
+package assetserver
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWellKnown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"robots.txt":                              &fstest.MapFile{Data: []byte("User-agent: *")},
+		".well-known/security.txt":                &fstest.MapFile{Data: []byte("Contact: mailto:security@example.com")},
+		".well-known/acme-challenge/AbCdEfGhIj":   &fstest.MapFile{Data: []byte("token")},
+		".well-known/acme-challenge/x.AbCdEfGhIj": &fstest.MapFile{Data: []byte("dotted token")},
+		"css/style.css":                           &fstest.MapFile{Data: []byte("body{}")},
+	}
+	separate := fstest.MapFS{
+		"robots.txt": &fstest.MapFile{Data: []byte("User-agent: * Disallow: /")},
+	}
+	for _, tt := range []struct {
+		opts         []Option
+		path         string
+		status       int
+		body         string
+		cacheControl string
+	}{
+		{nil, "/robots.txt", 200, "User-agent: *", "public, max-age=60"},
+		{nil, "/.well-known/acme-challenge/x.AbCdEfGhIj", 404, "", ""},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{})},
+			"/robots.txt", 200, "User-agent: *", "public, max-age=60",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{}), WithImmutableDirs("")},
+			"/.well-known/security.txt", 200, "Contact: mailto:security@example.com", "public, max-age=60",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{MaxAge: time.Hour})},
+			"/.well-known/security.txt", 200, "Contact: mailto:security@example.com", "public, max-age=3600",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{})},
+			"/.well-known/acme-challenge/x.AbCdEfGhIj", 200, "dotted token", "public, max-age=60",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{})},
+			"/.well-known/acme-challenge/AbCdEfGhIj", 200, "token", "public, max-age=60",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{})},
+			"/.well-known/missing.txt", 404, "", "",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{})},
+			"/.well-known", 404, "", "",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{FS: separate})},
+			"/robots.txt", 200, "User-agent: * Disallow: /", "public, max-age=60",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{FS: separate})},
+			"/.well-known/security.txt", 404, "", "",
+		},
+		{
+			[]Option{WithWellKnown(WellKnownOptions{FS: separate})},
+			"/css/style.css", 200, "body{}", "public, max-age=60",
+		},
+	} {
+		s := New(fsys, tt.opts...)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%d options: GET %s: got status %d; want %d", len(tt.opts), tt.path, w.Code, tt.status)
+		} else if tt.status == 200 {
+			if got := w.Body.String(); got != tt.body {
+				t.Errorf("%d options: GET %s: got body %q; want %q", len(tt.opts), tt.path, got, tt.body)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("%d options: GET %s: got Cache-Control %q; want %q", len(tt.opts), tt.path, got, tt.cacheControl)
+			}
+		}
+		s.Close()
+	}
+}
+
+func TestWellKnownTag(t *testing.T) {
+	fsys := fstest.MapFS{
+		"robots.txt":    &fstest.MapFile{Data: []byte("User-agent: *")},
+		"css/style.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	s := New(fsys, WithWellKnown(WellKnownOptions{}))
+	defer s.Close()
+	for _, name := range []string{"robots.txt", "/robots.txt"} {
+		got, err := s.Tag(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != name {
+			t.Errorf("Tag(%q): got %q; want it unchanged", name, got)
+		}
+	}
+	if _, err := s.Tag("security.txt"); err == nil {
+		t.Error("Tag of missing well-known file succeeded")
+	}
+	if got, _ := s.Tag("css/style.css"); got == "css/style.css" {
+		t.Error("Tag of an ordinary file returned it unchanged")
+	}
+}